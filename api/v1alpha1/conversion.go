@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+// NodeSpreadAnnotation may be set to "false" on an SmbShare to allow the
+// pods of a clustered instance to run on the same node. Later API versions
+// replace the annotation with the nodeSpread scaling field.
+const NodeSpreadAnnotation = "samba-operator.samba.org/node-spread"
+
+// The v1alpha1 types are the storage versions of the resources and the
+// hub that other API versions convert to and from.
+
+// Hub marks SmbShare as a conversion hub.
+func (*SmbShare) Hub() {}
+
+// Hub marks SmbSecurityConfig as a conversion hub.
+func (*SmbSecurityConfig) Hub() {}
+
+// Hub marks SmbCommonConfig as a conversion hub.
+func (*SmbCommonConfig) Hub() {}
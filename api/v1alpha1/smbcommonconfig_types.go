@@ -39,6 +39,11 @@ type SmbCommonConfigSpec struct {
 	// are scheduled in a kubernetes cluster.
 	PodSettings *SmbCommonConfigPodSettings `json:"podSettings,omitempty"`
 
+	// Security configures encryption, signing and the range of SMB
+	// protocol versions of the servers.
+	// +optional
+	Security *SmbCommonSecuritySpec `json:"security,omitempty"`
+
 	// GlobalConfig are configuration values that are applied to [global]
 	// section in smb.conf for the smb server. This allows users to add or
 	// override default configurations.
@@ -46,6 +51,34 @@ type SmbCommonConfigSpec struct {
 	CustomGlobalConfig *SmbCommonConfigGlobalConfig `json:"customGlobalConfig,omitempty"`
 }
 
+// SmbCommonSecuritySpec configures the security of the SMB protocol used
+// by the servers hosting shares.
+type SmbCommonSecuritySpec struct {
+	// Encryption controls whether the servers encrypt SMB3 traffic.
+	// "desired" offers encryption to clients, "required" rejects clients
+	// that do not support encryption.
+	// +kubebuilder:validation:Enum:=off;desired;required
+	// +optional
+	Encryption string `json:"encryption,omitempty"`
+
+	// Signing controls whether the servers sign SMB packets.
+	// "desired" offers signing to clients, "required" rejects clients
+	// that do not sign.
+	// +kubebuilder:validation:Enum:=off;desired;required
+	// +optional
+	Signing string `json:"signing,omitempty"`
+
+	// MinProtocol is the oldest protocol version the servers accept.
+	// +kubebuilder:validation:Enum:=NT1;SMB2;SMB2_02;SMB2_10;SMB3;SMB3_00;SMB3_02;SMB3_11
+	// +optional
+	MinProtocol string `json:"minProtocol,omitempty"`
+
+	// MaxProtocol is the newest protocol version the servers accept.
+	// +kubebuilder:validation:Enum:=NT1;SMB2;SMB2_02;SMB2_10;SMB3;SMB3_00;SMB3_02;SMB3_11
+	// +optional
+	MaxProtocol string `json:"maxProtocol,omitempty"`
+}
+
 // SmbCommonNetworkSpec values define networking properties for the services
 // that will host shares.
 type SmbCommonNetworkSpec struct {
@@ -84,6 +117,7 @@ type SmbCommonConfigStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // SmbCommonConfig is the Schema for the smbcommonconfigs API
 type SmbCommonConfig struct {
@@ -20,6 +20,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ForceIDMapChangeAnnotation may be set to "true" on an SmbSecurityConfig
+// to allow changing the ID ranges of its domains while the config is in
+// use by SmbShares. Changing the ranges changes the IDs the users and
+// groups of the domains are mapped to.
+const ForceIDMapChangeAnnotation = "samba-operator.samba.org/force-idmap-change"
+
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags
 // for the fields to be serialized.
@@ -37,10 +43,20 @@ type SmbSecurityConfigSpec struct {
 	// Realm specifies the active directory domain to use.
 	Realm string `json:"realm,omitempty"`
 
+	// Workgroup specifies the NetBIOS name of the active directory domain.
+	// If unset, the operator discovers the name from the domain.
+	// +kubebuilder:validation:MaxLength:=15
+	// +optional
+	Workgroup string `json:"workgroup,omitempty"`
+
 	// JoinSources holds a list of sources for domain join data for
 	// this configuration.
 	JoinSources []SmbSecurityJoinSpec `json:"joinSources,omitempty"`
 
+	// JoinOptions configures how server instances are joined to the domain.
+	// +optional
+	JoinOptions *SmbSecurityJoinOptionsSpec `json:"joinOptions,omitempty"`
+
 	// Domains holds a list of primary & trusted domain configurations.
 	// If left empty a simple default that automatically works with
 	// trusted domains will be used.
@@ -71,7 +87,18 @@ type SmbSecurityUsersSpec struct {
 // SmbSecurityJoinSpec configures how samba instances are allowed to
 // join to active directory if needed.
 type SmbSecurityJoinSpec struct {
+	// UserJoin joins the domain using the credentials of a user.
+	// +optional
 	UserJoin *SmbSecurityUserJoinSpec `json:"userJoin,omitempty"`
+
+	// KeytabJoin joins the domain using a Kerberos keytab.
+	// +optional
+	KeytabJoin *SmbSecurityKeytabJoinSpec `json:"keytabJoin,omitempty"`
+
+	// ComputerAccountJoin joins the domain using the one-time password of
+	// a pre-created computer account.
+	// +optional
+	ComputerAccountJoin *SmbSecurityComputerAccountJoinSpec `json:"computerAccountJoin,omitempty"`
 }
 
 // SmbSecurityUserJoinSpec configures samba container instances to
@@ -87,6 +114,49 @@ type SmbSecurityUserJoinSpec struct {
 	Key string `json:"key,omitempty"`
 }
 
+// SmbSecurityKeytabJoinSpec configures samba container instances to
+// use a secret containing a Kerberos keytab of a principal that is
+// permitted to join systems to the domain.
+type SmbSecurityKeytabJoinSpec struct {
+	// Secret that contains the keytab.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Secret string `json:"secret,omitempty"`
+	// Key within the secret containing the keytab.
+	// +kubebuilder:default:=krb5.keytab
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SmbSecurityComputerAccountJoinSpec configures samba container instances
+// to use a secret containing the one-time password of a computer account
+// that was created in the domain in advance.
+type SmbSecurityComputerAccountJoinSpec struct {
+	// Secret that contains the one-time password.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Secret string `json:"secret,omitempty"`
+	// Key within the secret containing the one-time password.
+	// +kubebuilder:default:=password
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SmbSecurityJoinOptionsSpec configures how samba container instances
+// are joined to the domain.
+type SmbSecurityJoinOptionsSpec struct {
+	// OrganizationalUnit the computer account is created in.
+	// +optional
+	OrganizationalUnit string `json:"organizationalUnit,omitempty"`
+	// ComputerAccountName is the name of the computer account to join as.
+	// If unset, the NetBIOS name of the server instance is used.
+	// The name becomes the NetBIOS name of the servers, so it may only be
+	// used by a single server group.
+	// +kubebuilder:validation:MaxLength:=15
+	// +optional
+	ComputerAccountName string `json:"computerAccountName,omitempty"`
+}
+
 // SmbSecurityDomainSpec configures samba's domain management and ID mapping
 // behavior for the specified domain.
 type SmbSecurityDomainSpec struct {
@@ -95,12 +165,33 @@ type SmbSecurityDomainSpec struct {
 	// +kubebuilder:validation:MinLength:=1
 	Name string `json:"name,omitempty"`
 
-	// Mode specifies what approach to security is being used.
+	// Backend specifies how IDs are mapped for the domain.
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum:=autorid;ad-rfc2307
+	// +kubebuilder:validation:Enum:=autorid;ad-rfc2307;ad;rid;tdb;nss
 	Backend string `json:"backend,omitempty"`
 
-	// TODO: add support for id mapping ranges, etc.
+	// RangeStart is the first ID of the range of IDs mapped for the domain.
+	// If unset, a range is assigned by the operator.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RangeStart int `json:"rangeStart,omitempty"`
+
+	// RangeSize is the number of IDs in the range of IDs mapped for the
+	// domain. Must be set along with RangeStart.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RangeSize int `json:"rangeSize,omitempty"`
+
+	// UnixPrimaryGroup uses the primary group stored in the RFC2307
+	// attributes of a user rather than the domain's primary group.
+	// Only valid for the ad and ad-rfc2307 backends.
+	// +optional
+	UnixPrimaryGroup bool `json:"unixPrimaryGroup,omitempty"`
+
+	// UnixNSSInfo uses the home directory and shell stored in the RFC2307
+	// attributes of a user. Only valid for the ad and ad-rfc2307 backends.
+	// +optional
+	UnixNSSInfo bool `json:"unixNSSInfo,omitempty"`
 }
 
 // SmbSecurityDNSSpec configures the relationship between systems managed
@@ -113,14 +204,63 @@ type SmbSecurityDNSSpec struct {
 	Register string `json:"register,omitempty"`
 }
 
+// SmbSecurityIDMapRange is the range of IDs the users and groups of a
+// domain are mapped to.
+type SmbSecurityIDMapRange struct {
+	// Name of the domain.
+	Name string `json:"name"`
+
+	// RangeStart is the first ID of the range.
+	RangeStart int `json:"rangeStart"`
+
+	// RangeSize is the number of IDs in the range.
+	RangeSize int `json:"rangeSize"`
+}
+
 // SmbSecurityConfigStatus defines the observed state of SmbSecurityConfig
 type SmbSecurityConfigStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Workgroup is the NetBIOS name of the active directory domain as
+	// discovered by the operator.
+	// +optional
+	Workgroup string `json:"workgroup,omitempty"`
+
+	// Shares lists the names of the SmbShares using this security config.
+	// +optional
+	Shares []string `json:"shares,omitempty"`
+
+	// IDMapRanges lists the ID ranges in use for the domains. Once
+	// recorded, the range of a domain is kept when the domains are
+	// reordered. A range set in the spec only replaces a recorded range if
+	// no SmbShare uses the security config or the change is forced with the
+	// force-idmap-change annotation.
+	// +optional
+	IDMapRanges []SmbSecurityIDMapRange `json:"idmapRanges,omitempty"`
+
+	// Conditions describe whether the security config and the secrets it
+	// refers to are valid, and whether the workgroup has been discovered.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// Condition types reported in the SmbSecurityConfig status.
+const (
+	// SecurityConfigConditionReady indicates the security config and the
+	// secrets it refers to are valid and may be used by shares.
+	SecurityConfigConditionReady = "Ready"
+	// SecurityConfigConditionInvalid indicates the security config or one
+	// of the secrets it refers to is missing or invalid.
+	SecurityConfigConditionInvalid = "Invalid"
+	// SecurityConfigConditionWorkgroupDiscovered indicates whether the
+	// NetBIOS name of the active directory domain has been discovered.
+	// Shares using the security config wait for the discovery to finish.
+	SecurityConfigConditionWorkgroupDiscovered = "WorkgroupDiscovered"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // SmbSecurityConfig is the Schema for the smbsecurityconfigs API
 type SmbSecurityConfig struct {
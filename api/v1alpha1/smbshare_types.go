@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,13 +29,20 @@ import (
 
 // SmbShareSpec defines the desired state of SmbShare
 type SmbShareSpec struct {
-	// TODO validation of share name
-
 	// ShareName is an optional string that lets you define an SMB compliant
 	// name for the share. If unset, the name will be derived automatically.
 	// +optional
 	ShareName string `json:"shareName,omitempty"`
 
+	// NetbiosName is the NetBIOS name of the servers hosting the share.
+	// All shares of a server group must specify the same name. If unset,
+	// the name of the server group is used. The name may only consist of
+	// letters, digits, hyphens and underscores and may not be changed.
+	// +kubebuilder:validation:MaxLength:=15
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-_a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	// +optional
+	NetbiosName string `json:"netbiosName,omitempty"`
+
 	// Storage defines the type and location of the storage that backs this
 	// share.
 	Storage SmbShareStorageSpec `json:"storage"`
@@ -64,6 +72,55 @@ type SmbShareSpec struct {
 	// +optional
 	CommonConfig string `json:"commonConfig,omitempty"`
 
+	// ValidUsers lists the users and groups allowed to connect to the
+	// share. If empty, any user may connect. Group names are prefixed
+	// with "@" and may be qualified by a domain ("@DOMAIN\group").
+	// +optional
+	ValidUsers []AccessEntry `json:"validUsers,omitempty"`
+
+	// InvalidUsers lists the users and groups that may never connect to
+	// the share.
+	// +optional
+	InvalidUsers []AccessEntry `json:"invalidUsers,omitempty"`
+
+	// AdminUsers lists the users and groups that are granted
+	// administrative privileges on the share. They perform all file
+	// operations as the superuser (root).
+	// +optional
+	AdminUsers []AccessEntry `json:"adminUsers,omitempty"`
+
+	// ReadList lists the users and groups that are given read-only
+	// access to the share, regardless of the readOnly setting.
+	// +optional
+	ReadList []AccessEntry `json:"readList,omitempty"`
+
+	// WriteList lists the users and groups that are given read-write
+	// access to the share, regardless of the readOnly setting.
+	// +optional
+	WriteList []AccessEntry `json:"writeList,omitempty"`
+
+	// Encryption overrides the encryption setting of the common config
+	// for this share.
+	// +kubebuilder:validation:Enum:=off;desired;required
+	// +optional
+	Encryption string `json:"encryption,omitempty"`
+
+	// Snapshots configures periodic VolumeSnapshots of the share's PVC.
+	// The snapshots are offered to clients as previous versions of the
+	// share's files.
+	// +optional
+	Snapshots *SmbShareSnapshotsSpec `json:"snapshots,omitempty"`
+
+	// Audit enables logging of the operations clients perform on the
+	// share's files.
+	// +optional
+	Audit *SmbShareAuditSpec `json:"audit,omitempty"`
+
+	// TimeMachine configures the share as a backup destination for the
+	// Time Machine feature of macOS clients.
+	// +optional
+	TimeMachine *SmbShareTimeMachineSpec `json:"timeMachine,omitempty"`
+
 	// CustomShareConfig specifies custom config values to be applied
 	// to share section in smb.conf
 	// +optional
@@ -74,11 +131,39 @@ type SmbShareSpec struct {
 	Scaling *SmbShareScalingSpec `json:"scaling,omitempty"`
 }
 
+// AccessEntry names a user or, prefixed with "@", a group. Users and groups
+// of an Active Directory domain may be qualified by the domain name, for
+// example "DOMAIN\user" or "@DOMAIN\group".
+// +kubebuilder:validation:MinLength=1
+// +kubebuilder:validation:Pattern=`^[^,"]+$`
+type AccessEntry string
+
 // SmbShareStorageSpec defines how storage is associated with a share.
+// Exactly one source of storage must be specified.
 type SmbShareStorageSpec struct {
 	// Pvc defines PVC backed storage for this share.
 	// +optional
 	Pvc *SmbSharePvcSpec `json:"pvc,omitempty"`
+
+	// PersistentVolume defines storage provided by an existing
+	// PersistentVolume. The operator binds the volume with a PVC
+	// of its own.
+	// +optional
+	PersistentVolume *SmbSharePersistentVolumeSpec `json:"persistentVolume,omitempty"`
+
+	// Ephemeral defines a generic ephemeral volume for this share. The
+	// volume, and the data stored on it, only lives as long as the pod
+	// of the smb server. Not supported by clustered shares.
+	// +optional
+	Ephemeral *SmbShareEphemeralSpec `json:"ephemeral,omitempty"`
+
+	// CSI defines an inline CSI volume for this share.
+	// +optional
+	CSI *SmbShareCSISpec `json:"csi,omitempty"`
+
+	// NFS defines an export of an NFS server to be shared.
+	// +optional
+	NFS *SmbShareNFSSpec `json:"nfs,omitempty"`
 }
 
 // SmbShareConfig defines custom config values for share section
@@ -100,12 +185,136 @@ type SmbSharePvcSpec struct {
 	// +optional
 	Spec *corev1.PersistentVolumeClaimSpec `json:"spec,omitempty"`
 
+	// ReclaimPolicy determines what happens to a PVC created from Spec
+	// when the share is deleted. With Delete the PVC is deleted along
+	// with the share. With Retain the PVC is kept and labeled as orphaned,
+	// a new share naming the PVC adopts it.
+	// +kubebuilder:validation:Enum:=Retain;Delete
+	// +kubebuilder:default:=Delete
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+
 	// Path within the PVC which should be exported.
 	// +kubebuilder:validation:Pattern=`^[^\/]+$`
 	// +optional
 	Path string `json:"path,omitempty"`
 }
 
+// Reclaim policies of a PVC created for a share.
+const (
+	// PvcReclaimDelete deletes the PVC along with the share.
+	PvcReclaimDelete = "Delete"
+	// PvcReclaimRetain keeps the PVC when the share is deleted.
+	PvcReclaimRetain = "Retain"
+)
+
+// SmbShareSnapshotsSpec defines how snapshots of a share's PVC are taken
+// and retained.
+type SmbShareSnapshotsSpec struct {
+	// VolumeSnapshotClassName is the name of the VolumeSnapshotClass used
+	// to take the snapshots. If unset the default class is used.
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// Interval is the time between two snapshots, for example "24h".
+	// +kubebuilder:default:="24h"
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// Retention is the number of snapshots to keep. Older snapshots are
+	// deleted.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=7
+	// +optional
+	Retention int `json:"retention,omitempty"`
+}
+
+// SmbShareAuditSpec defines how the operations on a share are audited.
+type SmbShareAuditSpec struct {
+	// SuccessOperations lists the VFS operations that are logged when they
+	// succeed, for example "openat" or "renameat". If empty, connecting
+	// to the share and opening, renaming and deleting files are logged.
+	// +optional
+	SuccessOperations []string `json:"successOperations,omitempty"`
+
+	// FailureOperations lists the VFS operations that are logged when they
+	// fail. If empty, the same operations as for SuccessOperations are
+	// logged.
+	// +optional
+	FailureOperations []string `json:"failureOperations,omitempty"`
+
+	// Output selects where audit records are written. "stdout" writes them
+	// to the output of the smbd container, "sidecar" to the output of a
+	// dedicated audit-log container.
+	// +kubebuilder:validation:Enum:=stdout;sidecar
+	// +kubebuilder:default:=stdout
+	// +optional
+	Output string `json:"output,omitempty"`
+}
+
+const (
+	// AuditOutputStdout writes audit records to the output of the smbd
+	// container.
+	AuditOutputStdout = "stdout"
+	// AuditOutputSidecar writes audit records to the output of a
+	// dedicated container.
+	AuditOutputSidecar = "sidecar"
+)
+
+// SmbShareTimeMachineSpec defines how a share is offered to macOS clients
+// as a Time Machine backup destination.
+type SmbShareTimeMachineSpec struct {
+	// MaxSize limits the total size of the backups stored on the share.
+	// If unset the backups may fill the share's storage.
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+}
+
+// SmbSharePersistentVolumeSpec defines how an existing PersistentVolume
+// may be associated with a share.
+type SmbSharePersistentVolumeSpec struct {
+	// Name of the PersistentVolume to use for the share.
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// Path within the volume which should be exported.
+	// +kubebuilder:validation:Pattern=`^[^\/]+$`
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// SmbShareEphemeralSpec defines a generic ephemeral volume for a share.
+type SmbShareEphemeralSpec struct {
+	// Spec of the PVC created for the volume.
+	Spec corev1.PersistentVolumeClaimSpec `json:"spec"`
+
+	// Path within the volume which should be exported.
+	// +kubebuilder:validation:Pattern=`^[^\/]+$`
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// SmbShareCSISpec defines an inline CSI volume for a share.
+type SmbShareCSISpec struct {
+	corev1.CSIVolumeSource `json:",inline"`
+
+	// Path within the volume which should be exported.
+	// +kubebuilder:validation:Pattern=`^[^\/]+$`
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// SmbShareNFSSpec defines an NFS export backing a share.
+type SmbShareNFSSpec struct {
+	// Server is the hostname or IP address of the NFS server.
+	// +kubebuilder:validation:MinLength:=1
+	Server string `json:"server"`
+
+	// Path is the exported path on the NFS server.
+	// +kubebuilder:validation:MinLength:=1
+	Path string `json:"path"`
+}
+
 // SmbShareScalingSpec defines scaling parameters for a share.
 type SmbShareScalingSpec struct {
 	// AvailabilityMode specifies how the operator is to scale share resources
@@ -117,6 +326,12 @@ type SmbShareScalingSpec struct {
 	// MinClusterSize specifies the minimum number of smb server instances
 	// to establish when availabilityMode is "clustered".
 	MinClusterSize int `json:"minClusterSize,omitempty"`
+	// MaxClusterSize specifies the maximum number of smb server instances
+	// to establish when availabilityMode is "clustered". A cluster larger
+	// than this size will be scaled down. If unset, no maximum is applied.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxClusterSize int `json:"maxClusterSize,omitempty"`
 	// Group specifies the name of a server group that will host
 	// this share. If the group doesn't already exist it will be created.
 	// The value must be a valid Kubernetes resource name (RFC 1035 label).
@@ -140,16 +355,115 @@ type SmbShareStatus struct {
 	// servers hosting this share. The name is assigned by the operator but is
 	// frequently the same as the SmbShare resource's name.
 	ServerGroup string `json:"serverGroup,omitempty"`
+
+	// ShareName is the name of the share as it was last written to the
+	// server configuration. It is used to report when the share is
+	// renamed.
+	// +optional
+	ShareName string `json:"shareName,omitempty"`
+
+	// ObservedGeneration is the most recent generation of the SmbShare
+	// that the operator has fully processed.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Endpoint describes where clients can connect to the share.
+	// +optional
+	Endpoint *SmbShareEndpointStatus `json:"endpoint,omitempty"`
+
+	// Replicas is the number of smb server instances currently hosting
+	// the share.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector, in string form, matching the pods
+	// of the smb server instances hosting the share. It is used by the
+	// scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Capacity is the storage capacity of the PVC holding the share's
+	// data, as reported by the PVC.
+	// +optional
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+
+	// Conditions describe the current state of the resources hosting
+	// this share.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// SmbShareEndpointStatus describes the network endpoint of a share.
+type SmbShareEndpointStatus struct {
+	// ServiceName is the name of the Service fronting the smb servers.
+	ServiceName string `json:"serviceName,omitempty"`
+	// ClusterDNSName is the fully qualified in-cluster DNS name of the
+	// Service.
+	ClusterDNSName string `json:"clusterDNSName,omitempty"`
+	// ClusterIP is the cluster internal IP address of the Service.
+	// +optional
+	ClusterIP string `json:"clusterIP,omitempty"`
+	// ExternalIP is the ingress IP address assigned to the Service's
+	// load balancer. Only set when the share is published externally.
+	// +optional
+	ExternalIP string `json:"externalIP,omitempty"`
+	// ExternalHostname is the ingress hostname assigned to the Service's
+	// load balancer. Only set when the share is published externally.
+	// +optional
+	ExternalHostname string `json:"externalHostname,omitempty"`
+	// Port is the TCP port the Service listens on.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// UNC is a UNC path (\\host\share) that can be used to connect to
+	// the share. The external address is preferred when it is available.
+	// +optional
+	UNC string `json:"unc,omitempty"`
 }
 
+// Condition types reported in the SmbShare status.
+const (
+	// ShareConditionReady indicates the share is fully reconciled and
+	// is being served.
+	ShareConditionReady = "Ready"
+	// ShareConditionConfigApplied indicates the samba configuration for
+	// the share has been written to the server group's ConfigMap.
+	ShareConditionConfigApplied = "ConfigApplied"
+	// ShareConditionStorageBound indicates the PVC backing the share is
+	// bound to a volume.
+	ShareConditionStorageBound = "StorageBound"
+	// ShareConditionServerAvailable indicates at least one smb server
+	// instance hosting the share is ready.
+	ShareConditionServerAvailable = "ServerAvailable"
+	// ShareConditionDegraded indicates the operator has encountered
+	// errors or the servers hosting the share are only partially ready.
+	ShareConditionDegraded = "Degraded"
+	// ShareConditionConverting indicates the server group hosting the
+	// share is being converted between availability modes.
+	ShareConditionConverting = "Converting"
+	// ShareConditionResizing indicates the PVC backing the share is being
+	// expanded.
+	ShareConditionResizing = "Resizing"
+	// ShareConditionSizeLimited indicates the requested cluster size exceeds
+	// the maximum cluster size of the share.
+	ShareConditionSizeLimited = "SizeLimited"
+	// ShareConditionSnapshotsReady indicates the newest VolumeSnapshot of
+	// the share's PVC is ready to use.
+	ShareConditionSnapshotsReady = "SnapshotsReady"
+)
+
 // revive:disable:line-length-limit kubebuilder markers
 
 // nolint:lll
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:scale:specpath=.spec.scaling.minClusterSize,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:printcolumn:JSONPath=`.spec.shareName`,description="Name of the Samba share",name="Share-name",type=string
 // +kubebuilder:printcolumn:JSONPath=`.spec.storage.pvc.path`,description="Path for the share within PVC",name="Share-path",type=string
 // +kubebuilder:printcolumn:JSONPath=`.spec.scaling.availabilityMode`,description="Samba availability mode",name="Availability",type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="Share is ready",name="Ready",type=string
 // +kubebuilder:printcolumn:JSONPath=`.metadata.creationTimestamp`,name="Age",type=date
 
 // SmbShare is the Schema for the smbshares API
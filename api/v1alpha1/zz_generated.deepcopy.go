@@ -22,6 +22,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -52,6 +53,28 @@ func (in *SmbCommonConfig) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbCommonConfigGlobalConfig) DeepCopyInto(out *SmbCommonConfigGlobalConfig) {
+	*out = *in
+	if in.Configs != nil {
+		in, out := &in.Configs, &out.Configs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbCommonConfigGlobalConfig.
+func (in *SmbCommonConfigGlobalConfig) DeepCopy() *SmbCommonConfigGlobalConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbCommonConfigGlobalConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbCommonConfigList) DeepCopyInto(out *SmbCommonConfigList) {
 	*out = *in
@@ -120,6 +143,16 @@ func (in *SmbCommonConfigSpec) DeepCopyInto(out *SmbCommonConfigSpec) {
 		*out = new(SmbCommonConfigPodSettings)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Security != nil {
+		in, out := &in.Security, &out.Security
+		*out = new(SmbCommonSecuritySpec)
+		**out = **in
+	}
+	if in.CustomGlobalConfig != nil {
+		in, out := &in.CustomGlobalConfig, &out.CustomGlobalConfig
+		*out = new(SmbCommonConfigGlobalConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbCommonConfigSpec.
@@ -162,13 +195,43 @@ func (in *SmbCommonNetworkSpec) DeepCopy() *SmbCommonNetworkSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbCommonSecuritySpec) DeepCopyInto(out *SmbCommonSecuritySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbCommonSecuritySpec.
+func (in *SmbCommonSecuritySpec) DeepCopy() *SmbCommonSecuritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbCommonSecuritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbSecurityComputerAccountJoinSpec) DeepCopyInto(out *SmbSecurityComputerAccountJoinSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityComputerAccountJoinSpec.
+func (in *SmbSecurityComputerAccountJoinSpec) DeepCopy() *SmbSecurityComputerAccountJoinSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbSecurityComputerAccountJoinSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbSecurityConfig) DeepCopyInto(out *SmbSecurityConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityConfig.
@@ -236,6 +299,11 @@ func (in *SmbSecurityConfigSpec) DeepCopyInto(out *SmbSecurityConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.JoinOptions != nil {
+		in, out := &in.JoinOptions, &out.JoinOptions
+		*out = new(SmbSecurityJoinOptionsSpec)
+		**out = **in
+	}
 	if in.Domains != nil {
 		in, out := &in.Domains, &out.Domains
 		*out = make([]SmbSecurityDomainSpec, len(*in))
@@ -261,6 +329,23 @@ func (in *SmbSecurityConfigSpec) DeepCopy() *SmbSecurityConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbSecurityConfigStatus) DeepCopyInto(out *SmbSecurityConfigStatus) {
 	*out = *in
+	if in.Shares != nil {
+		in, out := &in.Shares, &out.Shares
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IDMapRanges != nil {
+		in, out := &in.IDMapRanges, &out.IDMapRanges
+		*out = make([]SmbSecurityIDMapRange, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityConfigStatus.
@@ -303,6 +388,36 @@ func (in *SmbSecurityDomainSpec) DeepCopy() *SmbSecurityDomainSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbSecurityIDMapRange) DeepCopyInto(out *SmbSecurityIDMapRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityIDMapRange.
+func (in *SmbSecurityIDMapRange) DeepCopy() *SmbSecurityIDMapRange {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbSecurityIDMapRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbSecurityJoinOptionsSpec) DeepCopyInto(out *SmbSecurityJoinOptionsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityJoinOptionsSpec.
+func (in *SmbSecurityJoinOptionsSpec) DeepCopy() *SmbSecurityJoinOptionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbSecurityJoinOptionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbSecurityJoinSpec) DeepCopyInto(out *SmbSecurityJoinSpec) {
 	*out = *in
@@ -311,6 +426,16 @@ func (in *SmbSecurityJoinSpec) DeepCopyInto(out *SmbSecurityJoinSpec) {
 		*out = new(SmbSecurityUserJoinSpec)
 		**out = **in
 	}
+	if in.KeytabJoin != nil {
+		in, out := &in.KeytabJoin, &out.KeytabJoin
+		*out = new(SmbSecurityKeytabJoinSpec)
+		**out = **in
+	}
+	if in.ComputerAccountJoin != nil {
+		in, out := &in.ComputerAccountJoin, &out.ComputerAccountJoin
+		*out = new(SmbSecurityComputerAccountJoinSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityJoinSpec.
@@ -323,6 +448,21 @@ func (in *SmbSecurityJoinSpec) DeepCopy() *SmbSecurityJoinSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbSecurityKeytabJoinSpec) DeepCopyInto(out *SmbSecurityKeytabJoinSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSecurityKeytabJoinSpec.
+func (in *SmbSecurityKeytabJoinSpec) DeepCopy() *SmbSecurityKeytabJoinSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbSecurityKeytabJoinSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbSecurityUserJoinSpec) DeepCopyInto(out *SmbSecurityUserJoinSpec) {
 	*out = *in
@@ -359,7 +499,7 @@ func (in *SmbShare) DeepCopyInto(out *SmbShare) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShare.
@@ -380,6 +520,100 @@ func (in *SmbShare) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareAuditSpec) DeepCopyInto(out *SmbShareAuditSpec) {
+	*out = *in
+	if in.SuccessOperations != nil {
+		in, out := &in.SuccessOperations, &out.SuccessOperations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureOperations != nil {
+		in, out := &in.FailureOperations, &out.FailureOperations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareAuditSpec.
+func (in *SmbShareAuditSpec) DeepCopy() *SmbShareAuditSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareAuditSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareCSISpec) DeepCopyInto(out *SmbShareCSISpec) {
+	*out = *in
+	in.CSIVolumeSource.DeepCopyInto(&out.CSIVolumeSource)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareCSISpec.
+func (in *SmbShareCSISpec) DeepCopy() *SmbShareCSISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareCSISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareConfig) DeepCopyInto(out *SmbShareConfig) {
+	*out = *in
+	if in.Configs != nil {
+		in, out := &in.Configs, &out.Configs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareConfig.
+func (in *SmbShareConfig) DeepCopy() *SmbShareConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareEndpointStatus) DeepCopyInto(out *SmbShareEndpointStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareEndpointStatus.
+func (in *SmbShareEndpointStatus) DeepCopy() *SmbShareEndpointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareEndpointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareEphemeralSpec) DeepCopyInto(out *SmbShareEphemeralSpec) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareEphemeralSpec.
+func (in *SmbShareEphemeralSpec) DeepCopy() *SmbShareEphemeralSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareEphemeralSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbShareList) DeepCopyInto(out *SmbShareList) {
 	*out = *in
@@ -412,6 +646,36 @@ func (in *SmbShareList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareNFSSpec) DeepCopyInto(out *SmbShareNFSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareNFSSpec.
+func (in *SmbShareNFSSpec) DeepCopy() *SmbShareNFSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareNFSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbSharePersistentVolumeSpec) DeepCopyInto(out *SmbSharePersistentVolumeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbSharePersistentVolumeSpec.
+func (in *SmbSharePersistentVolumeSpec) DeepCopy() *SmbSharePersistentVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbSharePersistentVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbSharePvcSpec) DeepCopyInto(out *SmbSharePvcSpec) {
 	*out = *in
@@ -447,10 +711,71 @@ func (in *SmbShareScalingSpec) DeepCopy() *SmbShareScalingSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareSnapshotsSpec) DeepCopyInto(out *SmbShareSnapshotsSpec) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareSnapshotsSpec.
+func (in *SmbShareSnapshotsSpec) DeepCopy() *SmbShareSnapshotsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareSnapshotsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbShareSpec) DeepCopyInto(out *SmbShareSpec) {
 	*out = *in
 	in.Storage.DeepCopyInto(&out.Storage)
+	if in.ValidUsers != nil {
+		in, out := &in.ValidUsers, &out.ValidUsers
+		*out = make([]AccessEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.InvalidUsers != nil {
+		in, out := &in.InvalidUsers, &out.InvalidUsers
+		*out = make([]AccessEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdminUsers != nil {
+		in, out := &in.AdminUsers, &out.AdminUsers
+		*out = make([]AccessEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadList != nil {
+		in, out := &in.ReadList, &out.ReadList
+		*out = make([]AccessEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.WriteList != nil {
+		in, out := &in.WriteList, &out.WriteList
+		*out = make([]AccessEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = new(SmbShareSnapshotsSpec)
+		**out = **in
+	}
+	if in.Audit != nil {
+		in, out := &in.Audit, &out.Audit
+		*out = new(SmbShareAuditSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TimeMachine != nil {
+		in, out := &in.TimeMachine, &out.TimeMachine
+		*out = new(SmbShareTimeMachineSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CustomShareConfig != nil {
+		in, out := &in.CustomShareConfig, &out.CustomShareConfig
+		*out = new(SmbShareConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Scaling != nil {
 		in, out := &in.Scaling, &out.Scaling
 		*out = new(SmbShareScalingSpec)
@@ -471,6 +796,23 @@ func (in *SmbShareSpec) DeepCopy() *SmbShareSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmbShareStatus) DeepCopyInto(out *SmbShareStatus) {
 	*out = *in
+	if in.Endpoint != nil {
+		in, out := &in.Endpoint, &out.Endpoint
+		*out = new(SmbShareEndpointStatus)
+		**out = **in
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareStatus.
@@ -491,6 +833,26 @@ func (in *SmbShareStorageSpec) DeepCopyInto(out *SmbShareStorageSpec) {
 		*out = new(SmbSharePvcSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PersistentVolume != nil {
+		in, out := &in.PersistentVolume, &out.PersistentVolume
+		*out = new(SmbSharePersistentVolumeSpec)
+		**out = **in
+	}
+	if in.Ephemeral != nil {
+		in, out := &in.Ephemeral, &out.Ephemeral
+		*out = new(SmbShareEphemeralSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(SmbShareCSISpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NFS != nil {
+		in, out := &in.NFS, &out.NFS
+		*out = new(SmbShareNFSSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareStorageSpec.
@@ -502,3 +864,23 @@ func (in *SmbShareStorageSpec) DeepCopy() *SmbShareStorageSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmbShareTimeMachineSpec) DeepCopyInto(out *SmbShareTimeMachineSpec) {
+	*out = *in
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmbShareTimeMachineSpec.
+func (in *SmbShareTimeMachineSpec) DeepCopy() *SmbShareTimeMachineSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmbShareTimeMachineSpec)
+	in.DeepCopyInto(out)
+	return out
+}
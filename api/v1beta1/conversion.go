@@ -0,0 +1,326 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"fmt"
+	"strconv"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+// The v1alpha1 types are the conversion hub. Nested types that are
+// identical in both versions are converted directly, all others field by
+// field. Values that v1alpha1 stores in annotations are moved into, and
+// out of, the fields that replace them.
+
+// ConvertTo converts the SmbShare to the hub version.
+func (src *SmbShare) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.SmbShare)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", dstRaw)
+	}
+	s := src.DeepCopy()
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec = v1alpha1.SmbShareSpec{
+		ShareName:      s.Spec.ShareName,
+		NetbiosName:    s.Spec.NetbiosName,
+		Storage:        storageToHub(s.Spec.Storage),
+		ReadOnly:       s.Spec.ReadOnly,
+		Browseable:     s.Spec.Browseable,
+		SecurityConfig: s.Spec.SecurityConfig,
+		CommonConfig:   s.Spec.CommonConfig,
+		ValidUsers:     convertEntries[v1alpha1.AccessEntry](s.Spec.ValidUsers),
+		InvalidUsers:   convertEntries[v1alpha1.AccessEntry](s.Spec.InvalidUsers),
+		AdminUsers:     convertEntries[v1alpha1.AccessEntry](s.Spec.AdminUsers),
+		ReadList:       convertEntries[v1alpha1.AccessEntry](s.Spec.ReadList),
+		WriteList:      convertEntries[v1alpha1.AccessEntry](s.Spec.WriteList),
+		Encryption:     s.Spec.Encryption,
+		Snapshots:      (*v1alpha1.SmbShareSnapshotsSpec)(s.Spec.Snapshots),
+
+		Audit:             (*v1alpha1.SmbShareAuditSpec)(s.Spec.Audit),
+		TimeMachine:       (*v1alpha1.SmbShareTimeMachineSpec)(s.Spec.TimeMachine),
+		CustomShareConfig: (*v1alpha1.SmbShareConfig)(s.Spec.CustomShareConfig),
+	}
+	delete(dst.Annotations, v1alpha1.NodeSpreadAnnotation)
+	if sc := s.Spec.Scaling; sc != nil {
+		if sc.NodeSpread != nil {
+			setAnnotation(&dst.ObjectMeta.Annotations,
+				v1alpha1.NodeSpreadAnnotation, strconv.FormatBool(*sc.NodeSpread))
+		}
+		scaling := v1alpha1.SmbShareScalingSpec{
+			AvailabilityMode: string(sc.AvailabilityMode),
+			MinClusterSize:   sc.MinClusterSize,
+			MaxClusterSize:   sc.MaxClusterSize,
+			Group:            sc.Group,
+			GroupMode:        string(sc.GroupMode),
+		}
+		// a scaling block holding only the node spread is fully
+		// represented by the annotation
+		if scaling != (v1alpha1.SmbShareScalingSpec{}) || sc.NodeSpread == nil {
+			dst.Spec.Scaling = &scaling
+		}
+	}
+	dst.Status = v1alpha1.SmbShareStatus{
+		ServerGroup:        s.Status.ServerGroup,
+		ShareName:          s.Status.ShareName,
+		ObservedGeneration: s.Status.ObservedGeneration,
+		Endpoint:           (*v1alpha1.SmbShareEndpointStatus)(s.Status.Endpoint),
+		Replicas:           s.Status.Replicas,
+		Selector:           s.Status.Selector,
+		Capacity:           s.Status.Capacity,
+		Conditions:         s.Status.Conditions,
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version to an SmbShare.
+func (dst *SmbShare) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.SmbShare)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", srcRaw)
+	}
+	s := src.DeepCopy()
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec = SmbShareSpec{
+		ShareName:      s.Spec.ShareName,
+		NetbiosName:    s.Spec.NetbiosName,
+		Storage:        storageFromHub(s.Spec.Storage),
+		ReadOnly:       s.Spec.ReadOnly,
+		Browseable:     s.Spec.Browseable,
+		SecurityConfig: s.Spec.SecurityConfig,
+		CommonConfig:   s.Spec.CommonConfig,
+		ValidUsers:     convertEntries[AccessEntry](s.Spec.ValidUsers),
+		InvalidUsers:   convertEntries[AccessEntry](s.Spec.InvalidUsers),
+		AdminUsers:     convertEntries[AccessEntry](s.Spec.AdminUsers),
+		ReadList:       convertEntries[AccessEntry](s.Spec.ReadList),
+		WriteList:      convertEntries[AccessEntry](s.Spec.WriteList),
+		Encryption:     s.Spec.Encryption,
+		Snapshots:      (*SmbShareSnapshotsSpec)(s.Spec.Snapshots),
+
+		Audit:             (*SmbShareAuditSpec)(s.Spec.Audit),
+		TimeMachine:       (*SmbShareTimeMachineSpec)(s.Spec.TimeMachine),
+		CustomShareConfig: (*SmbShareConfig)(s.Spec.CustomShareConfig),
+	}
+	if sc := s.Spec.Scaling; sc != nil {
+		dst.Spec.Scaling = &SmbShareScalingSpec{
+			AvailabilityMode: AvailabilityMode(sc.AvailabilityMode),
+			MinClusterSize:   sc.MinClusterSize,
+			MaxClusterSize:   sc.MaxClusterSize,
+			Group:            sc.Group,
+			GroupMode:        GroupMode(sc.GroupMode),
+		}
+	}
+	if v, found := s.Annotations[v1alpha1.NodeSpreadAnnotation]; found {
+		// any value other than "false" enables the node spread
+		spread := v != "false"
+		if dst.Spec.Scaling == nil {
+			dst.Spec.Scaling = &SmbShareScalingSpec{}
+		}
+		dst.Spec.Scaling.NodeSpread = &spread
+		delete(dst.Annotations, v1alpha1.NodeSpreadAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+	dst.Status = SmbShareStatus{
+		ServerGroup:        s.Status.ServerGroup,
+		ShareName:          s.Status.ShareName,
+		ObservedGeneration: s.Status.ObservedGeneration,
+		Endpoint:           (*SmbShareEndpointStatus)(s.Status.Endpoint),
+		Replicas:           s.Status.Replicas,
+		Selector:           s.Status.Selector,
+		Capacity:           s.Status.Capacity,
+		Conditions:         s.Status.Conditions,
+	}
+	return nil
+}
+
+// ConvertTo converts the SmbSecurityConfig to the hub version.
+func (src *SmbSecurityConfig) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.SmbSecurityConfig)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", dstRaw)
+	}
+	s := src.DeepCopy()
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec = v1alpha1.SmbSecurityConfigSpec{
+		Mode:      string(s.Spec.Mode),
+		Users:     (*v1alpha1.SmbSecurityUsersSpec)(s.Spec.Users),
+		Realm:     s.Spec.Realm,
+		Workgroup: s.Spec.Workgroup,
+	}
+	dst.Status = v1alpha1.SmbSecurityConfigStatus{
+		Workgroup:  s.Status.Workgroup,
+		Shares:     s.Status.Shares,
+		Conditions: s.Status.Conditions,
+	}
+	for _, r := range s.Status.IDMapRanges {
+		dst.Status.IDMapRanges = append(dst.Status.IDMapRanges,
+			v1alpha1.SmbSecurityIDMapRange(r))
+	}
+	for _, j := range s.Spec.JoinSources {
+		dst.Spec.JoinSources = append(dst.Spec.JoinSources,
+			v1alpha1.SmbSecurityJoinSpec{
+				UserJoin: (*v1alpha1.SmbSecurityUserJoinSpec)(j.UserJoin),
+				KeytabJoin: (*v1alpha1.SmbSecurityKeytabJoinSpec)(
+					j.KeytabJoin),
+				ComputerAccountJoin: (*v1alpha1.SmbSecurityComputerAccountJoinSpec)(
+					j.ComputerAccountJoin),
+			})
+	}
+	dst.Spec.JoinOptions = (*v1alpha1.SmbSecurityJoinOptionsSpec)(
+		s.Spec.JoinOptions)
+	for _, d := range s.Spec.Domains {
+		dst.Spec.Domains = append(dst.Spec.Domains,
+			v1alpha1.SmbSecurityDomainSpec{
+				Name:             d.Name,
+				Backend:          string(d.Backend),
+				RangeStart:       d.RangeStart,
+				RangeSize:        d.RangeSize,
+				UnixPrimaryGroup: d.UnixPrimaryGroup,
+				UnixNSSInfo:      d.UnixNSSInfo,
+			})
+	}
+	if s.Spec.DNS != nil {
+		dst.Spec.DNS = &v1alpha1.SmbSecurityDNSSpec{
+			Register: string(s.Spec.DNS.Register),
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version to an SmbSecurityConfig.
+func (dst *SmbSecurityConfig) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.SmbSecurityConfig)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", srcRaw)
+	}
+	s := src.DeepCopy()
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec = SmbSecurityConfigSpec{
+		Mode:      SecurityMode(s.Spec.Mode),
+		Users:     (*SmbSecurityUsersSpec)(s.Spec.Users),
+		Realm:     s.Spec.Realm,
+		Workgroup: s.Spec.Workgroup,
+	}
+	dst.Status = SmbSecurityConfigStatus{
+		Workgroup:  s.Status.Workgroup,
+		Shares:     s.Status.Shares,
+		Conditions: s.Status.Conditions,
+	}
+	for _, r := range s.Status.IDMapRanges {
+		dst.Status.IDMapRanges = append(dst.Status.IDMapRanges,
+			SmbSecurityIDMapRange(r))
+	}
+	for _, j := range s.Spec.JoinSources {
+		dst.Spec.JoinSources = append(dst.Spec.JoinSources,
+			SmbSecurityJoinSpec{
+				UserJoin:   (*SmbSecurityUserJoinSpec)(j.UserJoin),
+				KeytabJoin: (*SmbSecurityKeytabJoinSpec)(j.KeytabJoin),
+				ComputerAccountJoin: (*SmbSecurityComputerAccountJoinSpec)(
+					j.ComputerAccountJoin),
+			})
+	}
+	dst.Spec.JoinOptions = (*SmbSecurityJoinOptionsSpec)(s.Spec.JoinOptions)
+	for _, d := range s.Spec.Domains {
+		dst.Spec.Domains = append(dst.Spec.Domains,
+			SmbSecurityDomainSpec{
+				Name:             d.Name,
+				Backend:          IDMapBackend(d.Backend),
+				RangeStart:       d.RangeStart,
+				RangeSize:        d.RangeSize,
+				UnixPrimaryGroup: d.UnixPrimaryGroup,
+				UnixNSSInfo:      d.UnixNSSInfo,
+			})
+	}
+	if s.Spec.DNS != nil {
+		dst.Spec.DNS = &SmbSecurityDNSSpec{
+			Register: DNSRegisterMode(s.Spec.DNS.Register),
+		}
+	}
+	return nil
+}
+
+// ConvertTo converts the SmbCommonConfig to the hub version.
+func (src *SmbCommonConfig) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.SmbCommonConfig)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", dstRaw)
+	}
+	s := src.DeepCopy()
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec = v1alpha1.SmbCommonConfigSpec{
+		Network: v1alpha1.SmbCommonNetworkSpec{
+			Publish: string(s.Spec.Network.Publish),
+		},
+		PodSettings: (*v1alpha1.SmbCommonConfigPodSettings)(s.Spec.PodSettings),
+		Security:    (*v1alpha1.SmbCommonSecuritySpec)(s.Spec.Security),
+
+		CustomGlobalConfig: (*v1alpha1.SmbCommonConfigGlobalConfig)(
+			s.Spec.CustomGlobalConfig),
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version to an SmbCommonConfig.
+func (dst *SmbCommonConfig) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.SmbCommonConfig)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", srcRaw)
+	}
+	s := src.DeepCopy()
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec = SmbCommonConfigSpec{
+		Network: SmbCommonNetworkSpec{
+			Publish: PublishMode(s.Spec.Network.Publish),
+		},
+		PodSettings: (*SmbCommonConfigPodSettings)(s.Spec.PodSettings),
+		Security:    (*SmbCommonSecuritySpec)(s.Spec.Security),
+
+		CustomGlobalConfig: (*SmbCommonConfigGlobalConfig)(
+			s.Spec.CustomGlobalConfig),
+	}
+	return nil
+}
+
+func storageToHub(st SmbShareStorageSpec) v1alpha1.SmbShareStorageSpec {
+	return v1alpha1.SmbShareStorageSpec{
+		Pvc:              (*v1alpha1.SmbSharePvcSpec)(st.Pvc),
+		PersistentVolume: (*v1alpha1.SmbSharePersistentVolumeSpec)(st.PersistentVolume),
+		Ephemeral:        (*v1alpha1.SmbShareEphemeralSpec)(st.Ephemeral),
+		CSI:              (*v1alpha1.SmbShareCSISpec)(st.CSI),
+		NFS:              (*v1alpha1.SmbShareNFSSpec)(st.NFS),
+	}
+}
+
+func storageFromHub(st v1alpha1.SmbShareStorageSpec) SmbShareStorageSpec {
+	return SmbShareStorageSpec{
+		Pvc:              (*SmbSharePvcSpec)(st.Pvc),
+		PersistentVolume: (*SmbSharePersistentVolumeSpec)(st.PersistentVolume),
+		Ephemeral:        (*SmbShareEphemeralSpec)(st.Ephemeral),
+		CSI:              (*SmbShareCSISpec)(st.CSI),
+		NFS:              (*SmbShareNFSSpec)(st.NFS),
+	}
+}
+
+// convertEntries converts a list of access entries between the versions.
+func convertEntries[D, S ~string](src []S) []D {
+	if src == nil {
+		return nil
+	}
+	dst := make([]D, len(src))
+	for i := range src {
+		dst[i] = D(src[i])
+	}
+	return dst
+}
+
+func setAnnotation(annotations *map[string]string, key, value string) {
+	if *annotations == nil {
+		*annotations = map[string]string{}
+	}
+	(*annotations)[key] = value
+}
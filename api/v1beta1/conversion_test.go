@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+func TestIsConvertible(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, AddToScheme(scheme))
+	for _, obj := range []runtime.Object{
+		&v1alpha1.SmbShare{},
+		&v1alpha1.SmbSecurityConfig{},
+		&v1alpha1.SmbCommonConfig{},
+	} {
+		ok, err := conversion.IsConvertible(scheme, obj)
+		assert.NoError(t, err)
+		assert.True(t, ok, "%T not convertible", obj)
+	}
+}
+
+func TestConvertSmbShare(t *testing.T) {
+	maxSize := resource.MustParse("2Ti")
+	alpha := &v1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tshare",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"example.com/keep":            "yes",
+				v1alpha1.NodeSpreadAnnotation: "false",
+			},
+		},
+		Spec: v1alpha1.SmbShareSpec{
+			ShareName:      "Data",
+			NetbiosName:    "FILES",
+			Browseable:     true,
+			SecurityConfig: "sec1",
+			ValidUsers:     []v1alpha1.AccessEntry{"alice", `@EXAMPLE\staff`},
+			WriteList:      []v1alpha1.AccessEntry{"alice"},
+			Snapshots: &v1alpha1.SmbShareSnapshotsSpec{
+				Interval:  metav1.Duration{Duration: 6 * time.Hour},
+				Retention: 4,
+			},
+			Encryption: "required",
+			Audit: &v1alpha1.SmbShareAuditSpec{
+				SuccessOperations: []string{"renameat"},
+				Output:            v1alpha1.AuditOutputSidecar,
+			},
+			TimeMachine: &v1alpha1.SmbShareTimeMachineSpec{
+				MaxSize: &maxSize,
+			},
+			Storage: v1alpha1.SmbShareStorageSpec{
+				Pvc: &v1alpha1.SmbSharePvcSpec{
+					Spec: &corev1.PersistentVolumeClaimSpec{
+						AccessModes: []corev1.PersistentVolumeAccessMode{
+							corev1.ReadWriteMany,
+						},
+					},
+					Path: "data",
+				},
+			},
+			CustomShareConfig: &v1alpha1.SmbShareConfig{
+				UseUnsafeCustomConfig: true,
+				Configs:               map[string]string{"x:y": "z"},
+			},
+			Scaling: &v1alpha1.SmbShareScalingSpec{
+				AvailabilityMode: "clustered",
+				MinClusterSize:   2,
+				MaxClusterSize:   4,
+				GroupMode:        "explicit",
+				Group:            "g1",
+			},
+		},
+		Status: v1alpha1.SmbShareStatus{
+			ServerGroup: "g1",
+			ShareName:   "Data",
+			Replicas:    2,
+			Endpoint: &v1alpha1.SmbShareEndpointStatus{
+				ServiceName: "g1",
+				Port:        445,
+			},
+		},
+	}
+
+	beta := &SmbShare{}
+	assert.NoError(t, beta.ConvertFrom(alpha))
+	assert.Equal(t, AvailabilityClustered, beta.Spec.Scaling.AvailabilityMode)
+	assert.Equal(t, GroupModeExplicit, beta.Spec.Scaling.GroupMode)
+	if assert.NotNil(t, beta.Spec.Scaling.NodeSpread) {
+		assert.False(t, *beta.Spec.Scaling.NodeSpread)
+	}
+	assert.NotContains(t, beta.Annotations, v1alpha1.NodeSpreadAnnotation)
+	assert.Equal(t, "yes", beta.Annotations["example.com/keep"])
+	assert.Equal(t, "data", beta.Spec.Storage.Pvc.Path)
+	assert.Equal(t, []AccessEntry{"alice", `@EXAMPLE\staff`}, beta.Spec.ValidUsers)
+	assert.Nil(t, beta.Spec.AdminUsers)
+	assert.Equal(t, 4, beta.Spec.Snapshots.Retention)
+	assert.Equal(t, "2Ti", beta.Spec.TimeMachine.MaxSize.String())
+	assert.Equal(t, "required", beta.Spec.Encryption)
+	assert.Equal(t, []string{"renameat"}, beta.Spec.Audit.SuccessOperations)
+	assert.Equal(t, int32(445), beta.Status.Endpoint.Port)
+
+	// converting must not modify the source
+	assert.Contains(t, alpha.Annotations, v1alpha1.NodeSpreadAnnotation)
+
+	back := &v1alpha1.SmbShare{}
+	assert.NoError(t, beta.ConvertTo(back))
+	assert.Equal(t, alpha, back)
+}
+
+func TestConvertSmbShareStorage(t *testing.T) {
+	alpha := &v1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{Name: "tshare"},
+		Spec: v1alpha1.SmbShareSpec{
+			Storage: v1alpha1.SmbShareStorageSpec{
+				NFS: &v1alpha1.SmbShareNFSSpec{
+					Server: "nfs.example.com",
+					Path:   "/exports/data",
+				},
+			},
+		},
+	}
+	beta := &SmbShare{}
+	assert.NoError(t, beta.ConvertFrom(alpha))
+	assert.Equal(t, "nfs.example.com", beta.Spec.Storage.NFS.Server)
+	back := &v1alpha1.SmbShare{}
+	assert.NoError(t, beta.ConvertTo(back))
+	assert.Equal(t, alpha, back)
+
+	alpha.Spec.Storage = v1alpha1.SmbShareStorageSpec{
+		CSI: &v1alpha1.SmbShareCSISpec{
+			CSIVolumeSource: corev1.CSIVolumeSource{
+				Driver:           "csi.example.com",
+				VolumeAttributes: map[string]string{"share": "data"},
+			},
+			Path: "sub",
+		},
+	}
+	beta = &SmbShare{}
+	assert.NoError(t, beta.ConvertFrom(alpha))
+	assert.Equal(t, "csi.example.com", beta.Spec.Storage.CSI.Driver)
+	back = &v1alpha1.SmbShare{}
+	assert.NoError(t, beta.ConvertTo(back))
+	assert.Equal(t, alpha, back)
+}
+
+func TestConvertSmbShareNodeSpread(t *testing.T) {
+	spread := true
+	beta := &SmbShare{
+		ObjectMeta: metav1.ObjectMeta{Name: "tshare"},
+		Spec: SmbShareSpec{
+			Scaling: &SmbShareScalingSpec{NodeSpread: &spread},
+		},
+	}
+	alpha := &v1alpha1.SmbShare{}
+	assert.NoError(t, beta.ConvertTo(alpha))
+	assert.Nil(t, alpha.Spec.Scaling)
+	assert.Equal(t, "true", alpha.Annotations[v1alpha1.NodeSpreadAnnotation])
+
+	back := &SmbShare{}
+	assert.NoError(t, back.ConvertFrom(alpha))
+	assert.Equal(t, beta, back)
+
+	// no scaling block and no annotation
+	beta = &SmbShare{ObjectMeta: metav1.ObjectMeta{Name: "tshare"}}
+	alpha = &v1alpha1.SmbShare{}
+	assert.NoError(t, beta.ConvertTo(alpha))
+	assert.Nil(t, alpha.Spec.Scaling)
+	assert.Empty(t, alpha.Annotations)
+}
+
+func TestConvertSmbSecurityConfig(t *testing.T) {
+	alpha := &v1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "sec1"},
+		Spec: v1alpha1.SmbSecurityConfigSpec{
+			Mode:      "active-directory",
+			Realm:     "cool.example.net",
+			Workgroup: "COOLNET",
+			JoinSources: []v1alpha1.SmbSecurityJoinSpec{
+				{UserJoin: &v1alpha1.SmbSecurityUserJoinSpec{
+					Secret: "join1",
+					Key:    "join.json",
+				}},
+				{KeytabJoin: &v1alpha1.SmbSecurityKeytabJoinSpec{
+					Secret: "keytab1",
+					Key:    "krb5.keytab",
+				}},
+				{ComputerAccountJoin: &v1alpha1.SmbSecurityComputerAccountJoinSpec{
+					Secret: "otp1",
+					Key:    "password",
+				}},
+			},
+			JoinOptions: &v1alpha1.SmbSecurityJoinOptionsSpec{
+				OrganizationalUnit:  "Servers",
+				ComputerAccountName: "COOLFILES",
+			},
+			Domains: []v1alpha1.SmbSecurityDomainSpec{
+				{
+					Name:             "COOL",
+					Backend:          "ad-rfc2307",
+					RangeStart:       100000,
+					RangeSize:        50000,
+					UnixPrimaryGroup: true,
+					UnixNSSInfo:      true,
+				},
+			},
+			DNS: &v1alpha1.SmbSecurityDNSSpec{Register: "external-ip"},
+		},
+		Status: v1alpha1.SmbSecurityConfigStatus{
+			Workgroup: "COOL",
+			Shares:    []string{"share1", "share2"},
+			IDMapRanges: []v1alpha1.SmbSecurityIDMapRange{
+				{Name: "COOL", RangeStart: 100000, RangeSize: 50000},
+				{Name: "*", RangeStart: 2000, RangeSize: 10000},
+			},
+			Conditions: []metav1.Condition{{
+				Type:   v1alpha1.SecurityConfigConditionReady,
+				Status: metav1.ConditionTrue,
+				Reason: "Valid",
+			}},
+		},
+	}
+
+	beta := &SmbSecurityConfig{}
+	assert.NoError(t, beta.ConvertFrom(alpha))
+	assert.Equal(t, SecurityModeActiveDirectory, beta.Spec.Mode)
+	assert.Equal(t, IDMapBackendADRFC2307, beta.Spec.Domains[0].Backend)
+	assert.Equal(t, 100000, beta.Spec.Domains[0].RangeStart)
+	assert.Equal(t, DNSRegisterExternalIP, beta.Spec.DNS.Register)
+	assert.Equal(t, "keytab1", beta.Spec.JoinSources[1].KeytabJoin.Secret)
+	assert.Equal(t, "Servers", beta.Spec.JoinOptions.OrganizationalUnit)
+	assert.Equal(t, alpha.Status.Shares, beta.Status.Shares)
+	assert.Equal(t, 100000, beta.Status.IDMapRanges[0].RangeStart)
+
+	back := &v1alpha1.SmbSecurityConfig{}
+	assert.NoError(t, beta.ConvertTo(back))
+	assert.Equal(t, alpha, back)
+}
+
+func TestConvertSmbCommonConfig(t *testing.T) {
+	alpha := &v1alpha1.SmbCommonConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "common1"},
+		Spec: v1alpha1.SmbCommonConfigSpec{
+			Network: v1alpha1.SmbCommonNetworkSpec{Publish: "external"},
+			PodSettings: &v1alpha1.SmbCommonConfigPodSettings{
+				NodeSelector: map[string]string{"zone": "a"},
+			},
+		},
+	}
+
+	beta := &SmbCommonConfig{}
+	assert.NoError(t, beta.ConvertFrom(alpha))
+	assert.Equal(t, PublishExternal, beta.Spec.Network.Publish)
+	assert.Equal(t, "a", beta.Spec.PodSettings.NodeSelector["zone"])
+
+	back := &v1alpha1.SmbCommonConfig{}
+	assert.NoError(t, beta.ConvertTo(back))
+	assert.Equal(t, alpha, back)
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PublishMode specifies how the services hosting shares are reachable.
+// +kubebuilder:validation:Enum:=cluster;external
+type PublishMode string
+
+const (
+	// PublishCluster makes shares reachable from within the cluster.
+	PublishCluster = PublishMode("cluster")
+	// PublishExternal makes shares reachable from outside of the cluster.
+	PublishExternal = PublishMode("external")
+)
+
+// SmbCommonConfigSpec values act as a template for properties of the services
+// that will host shares.
+type SmbCommonConfigSpec struct {
+	// Network specifies what kind of networking shares associated with
+	// this config will use.
+	// +kubebuilder:validation:Required
+	Network SmbCommonNetworkSpec `json:"network,omitempty"`
+
+	// PodSettings are configuration values that are applied to pods that
+	// the operator may create in order to host shares. The values specified
+	// under PodSettings allow admins and users to customize how pods
+	// are scheduled in a kubernetes cluster.
+	// +optional
+	PodSettings *SmbCommonConfigPodSettings `json:"podSettings,omitempty"`
+
+	// Security configures encryption, signing and the range of SMB
+	// protocol versions of the servers.
+	// +optional
+	Security *SmbCommonSecuritySpec `json:"security,omitempty"`
+
+	// CustomGlobalConfig are configuration values that are applied to
+	// [global] section in smb.conf for the smb server. This allows users to
+	// add or override default configurations.
+	// +optional
+	CustomGlobalConfig *SmbCommonConfigGlobalConfig `json:"customGlobalConfig,omitempty"`
+}
+
+// SmbCommonSecuritySpec configures the security of the SMB protocol used
+// by the servers hosting shares.
+type SmbCommonSecuritySpec struct {
+	// Encryption controls whether the servers encrypt SMB3 traffic.
+	// "desired" offers encryption to clients, "required" rejects clients
+	// that do not support encryption.
+	// +kubebuilder:validation:Enum:=off;desired;required
+	// +optional
+	Encryption string `json:"encryption,omitempty"`
+
+	// Signing controls whether the servers sign SMB packets.
+	// "desired" offers signing to clients, "required" rejects clients
+	// that do not sign.
+	// +kubebuilder:validation:Enum:=off;desired;required
+	// +optional
+	Signing string `json:"signing,omitempty"`
+
+	// MinProtocol is the oldest protocol version the servers accept.
+	// +kubebuilder:validation:Enum:=NT1;SMB2;SMB2_02;SMB2_10;SMB3;SMB3_00;SMB3_02;SMB3_11
+	// +optional
+	MinProtocol string `json:"minProtocol,omitempty"`
+
+	// MaxProtocol is the newest protocol version the servers accept.
+	// +kubebuilder:validation:Enum:=NT1;SMB2;SMB2_02;SMB2_10;SMB3;SMB3_00;SMB3_02;SMB3_11
+	// +optional
+	MaxProtocol string `json:"maxProtocol,omitempty"`
+}
+
+// SmbCommonNetworkSpec values define networking properties for the services
+// that will host shares.
+type SmbCommonNetworkSpec struct {
+	// Publish broadly specifies what kind of networking shares associated with
+	// this config are expected to use.
+	// +kubebuilder:validation:Required
+	Publish PublishMode `json:"publish,omitempty"`
+}
+
+// SmbCommonConfigPodSettings contains values pertaining to the customization
+// of pods created by the samba operator.
+type SmbCommonConfigPodSettings struct {
+	// NodeSelector values will be assigned to a PodSpec's NodeSelector.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Affinity values will be used as defaults for pods created by the
+	// samba operator.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+}
+
+// SmbCommonConfigGlobalConfig contains values for customizing configs in
+// [global] section in smb.conf
+type SmbCommonConfigGlobalConfig struct {
+	// UseUnsafeCustomConfig must be set for the custom configs to be
+	// applied.
+	UseUnsafeCustomConfig bool `json:"useUnsafeCustomConfig,omitempty"`
+	// Configs specify keys and values to smb.conf
+	Configs map[string]string `json:"configs,omitempty"`
+}
+
+// SmbCommonConfigStatus defines the observed state of SmbCommonConfig
+type SmbCommonConfigStatus struct{}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:unservedversion
+
+// SmbCommonConfig is the Schema for the smbcommonconfigs API
+type SmbCommonConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SmbCommonConfigSpec   `json:"spec,omitempty"`
+	Status SmbCommonConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SmbCommonConfigList contains a list of SmbCommonConfig
+type SmbCommonConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SmbCommonConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SmbCommonConfig{}, &SmbCommonConfigList{})
+}
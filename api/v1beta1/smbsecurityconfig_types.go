@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecurityMode specifies the approach to security used by the servers.
+// +kubebuilder:validation:Enum:=user;active-directory
+type SecurityMode string
+
+const (
+	// SecurityModeUser means users and groups are locally configured.
+	SecurityModeUser = SecurityMode("user")
+	// SecurityModeActiveDirectory means users and groups are sourced from
+	// an Active Directory domain.
+	SecurityModeActiveDirectory = SecurityMode("active-directory")
+)
+
+// IDMapBackend specifies how samba maps the IDs of a domain's users and
+// groups.
+// +kubebuilder:validation:Enum:=autorid;ad-rfc2307;ad;rid;tdb;nss
+type IDMapBackend string
+
+const (
+	// IDMapBackendAutoRID allocates IDs automatically.
+	IDMapBackendAutoRID = IDMapBackend("autorid")
+	// IDMapBackendADRFC2307 uses the RFC2307 attributes stored in the
+	// domain.
+	IDMapBackendADRFC2307 = IDMapBackend("ad-rfc2307")
+	// IDMapBackendAD uses the RFC2307 attributes stored in the domain.
+	// It is the same as IDMapBackendADRFC2307.
+	IDMapBackendAD = IDMapBackend("ad")
+	// IDMapBackendRID derives IDs from the RIDs of the domain's users and
+	// groups.
+	IDMapBackendRID = IDMapBackend("rid")
+	// IDMapBackendTDB allocates IDs and stores them locally.
+	IDMapBackendTDB = IDMapBackend("tdb")
+	// IDMapBackendNSS looks up IDs with the name service switch of the
+	// server.
+	IDMapBackendNSS = IDMapBackend("nss")
+)
+
+// DNSRegisterMode specifies what address a server registers with the
+// domain's DNS.
+// +kubebuilder:validation:Enum:=never;external-ip;cluster-ip
+type DNSRegisterMode string
+
+const (
+	// DNSRegisterNever disables DNS registration.
+	DNSRegisterNever = DNSRegisterMode("never")
+	// DNSRegisterExternalIP registers the external IP address.
+	DNSRegisterExternalIP = DNSRegisterMode("external-ip")
+	// DNSRegisterClusterIP registers the in-cluster IP address.
+	DNSRegisterClusterIP = DNSRegisterMode("cluster-ip")
+)
+
+// SmbSecurityConfigSpec defines the desired state of SmbSecurityConfig
+type SmbSecurityConfigSpec struct {
+	// Mode specifies what approach to security is being used.
+	// +kubebuilder:validation:Required
+	Mode SecurityMode `json:"mode,omitempty"`
+
+	// Users is used to configure "local" user and group based security.
+	// +optional
+	Users *SmbSecurityUsersSpec `json:"users,omitempty"`
+
+	// Realm specifies the active directory domain to use.
+	// +optional
+	Realm string `json:"realm,omitempty"`
+
+	// Workgroup specifies the NetBIOS name of the active directory domain.
+	// If unset, the operator discovers the name from the domain.
+	// +kubebuilder:validation:MaxLength:=15
+	// +optional
+	Workgroup string `json:"workgroup,omitempty"`
+
+	// JoinSources holds a list of sources for domain join data for
+	// this configuration.
+	// +optional
+	JoinSources []SmbSecurityJoinSpec `json:"joinSources,omitempty"`
+
+	// JoinOptions configures how server instances are joined to the domain.
+	// +optional
+	JoinOptions *SmbSecurityJoinOptionsSpec `json:"joinOptions,omitempty"`
+
+	// Domains holds a list of primary & trusted domain configurations.
+	// If left empty a simple default that automatically works with
+	// trusted domains will be used.
+	// +optional
+	Domains []SmbSecurityDomainSpec `json:"domains,omitempty"`
+
+	// DNS is used to configure properties related to the DNS services
+	// of the domain.
+	// +optional
+	DNS *SmbSecurityDNSSpec `json:"dns,omitempty"`
+}
+
+// SmbSecurityUsersSpec configures user level security.
+type SmbSecurityUsersSpec struct {
+	// Secret identifies the name of the secret storing user and group
+	// configuration json.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Secret string `json:"secret,omitempty"`
+
+	// Key identifies the key within the secret that stores the user and
+	// group configuration json.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Key string `json:"key,omitempty"`
+}
+
+// SmbSecurityJoinSpec configures how samba instances are allowed to
+// join to active directory if needed.
+type SmbSecurityJoinSpec struct {
+	// UserJoin joins the domain using the credentials of a user.
+	// +optional
+	UserJoin *SmbSecurityUserJoinSpec `json:"userJoin,omitempty"`
+
+	// KeytabJoin joins the domain using a Kerberos keytab.
+	// +optional
+	KeytabJoin *SmbSecurityKeytabJoinSpec `json:"keytabJoin,omitempty"`
+
+	// ComputerAccountJoin joins the domain using the one-time password of
+	// a pre-created computer account.
+	// +optional
+	ComputerAccountJoin *SmbSecurityComputerAccountJoinSpec `json:"computerAccountJoin,omitempty"`
+}
+
+// SmbSecurityUserJoinSpec configures samba container instances to
+// use a secret containing a username and password.
+type SmbSecurityUserJoinSpec struct {
+	// Secret that contains the username and password.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Secret string `json:"secret,omitempty"`
+	// Key within the secret containing the username and password.
+	// +kubebuilder:default:=join.json
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SmbSecurityKeytabJoinSpec configures samba container instances to
+// use a secret containing a Kerberos keytab of a principal that is
+// permitted to join systems to the domain.
+type SmbSecurityKeytabJoinSpec struct {
+	// Secret that contains the keytab.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Secret string `json:"secret,omitempty"`
+	// Key within the secret containing the keytab.
+	// +kubebuilder:default:=krb5.keytab
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SmbSecurityComputerAccountJoinSpec configures samba container instances
+// to use a secret containing the one-time password of a computer account
+// that was created in the domain in advance.
+type SmbSecurityComputerAccountJoinSpec struct {
+	// Secret that contains the one-time password.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Secret string `json:"secret,omitempty"`
+	// Key within the secret containing the one-time password.
+	// +kubebuilder:default:=password
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SmbSecurityJoinOptionsSpec configures how samba container instances
+// are joined to the domain.
+type SmbSecurityJoinOptionsSpec struct {
+	// OrganizationalUnit the computer account is created in.
+	// +optional
+	OrganizationalUnit string `json:"organizationalUnit,omitempty"`
+	// ComputerAccountName is the name of the computer account to join as.
+	// If unset, the NetBIOS name of the server instance is used.
+	// The name becomes the NetBIOS name of the servers, so it may only be
+	// used by a single server group.
+	// +kubebuilder:validation:MaxLength:=15
+	// +optional
+	ComputerAccountName string `json:"computerAccountName,omitempty"`
+}
+
+// SmbSecurityDomainSpec configures samba's domain management and ID mapping
+// behavior for the specified domain.
+type SmbSecurityDomainSpec struct {
+	// Name of the domain.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name,omitempty"`
+
+	// Backend specifies how IDs are mapped for the domain.
+	// +kubebuilder:validation:Required
+	Backend IDMapBackend `json:"backend,omitempty"`
+
+	// RangeStart is the first ID of the range of IDs mapped for the domain.
+	// If unset, a range is assigned by the operator.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RangeStart int `json:"rangeStart,omitempty"`
+
+	// RangeSize is the number of IDs in the range of IDs mapped for the
+	// domain. Must be set along with RangeStart.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RangeSize int `json:"rangeSize,omitempty"`
+
+	// UnixPrimaryGroup uses the primary group stored in the RFC2307
+	// attributes of a user rather than the domain's primary group.
+	// Only valid for the ad and ad-rfc2307 backends.
+	// +optional
+	UnixPrimaryGroup bool `json:"unixPrimaryGroup,omitempty"`
+
+	// UnixNSSInfo uses the home directory and shell stored in the RFC2307
+	// attributes of a user. Only valid for the ad and ad-rfc2307 backends.
+	// +optional
+	UnixNSSInfo bool `json:"unixNSSInfo,omitempty"`
+}
+
+// SmbSecurityDNSSpec configures the relationship between systems managed
+// via this SmbSecurityConfig and the domain. Ignored by user mode.
+type SmbSecurityDNSSpec struct {
+	// Register a specified member server's address with the domain's DNS or
+	// disabled when set to "never".
+	// NOTE: cluster-ip is not generally supported, it is only for testing.
+	// +optional
+	Register DNSRegisterMode `json:"register,omitempty"`
+}
+
+// SmbSecurityIDMapRange is the range of IDs the users and groups of a
+// domain are mapped to.
+type SmbSecurityIDMapRange struct {
+	// Name of the domain.
+	Name string `json:"name"`
+
+	// RangeStart is the first ID of the range.
+	RangeStart int `json:"rangeStart"`
+
+	// RangeSize is the number of IDs in the range.
+	RangeSize int `json:"rangeSize"`
+}
+
+// SmbSecurityConfigStatus defines the observed state of SmbSecurityConfig
+type SmbSecurityConfigStatus struct {
+	// Workgroup is the NetBIOS name of the active directory domain as
+	// discovered by the operator.
+	// +optional
+	Workgroup string `json:"workgroup,omitempty"`
+
+	// Shares lists the names of the SmbShares using this security config.
+	// +optional
+	Shares []string `json:"shares,omitempty"`
+
+	// IDMapRanges lists the ID ranges in use for the domains. Once
+	// recorded, the range of a domain is kept when the domains are
+	// reordered. A range set in the spec only replaces a recorded range if
+	// no SmbShare uses the security config or the change is forced with the
+	// force-idmap-change annotation.
+	// +optional
+	IDMapRanges []SmbSecurityIDMapRange `json:"idmapRanges,omitempty"`
+
+	// Conditions describe whether the security config and the secrets it
+	// refers to are valid, and whether the workgroup has been discovered.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:unservedversion
+
+// SmbSecurityConfig is the Schema for the smbsecurityconfigs API
+type SmbSecurityConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SmbSecurityConfigSpec   `json:"spec,omitempty"`
+	Status SmbSecurityConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SmbSecurityConfigList contains a list of SmbSecurityConfig
+type SmbSecurityConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SmbSecurityConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SmbSecurityConfig{}, &SmbSecurityConfigList{})
+}
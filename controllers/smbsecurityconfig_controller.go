@@ -20,16 +20,23 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/resources"
 )
 
 // SmbSecurityConfigReconciler reconciles a SmbSecurityConfig object
 type SmbSecurityConfigReconciler struct {
 	client.Client
-	Log logr.Logger
+	Log      logr.Logger
+	recorder record.EventRecorder
+	reader   client.Reader
 }
 
 //revive:disable kubebuilder directives
@@ -37,23 +44,56 @@ type SmbSecurityConfigReconciler struct {
 // nolint:lll
 // +kubebuilder:rbac:groups=samba-operator.samba.org,resources=smbsecurityconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=samba-operator.samba.org,resources=smbsecurityconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=samba-operator.samba.org,resources=smbshares,verbs=get;list;watch
 
 //revive:enable
 
 // Reconcile the SmbSecurityConfig resource.
 func (r *SmbSecurityConfigReconciler) Reconcile(
-	_ context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	// ---
-	_ = r.Log.WithValues("smbsecurityconfig", req.NamespacedName)
-
-	// your logic here
-
-	return ctrl.Result{}, nil
+	reqLogger := r.Log.WithValues("smbsecurityconfig", req.NamespacedName)
+	reqLogger.Info("Reconciling SmbSecurityConfig")
+
+	securityConfigManager := resources.NewSmbSecurityConfigManager(
+		r, r.reader, r.Scheme(), r.recorder, reqLogger) // nolint:typecheck
+
+	res := securityConfigManager.Process(ctx, req.NamespacedName)
+	err := res.Err()
+	if res.Requeue() {
+		return ctrl.Result{Requeue: true}, err
+	}
+	return ctrl.Result{RequeueAfter: res.RequeueAfter()}, err
 }
 
-// SetupWithManager sets up the reconciler.
+// SetupWithManager sets up the reconciler. Secrets are read directly from
+// the API server instead of being cached.
 func (r *SmbSecurityConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("smbsecurityconfig-controller")
+	r.reader = mgr.GetAPIReader()
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&sambaoperatorv1alpha1.SmbSecurityConfig{}).
+		Owns(&batchv1.Job{}).
+		Watches(
+			&source.Kind{Type: &sambaoperatorv1alpha1.SmbShare{}},
+			handler.EnqueueRequestsFromMapFunc(securityConfigOf)).
 		Complete(r)
 }
+
+// securityConfigOf maps an SmbShare to a request for the security config
+// it refers to, so that the shares listed in the status stay current.
+func securityConfigOf(obj client.Object) []ctrl.Request {
+	s, ok := obj.(*sambaoperatorv1alpha1.SmbShare)
+	if !ok || s.Spec.SecurityConfig == "" {
+		return nil
+	}
+	return []ctrl.Request{{
+		NamespacedName: client.ObjectKey{
+			Namespace: s.Namespace,
+			Name:      s.Spec.SecurityConfig,
+		},
+	}}
+}
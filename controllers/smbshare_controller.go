@@ -22,10 +22,13 @@ import (
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
 	"github.com/samba-in-kubernetes/samba-operator/internal/resources"
@@ -36,6 +39,7 @@ type SmbShareReconciler struct {
 	client.Client
 	Log      logr.Logger
 	recorder record.EventRecorder
+	execer   resources.PodExecer
 }
 
 //revive:disable kubebuilder directives
@@ -47,7 +51,12 @@ type SmbShareReconciler struct {
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create
@@ -66,12 +75,12 @@ func (r *SmbShareReconciler) Reconcile(
 	reqLogger.Info("Reconciling SmbShare")
 
 	smbShareManager := resources.NewSmbShareManager(
-		r, r.Scheme(), r.recorder, reqLogger) // nolint:typecheck
+		r, r.Scheme(), r.recorder, reqLogger, r.execer) // nolint:typecheck
 
 	res := smbShareManager.Process(ctx, req.NamespacedName)
 	err := res.Err()
 	if res.Requeue() {
-		return ctrl.Result{Requeue: true}, err
+		return ctrl.Result{Requeue: true, RequeueAfter: res.RequeueAfter()}, err
 	}
 	return ctrl.Result{}, err
 }
@@ -83,6 +92,11 @@ func (r *SmbShareReconciler) setRecorder(mgr ctrl.Manager) {
 // SetupWithManager sets up resource management.
 func (r *SmbShareReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.setRecorder(mgr)
+	execer, err := resources.NewPodExecer(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	r.execer = execer
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&sambaoperatorv1alpha1.SmbShare{}).
 		Owns(&corev1.PersistentVolumeClaim{}).
@@ -90,5 +104,45 @@ func (r *SmbShareReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Service{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&appsv1.StatefulSet{}).
+		Owns(&batchv1.Job{}).
+		Watches(
+			&source.Kind{Type: &sambaoperatorv1alpha1.SmbSecurityConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.sharesUsing(
+				func(s *sambaoperatorv1alpha1.SmbShare) string {
+					return s.Spec.SecurityConfig
+				}))).
+		Watches(
+			&source.Kind{Type: &sambaoperatorv1alpha1.SmbCommonConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.sharesUsing(
+				func(s *sambaoperatorv1alpha1.SmbShare) string {
+					return s.Spec.CommonConfig
+				}))).
 		Complete(r)
 }
+
+// sharesUsing returns a function mapping a changed config resource to
+// requests for the SmbShares of its namespace that refer to it, so that
+// changes to the config reach the configuration of the shares.
+func (r *SmbShareReconciler) sharesUsing(
+	ref func(*sambaoperatorv1alpha1.SmbShare) string) handler.MapFunc {
+	// ---
+	return func(obj client.Object) []ctrl.Request {
+		l := &sambaoperatorv1alpha1.SmbShareList{}
+		err := r.List(
+			context.TODO(), l, client.InNamespace(obj.GetNamespace()))
+		if err != nil {
+			r.Log.Error(err, "Failed to list SmbShares",
+				"Namespace", obj.GetNamespace())
+			return nil
+		}
+		requests := []ctrl.Request{}
+		for i := range l.Items {
+			if ref(&l.Items[i]) == obj.GetName() {
+				requests = append(requests, ctrl.Request{
+					NamespacedName: client.ObjectKeyFromObject(&l.Items[i]),
+				})
+			}
+		}
+		return requests
+	}
+}
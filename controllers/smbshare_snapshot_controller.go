@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/resources"
+)
+
+// SmbShareSnapshotReconciler takes VolumeSnapshots of the storage of
+// SmbShare objects.
+type SmbShareSnapshotReconciler struct {
+	client.Client
+	Log      logr.Logger
+	recorder record.EventRecorder
+	// available is false if the VolumeSnapshot API is not installed.
+	available bool
+}
+
+//revive:disable kubebuilder directives
+
+// nolint:lll
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;delete
+
+//revive:enable
+
+// Reconcile the VolumeSnapshots of SmbShare resources.
+func (r *SmbShareSnapshotReconciler) Reconcile(
+	ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// ---
+	reqLogger := r.Log.WithValues("smbshare", req.NamespacedName)
+	reqLogger.Info("Reconciling SmbShare snapshots")
+
+	snapshotManager := resources.NewSnapshotManager(
+		r, r.Scheme(), r.recorder, reqLogger, r.available) // nolint:typecheck
+
+	res := snapshotManager.Process(ctx, req.NamespacedName)
+	err := res.Err()
+	if res.Requeue() {
+		return ctrl.Result{Requeue: true}, err
+	}
+	return ctrl.Result{RequeueAfter: res.RequeueAfter()}, err
+}
+
+// SetupWithManager sets up resource management. If the VolumeSnapshot API
+// is not available in the cluster the controller only reports that in the
+// status of shares requesting snapshots.
+func (r *SmbShareSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	gvk := resources.VolumeSnapshotGVK
+	_, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return err
+	}
+	r.available = err == nil
+	r.recorder = mgr.GetEventRecorderFor("smbshare-snapshot-controller")
+	b := ctrl.NewControllerManagedBy(mgr).
+		Named("smbsharesnapshot").
+		For(&sambaoperatorv1alpha1.SmbShare{})
+	if r.available {
+		b = b.Owns(resources.NewVolumeSnapshot())
+	} else {
+		r.Log.Info("VolumeSnapshot API not available, snapshots disabled")
+	}
+	return b.Complete(r)
+}
@@ -33,6 +33,10 @@ var DefaultOperatorConfig = OperatorConfig{
 	ImagePullPolicy:           "IfNotPresent",
 	DefaultNodeSelector:       "",
 	ClusterType:               "",
+	ClusterDomain:             "cluster.local",
+	EnableWebhooks:            false,
+	DefaultSecurityConfig:     "",
+	DefaultCommonConfig:       "",
 }
 
 // OperatorConfig is a type holding general configuration values.
@@ -95,6 +99,19 @@ type OperatorConfig struct {
 	// cluster (minikube, OpenShift etc). If not provided, the operator will
 	// try to figure it out.
 	ClusterType string `mapstructure:"cluster-type"`
+	// ClusterDomain is the DNS domain of the kubernetes cluster. It is used
+	// to construct the fully qualified names of services.
+	ClusterDomain string `mapstructure:"cluster-domain"`
+	// EnableWebhooks is a (boolean) value that enables the admission
+	// webhooks. The webhook server requires a serving certificate to be
+	// provided to the operator.
+	EnableWebhooks bool `mapstructure:"enable-webhooks"`
+	// DefaultSecurityConfig is the name of the SmbSecurityConfig assigned
+	// by the defaulting webhook to new SmbShares that do not specify one.
+	DefaultSecurityConfig string `mapstructure:"default-security-config"`
+	// DefaultCommonConfig is the name of the SmbCommonConfig assigned
+	// by the defaulting webhook to new SmbShares that do not specify one.
+	DefaultCommonConfig string `mapstructure:"default-common-config"`
 }
 
 // Validate the OperatorConfig returning an error if the config is not
@@ -147,6 +164,10 @@ func NewSource() *Source {
 	v.SetDefault("image-pull-policy", d.ImagePullPolicy)
 	v.SetDefault("default-node-selector", d.DefaultNodeSelector)
 	v.SetDefault("cluster-type", d.ClusterType)
+	v.SetDefault("cluster-domain", d.ClusterDomain)
+	v.SetDefault("enable-webhooks", d.EnableWebhooks)
+	v.SetDefault("default-security-config", d.DefaultSecurityConfig)
+	v.SetDefault("default-common-config", d.DefaultCommonConfig)
 	return &Source{v: v}
 }
 
@@ -15,6 +15,10 @@ limitations under the License.
 
 package planner
 
+import (
+	"strconv"
+)
+
 // SambaContainerArgs generates sets for arguments for samba-container
 // instances.
 type SambaContainerArgs struct {
@@ -66,6 +70,20 @@ func (s *SambaContainerArgs) Run(name string) []string {
 	return args
 }
 
+// SyslogCommand container command generator. The command links /dev/log
+// to the syslog socket of the audit-log container, found on the volume the
+// containers share, and then runs samba-container with the arguments of the
+// container. The link is made before any samba process starts and follows
+// the socket if the audit-log container is restarted.
+func (s *SambaContainerArgs) SyslogCommand() []string {
+	script := `ln -sf "$1" /dev/log || exit 1
+shift
+exec samba-container "$@"
+`
+	return []string{"/bin/sh", "-c", script, "syslog-run",
+		s.planner.Paths().AuditSocket()}
+}
+
 // CTDBDaemon container arguments generator.
 func (*SambaContainerArgs) CTDBDaemon() []string {
 	return []string{
@@ -134,3 +152,69 @@ func (*SambaContainerArgs) UpdateConfigWatch() []string {
 		"--watch",
 	}
 }
+
+// CTDBRemoveNode container arguments generator. sambacc removes the node
+// from the CTDB nodes of the cluster, keeping the node numbers of the
+// remaining nodes unchanged.
+func (*SambaContainerArgs) CTDBRemoveNode(pnn int32) []string {
+	return []string{
+		"ctdb-remove-node",
+		"--node-number=" + strconv.Itoa(int(pnn)),
+	}
+}
+
+// CTDBStatus container command generator. The command prints the status
+// of the nodes of the cluster in machine readable form. Deleted nodes are
+// not listed.
+func (*SambaContainerArgs) CTDBStatus() []string {
+	return []string{"ctdb", "-X", "status"}
+}
+
+// WorkgroupLookup container command generator. The NetBIOS name of the
+// domain is looked up with a CLDAP query to a domain controller of the
+// realm and written to the termination message of the container.
+func (*SambaContainerArgs) WorkgroupLookup(realm string) []string {
+	script := `set -e
+printf '[global]\n\tsecurity = ads\n\trealm = %s\n' "$1" > /tmp/smb.conf
+net ads lookup -s /tmp/smb.conf > /tmp/lookup
+sed -n -e 's/^Pre-Win2k Domain:[[:space:]]*//p' /tmp/lookup \
+	> /dev/termination-log
+test -s /dev/termination-log
+`
+	return []string{"/bin/sh", "-c", script, "workgroup-lookup", realm}
+}
+
+// MustJoin container arguments generator. The keytab and machine password
+// join sources are passed to sambacc's must-join, along with the
+// organizational unit the computer account is created in. The user join
+// sources are read by sambacc from the environment.
+func (s *SambaContainerArgs) MustJoin(keytabs, passwords []string) []string {
+	args := s.Initializer("must-join")
+	args = append(args, joinSourceOptions(keytabs)...)
+	for _, p := range passwords {
+		args = append(args, "--machine-password-file="+p)
+	}
+	if ou := s.planner.JoinOrganizationalUnit(); ou != "" {
+		args = append(args, "--ou="+ou)
+	}
+	return args
+}
+
+// DomainLeave container arguments generator. The computer account of the
+// server group is removed from the domain with the user join sources, read
+// by sambacc from the environment, or the given keytab join sources. A
+// computer account can not remove itself, so machine passwords are not
+// used.
+func (*SambaContainerArgs) DomainLeave(keytabs []string) []string {
+	args := []string{"leave"}
+	args = append(args, joinSourceOptions(keytabs)...)
+	return args
+}
+
+func joinSourceOptions(keytabs []string) []string {
+	args := []string{}
+	for _, k := range keytabs {
+		args = append(args, "--keytab="+k)
+	}
+	return args
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"strings"
+
+	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+const (
+	auditSuccessParam = "full_audit:success"
+	auditFailureParam = "full_audit:failure"
+	auditPrefixParam  = "full_audit:prefix"
+	auditSyslogParam  = "full_audit:syslog"
+	logLevelParam     = "log level"
+
+	// auditPrefix identifies the user, client address, client name and
+	// share of every audit record.
+	auditPrefix = "%u|%I|%m|%S"
+	// auditLogLevel makes smbd write the audit records, which are logged
+	// with debug level 1 in the full_audit class, to its log.
+	auditLogLevel = "0 full_audit:1"
+)
+
+// defaultAuditOperations are audited if the share does not select any
+// operations.
+var defaultAuditOperations = []string{
+	"connect", "disconnect", "openat", "renameat", "unlinkat", "mkdirat",
+}
+
+// AuditEnabled returns true if the operations on the share are audited.
+func (pl *Planner) AuditEnabled() bool {
+	return pl.SmbShare.Spec.Audit != nil
+}
+
+// AuditSidecar returns true if the server pods need a dedicated container
+// for audit records. This is the case if the share or any other share of
+// the server group writes its audit records to the sidecar.
+func (pl *Planner) AuditSidecar() bool {
+	if auditToSidecar(pl.SmbShare) {
+		return true
+	}
+	for _, ic := range pl.Peers {
+		if auditToSidecar(ic.SmbShare) {
+			return true
+		}
+	}
+	return false
+}
+
+func auditToSidecar(s *api.SmbShare) bool {
+	a := s.Spec.Audit
+	return a != nil && a.Output == api.AuditOutputSidecar
+}
+
+// auditOptions returns the share options configuring vfs_full_audit.
+// Records written to a sidecar are sent through syslog, the sidecar
+// receives them on the syslog socket. Other records go to the smbd log.
+func (pl *Planner) auditOptions() smbcc.SmbOptions {
+	a := pl.SmbShare.Spec.Audit
+	if a == nil {
+		a = &api.SmbShareAuditSpec{}
+	}
+	success := a.SuccessOperations
+	if len(success) == 0 {
+		success = defaultAuditOperations
+	}
+	failure := a.FailureOperations
+	if len(failure) == 0 {
+		failure = success
+	}
+	syslog := smbcc.No
+	if auditToSidecar(pl.SmbShare) {
+		syslog = smbcc.Yes
+	}
+	return smbcc.SmbOptions{
+		auditSuccessParam: strings.Join(success, " "),
+		auditFailureParam: strings.Join(failure, " "),
+		auditPrefixParam:  auditPrefix,
+		auditSyslogParam:  syslog,
+	}
+}
+
+// applyAudit adds the vfs_full_audit options to the share if the share is
+// audited and removes them otherwise.
+func (pl *Planner) applyAudit(share smbcc.ShareConfig) bool {
+	return applyFeatureOptions(share, pl.SmbShare.Spec,
+		pl.auditOptions(), pl.AuditEnabled())
+}
+
+// applyAuditLogLevel raises the log level of the full_audit debug class as
+// long as any share of the configuration writes audit records to the smbd
+// log. A log level set by the custom global config is left untouched.
+func (pl *Planner) applyAuditLogLevel() bool {
+	cc := pl.CommonConfig
+	if cc != nil && customGlobalParam(cc.Spec, logLevelParam) {
+		return false
+	}
+	globals := pl.ConfigState.Globals[smbcc.Globals]
+	wanted := false
+	for _, share := range pl.ConfigState.Shares {
+		if share.Options[auditSyslogParam] == smbcc.No {
+			wanted = true
+			break
+		}
+	}
+	current, found := globals.Options[logLevelParam]
+	switch {
+	case wanted && current != auditLogLevel:
+		globals.Options[logLevelParam] = auditLogLevel
+		return true
+	case !wanted && found && current == auditLogLevel:
+		delete(globals.Options, logLevelParam)
+		return true
+	}
+	return false
+}
@@ -60,9 +60,8 @@ func CheckCompatible(current, existing InstanceConfiguration) error {
 	if current.SmbShare.Namespace != existing.SmbShare.Namespace {
 		return incompatible(current, existing, "namespaces differ")
 	}
-	if current.SmbShare.Spec.Storage.Pvc.Name != existing.SmbShare.Spec.Storage.Pvc.Name {
-		return incompatible(current, existing,
-			"PersistentVolumeClaim name mismatch")
+	if reason := storageMismatch(current, existing); reason != "" {
+		return incompatible(current, existing, reason)
 	}
 	if current.SmbShare.Spec.SecurityConfig != existing.SmbShare.Spec.SecurityConfig {
 		return incompatible(current, existing,
@@ -73,6 +72,10 @@ func CheckCompatible(current, existing InstanceConfiguration) error {
 			"common config name mismatch")
 	}
 
+	if current.SmbShare.Spec.NetbiosName != existing.SmbShare.Spec.NetbiosName {
+		return incompatible(current, existing, "netbios name mismatch")
+	}
+
 	// additional checks
 	var uid1, uid2 types.UID
 	if current.SecurityConfig != nil {
@@ -72,6 +72,20 @@ func TestCheckCompatible(t *testing.T) {
 		assert.NoError(t, CheckCompatible(ic1, ic2))
 	})
 
+	t.Run("sameClaimDifferentSource", func(t *testing.T) {
+		ic3 := phonyInstanceConfiguration()
+		ic3.SmbShare.Spec.Storage.Pvc.Name = "group1-pv1"
+		ic2 := phonyInstanceConfiguration2("smbshares", "myusers1", "mycommon1", "mydata")
+		ic2.SmbShare.Spec.Storage.Pvc = nil
+		ic2.SmbShare.Spec.Storage.PersistentVolume =
+			&sambaoperatorv1alpha1.SmbSharePersistentVolumeSpec{Name: "pv1"}
+		ic2.SmbShare.Status.ServerGroup = "group1"
+		err := CheckCompatible(ic3, ic2)
+		if assert.Error(t, err) {
+			assert.ErrorContains(t, err, "storage source")
+		}
+	})
+
 	t.Run("invalidShares", func(t *testing.T) {
 		icx := InstanceConfiguration{}
 		err := CheckCompatible(icx, icx)
@@ -109,11 +123,9 @@ func TestCheckCompatible(t *testing.T) {
 	})
 
 	t.Run("differentPVC", func(t *testing.T) {
+		// a server group may host shares backed by different PVCs
 		ic2 := phonyInstanceConfiguration2("smbshares", "myusers1", "mycommon1", "foobar")
-		err := CheckCompatible(ic1, ic2)
-		if assert.Error(t, err) {
-			assert.ErrorContains(t, err, "PersistentVolumeClaim name")
-		}
+		assert.NoError(t, CheckCompatible(ic1, ic2))
 	})
 
 	t.Run("differentSecurityConfig", func(t *testing.T) {
@@ -131,4 +143,24 @@ func TestCheckCompatible(t *testing.T) {
 			assert.ErrorContains(t, err, "common config name")
 		}
 	})
+	t.Run("differentAuditOutput", func(t *testing.T) {
+		ic2 := phonyInstanceConfiguration2("smbshares", "myusers1", "mycommon1", "mydata")
+		ic2.SmbShare.Spec.Audit = &sambaoperatorv1alpha1.SmbShareAuditSpec{}
+		assert.NoError(t, CheckCompatible(ic1, ic2))
+		// the sidecar is added to the pods if any share of the group needs it
+		ic2.SmbShare.Spec.Audit.Output = sambaoperatorv1alpha1.AuditOutputSidecar
+		assert.NoError(t, CheckCompatible(ic1, ic2))
+		pl := New(ic1, nil)
+		assert.False(t, pl.AuditSidecar())
+		pl.Peers = []InstanceConfiguration{ic2}
+		assert.True(t, pl.AuditSidecar())
+	})
+	t.Run("differentNetbiosName", func(t *testing.T) {
+		ic2 := phonyInstanceConfiguration2("smbshares", "myusers1", "mycommon1", "mydata")
+		ic2.SmbShare.Spec.NetbiosName = "FILES"
+		err := CheckCompatible(ic1, ic2)
+		if assert.Error(t, err) {
+			assert.ErrorContains(t, err, "netbios name")
+		}
+	})
 }
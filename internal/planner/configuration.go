@@ -16,7 +16,11 @@ limitations under the License.
 package planner
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
 	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
@@ -26,6 +30,12 @@ func (pl *Planner) instanceID() smbcc.Key {
 	return smbcc.Key(pl.InstanceName())
 }
 
+// conversionID returns the key of the configuration used by the servers of
+// the new backend while the server group is being converted.
+func (pl *Planner) conversionID() smbcc.Key {
+	return smbcc.Key(pl.InstanceName() + "-conversion")
+}
+
 func (pl *Planner) shareName() string {
 	// todo: make sure this is smb-conf clean, otherwise we need to
 	// fix up the name value(s).
@@ -37,181 +47,253 @@ func (pl *Planner) shareName() string {
 	return pl.SmbShare.Name
 }
 
-func (pl *Planner) idmapOptions() smbcc.SmbOptions {
-	if pl.SecurityConfig == nil || len(pl.SecurityConfig.Spec.Domains) == 0 {
-		// default idmap config
-		return smbcc.SmbOptions{
-			"idmap config * : backend": "autorid",
-			"idmap config * : range":   "2000-9999999",
+// globalOptions returns the options the default globals are created with.
+func (pl *Planner) globalOptions() smbcc.GlobalOptions {
+	globalOptions := smbcc.NewGlobalOptions()
+	globalOptions.SmbPort = pl.GlobalConfig.SmbdPort
+	return globalOptions
+}
+
+// Update the held configuration based on the state of the instance
+// configuration. The complete configuration of the server group is
+// generated from the instance and its peers. The held configuration is
+// replaced if it differs from the generated one.
+func (pl *Planner) Update() (changed bool, err error) {
+	desired := smbcc.New()
+	for _, ic := range pl.members() {
+		mp := New(ic, desired)
+		mp.PrimaryVolume = pl.PrimaryVolume
+		if err := mp.generate(); err != nil {
+			return false, err
 		}
 	}
-	// this is hacky and needs both config to support user supplied ID
-	// ranges as well as a decent algo to deal with ID map ranges.
-	// for now we're just punting though (call it prototyping) :-)
-	o := smbcc.SmbOptions{}
-	doms := []api.SmbSecurityDomainSpec{}
-	userDefault := false
-	for _, d := range pl.SecurityConfig.Spec.Domains {
-		doms = append(doms, d)
-		if d.Name == "*" {
-			userDefault = true
-		}
+	pl.applyConversion(desired)
+	if sameConfig(pl.ConfigState, desired) {
+		return false, nil
 	}
-	if !userDefault {
-		doms = append(doms, api.SmbSecurityDomainSpec{
-			Name:    "*",
-			Backend: "autorid",
-		})
-	}
-	step := 10000
-	for i, d := range doms {
-		pfx := fmt.Sprintf("idmap config %s : ", d.Name)
-		if d.Backend == "autorid" {
-			o[pfx+"backend"] = "autorid"
-		} else {
-			o[pfx+"backend"] = "ad"
-			o[pfx+"schema_mode"] = "rfc2307"
-		}
-		rs := (i * step) + 2000
-		o[pfx+"range"] = fmt.Sprintf("%d-%d", rs, rs+step-1)
+	*pl.ConfigState = *desired
+	return true, nil
+}
+
+// applyConversion adds the configuration used by the servers of the new
+// backend while the server group is being converted. As long as servers of
+// the old backend are running the configuration of the server group keeps
+// the clustering state of the old backend.
+func (pl *Planner) applyConversion(state *smbcc.SambaContainerConfig) {
+	if pl.Conversion == NotConverting {
+		return
+	}
+	cfg, found := state.Configs[pl.instanceID()]
+	if !found {
+		return
+	}
+	conv := cfg
+	conv.InstanceFeatures = append(
+		[]smbcc.FeatureFlag{}, cfg.InstanceFeatures...)
+	setFeature(&conv, smbcc.CTDB, pl.IsClustered())
+	state.Configs[pl.conversionID()] = conv
+	if pl.Conversion == ConvertingServers {
+		setFeature(&cfg, smbcc.CTDB, !pl.IsClustered())
+		state.Configs[pl.instanceID()] = cfg
 	}
-	return o
 }
 
-// Update the held configuration based on the state of the instance
-// configuration.
-func (pl *Planner) Update() (changed bool, err error) {
-	_, found := pl.ConfigState.Globals[smbcc.Globals]
+// members returns the instance configurations of all the shares hosted by
+// the server group, ordered by share name.
+func (pl *Planner) members() []InstanceConfiguration {
+	members := append(
+		[]InstanceConfiguration{pl.InstanceConfiguration}, pl.Peers...)
+	sort.SliceStable(members, func(i, j int) bool {
+		return New(members[i], nil).shareName() <
+			New(members[j], nil).shareName()
+	})
+	return members
+}
+
+// generate adds the share of the instance, and the globals and config
+// section the share requires, to the held configuration.
+func (pl *Planner) generate() error {
+	state := pl.ConfigState
+	globals, found := state.Globals[smbcc.Globals]
 	if !found {
-		globalOptions := smbcc.NewGlobalOptions()
-		globalOptions.SmbPort = pl.GlobalConfig.SmbdPort
-		globals := smbcc.NewGlobals(globalOptions)
-		pl.ConfigState.Globals[smbcc.Globals] = globals
-		changed = true
+		globals = smbcc.NewGlobals(pl.globalOptions())
+		state.Globals[smbcc.Globals] = globals
 	}
 	shareKey := smbcc.Key(pl.shareName())
-	share, found := pl.ConfigState.Shares[shareKey]
-	if !found {
-		share = smbcc.NewSimpleShare(pl.Paths().Share())
-		pl.ConfigState.Shares[shareKey] = share
-		changed = true
+	if _, found := state.Shares[shareKey]; found {
+		return fmt.Errorf(
+			"share name %s already in use in server group %s",
+			shareKey, pl.InstanceName())
 	}
+	share := smbcc.NewSimpleShare(pl.Paths().Share())
+	state.Shares[shareKey] = share
+	applySharePath(share, pl.Paths().Share(), pl.SmbShare.Spec)
+	commonSpec := api.SmbCommonConfigSpec{}
 	if pl.CommonConfig != nil {
-		if c := applyCustomGlobal(pl.ConfigState.Globals[smbcc.Globals],
-			pl.CommonConfig.Spec); c {
-			changed = true
-		}
-	}
-	if c := applyCustomShare(share, pl.SmbShare.Spec); c {
-		changed = true
-	}
-	if c := applyShareValues(share, pl.SmbShare.Spec); c {
-		changed = true
+		commonSpec = pl.CommonConfig.Spec
 	}
+	applyCustomGlobal(globals, commonSpec)
+	applyCustomShare(share, pl.SmbShare.Spec)
+	applyShareValues(share, pl.SmbShare.Spec)
+	applyShareEncryption(share, pl.SmbShare.Spec)
+	pl.applyShadowCopy(share)
+	pl.applyTimeMachine(share)
+	pl.applyAudit(share)
+	pl.applyAuditLogLevel()
+	applyVFSObjects(share, globals, pl.vfsModules(), pl.SmbShare.Spec)
+
 	cfgKey := pl.instanceID()
-	cfg, found := pl.ConfigState.Configs[cfgKey]
+	cfg, found := state.Configs[cfgKey]
 	if !found {
 		cfg = smbcc.ConfigSection{
-			Shares:       []smbcc.Key{shareKey},
 			Globals:      []smbcc.Key{smbcc.Globals},
-			InstanceName: pl.InstanceName(),
+			InstanceName: pl.NetbiosName(),
 			Permissions:  smbcc.NewPermissionsConfig(),
 		}
-		if pl.SecurityMode() == ADMode {
-			realmKey := smbcc.Key(pl.Realm())
+	}
+	cfg.Shares = append(cfg.Shares, shareKey)
+	setFeature(&cfg, smbcc.CTDB, pl.IsClustered())
+	if pl.SecurityMode() == ADMode {
+		realmKey := smbcc.Key(pl.Realm())
+		if !hasGlobal(&cfg, realmKey) {
 			cfg.Globals = append(cfg.Globals, realmKey)
 		}
-		if pl.IsClustered() {
-			cfg.InstanceFeatures = []smbcc.FeatureFlag{smbcc.CTDB}
+		state.Globals[realmKey] = smbcc.GlobalConfig{
+			Options: pl.realmOptions(),
 		}
-		changed = true
 	}
-	if !hasShare(&cfg, shareKey) {
-		cfg.Shares = append(cfg.Shares, shareKey)
-		changed = true
+	state.Configs[cfgKey] = cfg
+	state.Users = smbcc.NewDefaultUsers()
+	return nil
+}
+
+// realmOptions returns the global options required to join the AD realm.
+func (pl *Planner) realmOptions() smbcc.SmbOptions {
+	opts := pl.idmapOptions()
+	// security mode
+	opts["security"] = "ads"
+	// workgroup and realm
+	opts["workgroup"] = pl.Workgroup()
+	opts["realm"] = pl.Realm()
+	return opts
+}
+
+// sameConfig returns true if both configurations serialize to the same
+// JSON.
+func sameConfig(a, b *smbcc.SambaContainerConfig) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
 	}
-	if changed {
-		pl.ConfigState.Configs[cfgKey] = cfg
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
 	}
-	if len(pl.ConfigState.Users) == 0 {
-		pl.ConfigState.Users = smbcc.NewDefaultUsers()
-		changed = true
+	return bytes.Equal(ja, jb)
+}
+
+// Prune the target share from the configuration. Only the shares of the
+// peers are kept, this also removes the share if it was renamed without
+// the configuration having been updated yet.
+func (pl *Planner) Prune() (changed bool, err error) {
+	keep := map[smbcc.Key]bool{}
+	for _, ic := range pl.Peers {
+		keep[smbcc.Key(New(ic, nil).shareName())] = true
 	}
-	if pl.SecurityMode() == ADMode {
-		realmKey := smbcc.Key(pl.Realm())
-		_, found := pl.ConfigState.Globals[realmKey]
-		if !found {
-			opts := pl.idmapOptions()
-			// security mode
-			opts["security"] = "ads"
-			// workgroup and realm
-			opts["workgroup"] = pl.Workgroup()
-			opts["realm"] = pl.Realm()
-			pl.ConfigState.Globals[realmKey] = smbcc.GlobalConfig{
-				Options: opts,
-			}
+	for shareKey := range pl.ConfigState.Shares {
+		if keep[shareKey] {
+			continue
+		}
+		if pl.dropShare(shareKey) {
 			changed = true
 		}
 	}
+	if changed {
+		// the log level may only have been raised for the dropped share
+		pl.applyAuditLogLevel()
+	}
 	return
 }
 
-// Prune the target share from the configuration.
-func (pl *Planner) Prune() (changed bool, err error) {
-	cfgKey := pl.instanceID()
-	shareKey := smbcc.Key(pl.shareName())
-
-	if cfg, found := pl.ConfigState.Configs[cfgKey]; found {
-		if removeShare(&cfg, shareKey) {
-			pl.ConfigState.Configs[cfgKey] = cfg
-			changed = true
+// dropShare removes the share from the instance's config sections and
+// from the shares of the configuration. Returns true if the configuration
+// was changed.
+func (pl *Planner) dropShare(shareKey smbcc.Key) bool {
+	changed := false
+	for _, cfgKey := range []smbcc.Key{pl.instanceID(), pl.conversionID()} {
+		if cfg, found := pl.ConfigState.Configs[cfgKey]; found {
+			if removeShare(&cfg, shareKey) {
+				pl.ConfigState.Configs[cfgKey] = cfg
+				changed = true
+			}
 		}
 	}
 	if _, found := pl.ConfigState.Shares[shareKey]; found {
 		delete(pl.ConfigState.Shares, shareKey)
 		changed = true
 	}
-	return
+	return changed
 }
 
-func applyCustomGlobal(globals smbcc.GlobalConfig, spec api.SmbCommonConfigSpec) bool {
+// applyCustomGlobal applies the security settings and the custom configs
+// of the common config to the globals.
+func applyCustomGlobal(
+	globals smbcc.GlobalConfig, spec api.SmbCommonConfigSpec) bool {
+	// ---
 	changed := false
-	if spec.CustomGlobalConfig != nil {
-		// check if the user wants to use custom configs
-		// if not, just return
-		if !spec.CustomGlobalConfig.UseUnsafeCustomConfig {
-			return changed
-		}
-		for k, v := range spec.CustomGlobalConfig.Configs {
-			oriValue, ok := globals.Options[k]
-			if !ok || (ok && oriValue != v) {
-				globals.Options[k] = v
-				changed = true
-			}
+	if c := spec.CustomGlobalConfig; c != nil && c.UseUnsafeCustomConfig {
+		if applyCustomOptions(globals.Options, c.Configs) {
+			changed = true
 		}
 	}
+	if applyOptions(globals.Options,
+		securityGlobalOptions(spec.Security),
+		func(k string) bool { return customGlobalParam(spec, k) }) {
+		changed = true
+	}
 	return changed
 }
 
+// applyCustomShare applies the custom configs of the share.
 func applyCustomShare(share smbcc.ShareConfig, spec api.SmbShareSpec) bool {
+	c := spec.CustomShareConfig
+	if c == nil || !c.UseUnsafeCustomConfig {
+		return false
+	}
+	return applyCustomOptions(share.Options, c.Configs)
+}
+
+// applyCustomOptions sets the custom options. As the configuration is
+// generated anew on every update, options of a previous version of the
+// custom config that are no longer requested are not carried over.
+func applyCustomOptions(
+	options smbcc.SmbOptions, custom map[string]string) bool {
+	// ---
 	changed := false
-	if spec.CustomShareConfig != nil {
-		// check if the user wants to use custom configs
-		// if not, just return
-		if !spec.CustomShareConfig.UseUnsafeCustomConfig {
-			return changed
-		}
-		for k, v := range spec.CustomShareConfig.Configs {
-			oriValue, ok := share.Options[k]
-			if !ok || (ok && oriValue != v) {
-				share.Options[k] = v
-				changed = true
-			}
+	for k, v := range custom {
+		if current, found := options[k]; !found || current != v {
+			options[k] = v
+			changed = true
 		}
 	}
 	return changed
 }
 
+// applySharePath keeps the path of the share in sync with the location the
+// share's volume is mounted at, unless the path is set by a custom config.
+func applySharePath(
+	share smbcc.ShareConfig, sharePath string, spec api.SmbShareSpec) bool {
+	// ---
+	if customParam(spec, smbcc.PathParam) {
+		return false
+	}
+	if share.Options[smbcc.PathParam] == sharePath {
+		return false
+	}
+	share.Options[smbcc.PathParam] = sharePath
+	return true
+}
+
 func applyShareValues(share smbcc.ShareConfig, spec api.SmbShareSpec) bool {
 	changed := false
 
@@ -235,12 +317,185 @@ func applyShareValues(share smbcc.ShareConfig, spec api.SmbShareSpec) bool {
 		changed = true
 	}
 
+	accessLists := []struct {
+		param   string
+		entries []api.AccessEntry
+	}{
+		{smbcc.ValidUsersParam, spec.ValidUsers},
+		{smbcc.InvalidUsersParam, spec.InvalidUsers},
+		{smbcc.AdminUsersParam, spec.AdminUsers},
+		{smbcc.ReadListParam, spec.ReadList},
+		{smbcc.WriteListParam, spec.WriteList},
+	}
+	for _, al := range accessLists {
+		if customParam(spec, al.param) {
+			continue
+		}
+		value := accessListValue(al.entries)
+		current, found := share.Options[al.param]
+		switch {
+		case value == "" && found:
+			delete(share.Options, al.param)
+			changed = true
+		case value != "" && current != value:
+			share.Options[al.param] = value
+			changed = true
+		}
+	}
+
 	return changed
 }
 
-func hasShare(cfg *smbcc.ConfigSection, k smbcc.Key) bool {
-	for i := range cfg.Shares {
-		if cfg.Shares[i] == k {
+// vfsModules returns the vfs modules required by the features enabled for
+// the share.
+func (pl *Planner) vfsModules() []string {
+	modules := []string{}
+	if pl.AuditEnabled() {
+		// full_audit sits on top of the stack to see every operation
+		modules = append(modules, "full_audit")
+	}
+	if pl.TimeMachineEnabled() {
+		// fruit must be stacked above streams_xattr
+		modules = append(modules, "fruit", "streams_xattr")
+	}
+	if pl.SnapshotsEnabled() {
+		modules = append(modules, "shadow_copy2")
+	}
+	return modules
+}
+
+// applyVFSObjects sets the vfs objects of the share to the given modules.
+// The vfs objects of a share replace the global ones, so the modules of
+// the globals are appended to keep them active for the share.
+func applyVFSObjects(
+	share smbcc.ShareConfig,
+	globals smbcc.GlobalConfig,
+	modules []string,
+	spec api.SmbShareSpec) bool {
+	// ---
+	if customParam(spec, smbcc.VFSObjectsParam) {
+		return false
+	}
+	current, found := share.Options[smbcc.VFSObjectsParam]
+	if len(modules) == 0 {
+		if found {
+			delete(share.Options, smbcc.VFSObjectsParam)
+			return true
+		}
+		return false
+	}
+	value := strings.Join(
+		appendMissing(modules,
+			strings.Fields(globals.Options[smbcc.VFSObjectsParam])),
+		" ")
+	if current == value {
+		return false
+	}
+	share.Options[smbcc.VFSObjectsParam] = value
+	return true
+}
+
+// applyFeatureOptions adds the options of a feature to the share if the
+// feature is enabled and removes them otherwise. Options set by the custom
+// share config are left untouched.
+func applyFeatureOptions(
+	share smbcc.ShareConfig,
+	spec api.SmbShareSpec,
+	opts smbcc.SmbOptions,
+	enabled bool) bool {
+	// ---
+	if !enabled {
+		disabled := smbcc.SmbOptions{}
+		for k := range opts {
+			disabled[k] = ""
+		}
+		opts = disabled
+	}
+	return applyOptions(share.Options, opts,
+		func(k string) bool { return customParam(spec, k) })
+}
+
+// applyOptions sets the options to the given values. Options with an empty
+// value are removed. Options for which skip returns true are left
+// untouched.
+func applyOptions(
+	options, opts smbcc.SmbOptions, skip func(string) bool) bool {
+	// ---
+	changed := false
+	for k, v := range opts {
+		if skip(k) {
+			continue
+		}
+		current, found := options[k]
+		switch {
+		case v == "" && found:
+			delete(options, k)
+			changed = true
+		case v != "" && current != v:
+			options[k] = v
+			changed = true
+		}
+	}
+	return changed
+}
+
+func appendMissing(values, more []string) []string {
+	out := append([]string{}, values...)
+	for _, m := range more {
+		found := false
+		for _, v := range out {
+			if v == m {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// accessListValue formats the entries as a smb.conf list. Entries
+// containing spaces, like "@DOMAIN\Domain Users", are quoted.
+func accessListValue(entries []api.AccessEntry) string {
+	values := make([]string, len(entries))
+	for i, e := range entries {
+		v := string(e)
+		if strings.ContainsAny(v, " \t") {
+			v = `"` + v + `"`
+		}
+		values[i] = v
+	}
+	return strings.Join(values, " ")
+}
+
+// customGlobalParam returns true if the parameter is set by the common
+// config's custom global config. Custom values take precedence over those
+// derived from the spec.
+func customGlobalParam(spec api.SmbCommonConfigSpec, param string) bool {
+	c := spec.CustomGlobalConfig
+	if c == nil || !c.UseUnsafeCustomConfig {
+		return false
+	}
+	_, found := c.Configs[param]
+	return found
+}
+
+// customParam returns true if the parameter is set by the share's custom
+// config. Custom values take precedence over those derived from the spec.
+func customParam(spec api.SmbShareSpec, param string) bool {
+	c := spec.CustomShareConfig
+	if c == nil || !c.UseUnsafeCustomConfig {
+		return false
+	}
+	_, found := c.Configs[param]
+	return found
+}
+
+func hasGlobal(cfg *smbcc.ConfigSection, k smbcc.Key) bool {
+	for i := range cfg.Globals {
+		if cfg.Globals[i] == k {
 			return true
 		}
 	}
@@ -256,3 +511,34 @@ func removeShare(cfg *smbcc.ConfigSection, k smbcc.Key) bool {
 	}
 	return false
 }
+
+func hasFeature(cfg *smbcc.ConfigSection, f smbcc.FeatureFlag) bool {
+	for i := range cfg.InstanceFeatures {
+		if cfg.InstanceFeatures[i] == f {
+			return true
+		}
+	}
+	return false
+}
+
+// setFeature enables or disables the feature flag in the config section.
+// Returns true if the section was changed.
+func setFeature(
+	cfg *smbcc.ConfigSection, f smbcc.FeatureFlag, enable bool) bool {
+	// ---
+	if hasFeature(cfg, f) == enable {
+		return false
+	}
+	if enable {
+		cfg.InstanceFeatures = append(cfg.InstanceFeatures, f)
+		return true
+	}
+	features := []smbcc.FeatureFlag{}
+	for _, v := range cfg.InstanceFeatures {
+		if v != f {
+			features = append(features, v)
+		}
+	}
+	cfg.InstanceFeatures = features
+	return true
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
@@ -24,6 +25,45 @@ func TestUpdate(t *testing.T) {
 	t.Run("adShare", func(t *testing.T) {
 		testADShare(t, smbcc.New())
 	})
+	t.Run("convertClustered", func(t *testing.T) {
+		testConvertClustered(t, smbcc.New())
+	})
+	t.Run("conversionPhases", func(t *testing.T) {
+		testConversionPhases(t, smbcc.New())
+	})
+	t.Run("renameShare", func(t *testing.T) {
+		testRenameShare(t, smbcc.New())
+	})
+	t.Run("renameShareInUse", func(t *testing.T) {
+		testRenameShareInUse(t, smbcc.New())
+	})
+	t.Run("movedShare", func(t *testing.T) {
+		testMovedShare(t, smbcc.New())
+	})
+	t.Run("accessLists", func(t *testing.T) {
+		testAccessLists(t, smbcc.New())
+	})
+	t.Run("snapshots", func(t *testing.T) {
+		testSnapshots(t, smbcc.New())
+	})
+	t.Run("timeMachine", func(t *testing.T) {
+		testTimeMachine(t, smbcc.New())
+	})
+	t.Run("security", func(t *testing.T) {
+		testSecurity(t, smbcc.New())
+	})
+	t.Run("audit", func(t *testing.T) {
+		testAudit(t, smbcc.New())
+	})
+	t.Run("removeCustomConfig", func(t *testing.T) {
+		testRemoveCustomConfig(t, smbcc.New())
+	})
+	t.Run("changeRealm", func(t *testing.T) {
+		testChangeRealm(t, smbcc.New())
+	})
+	t.Run("workgroup", func(t *testing.T) {
+		testWorkgroup(t, smbcc.New())
+	})
 }
 
 func TestPrune(t *testing.T) {
@@ -36,6 +76,9 @@ func TestPrune(t *testing.T) {
 	t.Run("addTwoPruneSame", func(t *testing.T) {
 		testAddTwoPruneSame(t, smbcc.New())
 	})
+	t.Run("prunePreviousNameOfPeer", func(t *testing.T) {
+		testPrunePreviousNameOfPeer(t, smbcc.New())
+	})
 }
 
 func sampleSmbShare1() *sambaoperatorv1alpha1.SmbShare {
@@ -166,6 +209,7 @@ func testSecondShare(t *testing.T, state *smbcc.SambaContainerConfig) {
 		SmbShare:     sampleSmbShare2(),
 		GlobalConfig: &conf.OperatorConfig{},
 	}, state)
+	p2.Peers = []InstanceConfiguration{p.InstanceConfiguration}
 	changed, err = p2.Update()
 	assert.NoError(t, err)
 	assert.True(t, changed)
@@ -201,6 +245,138 @@ func testADShare(t *testing.T, state *smbcc.SambaContainerConfig) {
 	assert.Contains(t, state.Shares, smbcc.Key("share3"))
 	assert.Contains(t, state.Configs[p.instanceID()].Shares, smbcc.Key("share3"))
 	assert.Contains(t, state.Globals, smbcc.Key("FOO.TEST"))
+	assert.Equal(t, "FOO", state.Globals["FOO.TEST"].Options["workgroup"])
+}
+
+func testConvertClustered(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	cfgKey := smbcc.Key(share.Status.ServerGroup)
+	assert.Empty(t, state.Configs[cfgKey].InstanceFeatures)
+
+	share.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+		AvailabilityMode: "clustered",
+		MinClusterSize:   2,
+	}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t,
+		[]smbcc.FeatureFlag{smbcc.CTDB},
+		state.Configs[cfgKey].InstanceFeatures)
+
+	share.Spec.Scaling.AvailabilityMode = "standard"
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Empty(t, state.Configs[cfgKey].InstanceFeatures)
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func testConversionPhases(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	cfgKey := smbcc.Key(share.Status.ServerGroup)
+	convKey := smbcc.Key(share.Status.ServerGroup + "-conversion")
+
+	share.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+		AvailabilityMode: "clustered",
+		MinClusterSize:   2,
+	}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+
+	// the running standard servers keep their configuration
+	p.Conversion = ConvertingServers
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Empty(t, state.Configs[cfgKey].InstanceFeatures)
+	assert.Equal(t,
+		[]smbcc.FeatureFlag{smbcc.CTDB},
+		state.Configs[convKey].InstanceFeatures)
+	assert.Equal(t, string(convKey), p.ContainerID())
+
+	p.Conversion = ConvertingConfig
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t,
+		[]smbcc.FeatureFlag{smbcc.CTDB},
+		state.Configs[cfgKey].InstanceFeatures)
+	assert.Contains(t, state.Configs, convKey)
+	assert.Equal(t, string(cfgKey), p.ContainerID())
+
+	p.Conversion = NotConverting
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, state.Configs, convKey)
+	assert.Equal(t,
+		[]smbcc.FeatureFlag{smbcc.CTDB},
+		state.Configs[cfgKey].InstanceFeatures)
+}
+
+func testRenameShare(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	share.Status.ShareName = "share1"
+	share.Spec.ShareName = "Renamed"
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	assert.Len(t, state.Shares, 1)
+	assert.Contains(t, state.Shares, smbcc.Key("Renamed"))
+	assert.NotContains(t, state.Shares, smbcc.Key("share1"))
+	assert.Equal(t,
+		[]smbcc.Key{"Renamed"},
+		state.Configs[p.instanceID()].Shares)
+
+	// the old name is no longer present, nothing more to change
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// prune removes a share recorded under its previous name too
+	share.Status.ShareName = "Renamed"
+	share.Spec.ShareName = "Another"
+	changed, err = p.Prune()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, state.Shares, 0)
+	assert.Empty(t, state.Configs[p.instanceID()].Shares)
+}
+
+func testRenameShareInUse(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSecondShare(t, state)
+	share := sampleSmbShare1()
+	share.Status.ShareName = "share1"
+	share.Spec.ShareName = "share2"
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	p.Peers = []InstanceConfiguration{{
+		SmbShare:     sampleSmbShare2(),
+		GlobalConfig: &conf.OperatorConfig{},
+	}}
+	_, err := p.Update()
+	assert.Error(t, err)
+	assert.Len(t, state.Shares, 2)
 }
 
 func testAddTwoPruneOne(t *testing.T, state *smbcc.SambaContainerConfig) {
@@ -210,6 +386,10 @@ func testAddTwoPruneOne(t *testing.T, state *smbcc.SambaContainerConfig) {
 		SmbShare:     sampleSmbShare1(),
 		GlobalConfig: &conf.OperatorConfig{},
 	}, state)
+	p.Peers = []InstanceConfiguration{{
+		SmbShare:     sampleSmbShare2(),
+		GlobalConfig: &conf.OperatorConfig{},
+	}}
 	changed, err := p.Prune()
 	assert.NoError(t, err)
 	assert.True(t, changed)
@@ -247,6 +427,10 @@ func testAddTwoPruneSame(t *testing.T, state *smbcc.SambaContainerConfig) {
 		SmbShare:     sampleSmbShare1(),
 		GlobalConfig: &conf.OperatorConfig{},
 	}, state)
+	p.Peers = []InstanceConfiguration{{
+		SmbShare:     sampleSmbShare2(),
+		GlobalConfig: &conf.OperatorConfig{},
+	}}
 	changed, err := p.Prune()
 	assert.NoError(t, err)
 	assert.False(t, changed)
@@ -257,3 +441,389 @@ func testAddTwoPruneSame(t *testing.T, state *smbcc.SambaContainerConfig) {
 	assert.NotContains(t, state.Configs[p.instanceID()].Shares, smbcc.Key("share1"))
 	assert.Contains(t, state.Configs[p.instanceID()].Shares, smbcc.Key("share2"))
 }
+
+func testPrunePreviousNameOfPeer(
+	t *testing.T, state *smbcc.SambaContainerConfig) {
+	// ---
+	testSecondShare(t, state)
+
+	// share1 was once named share2, a name since taken by a peer
+	share := sampleSmbShare1()
+	share.Status.ShareName = "share2"
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	p.Peers = []InstanceConfiguration{{
+		SmbShare:     sampleSmbShare2(),
+		GlobalConfig: &conf.OperatorConfig{},
+	}}
+	changed, err := p.Prune()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	assert.Len(t, state.Shares, 1)
+	assert.Contains(t, state.Shares, smbcc.Key("share2"))
+	assert.Contains(t, state.Configs[p.instanceID()].Shares, smbcc.Key("share2"))
+}
+
+func testMovedShare(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	// the share was configured before its volume was moved
+	state.Shares["share1"].Options[smbcc.PathParam] = "/mnt/elsewhere"
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t,
+		p.Paths().Share(),
+		state.Shares["share1"].Options[smbcc.PathParam])
+
+	// a path set by a custom config is left alone
+	share.Spec.CustomShareConfig = &sambaoperatorv1alpha1.SmbShareConfig{
+		UseUnsafeCustomConfig: true,
+		Configs: map[string]string{
+			smbcc.PathParam: "/srv/custom",
+		},
+	}
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "/srv/custom", state.Shares["share1"].Options[smbcc.PathParam])
+}
+
+func testAccessLists(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	share.Spec.ValidUsers = []sambaoperatorv1alpha1.AccessEntry{
+		"alice", `@EXAMPLE\Domain Users`,
+	}
+	share.Spec.AdminUsers = []sambaoperatorv1alpha1.AccessEntry{"bob"}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts := state.Shares["share1"].Options
+	assert.Equal(t, `alice "@EXAMPLE\Domain Users"`, opts[smbcc.ValidUsersParam])
+	assert.Equal(t, "bob", opts[smbcc.AdminUsersParam])
+	assert.NotContains(t, opts, smbcc.WriteListParam)
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// emptied lists are removed from the share
+	share.Spec.AdminUsers = nil
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	assert.NotContains(t, opts, smbcc.AdminUsersParam)
+}
+
+func testSnapshots(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	share.Spec.Snapshots = &sambaoperatorv1alpha1.SmbShareSnapshotsSpec{}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts := state.Shares["share1"].Options
+	// the global fileid module must remain active for the share
+	assert.Equal(t, "shadow_copy2 fileid", opts[smbcc.VFSObjectsParam])
+	assert.Equal(t, "/snapshots/phonyuid1", opts["shadow:snapdir"])
+	assert.Equal(t, "/mnt/phonyuid1", opts["shadow:mountpoint"])
+	assert.Equal(t, SnapshotFormat, opts["shadow:format"])
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	share.Spec.Snapshots = nil
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	assert.NotContains(t, opts, smbcc.VFSObjectsParam)
+	assert.NotContains(t, opts, "shadow:snapdir")
+}
+
+func testTimeMachine(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testSimpleUpdate(t, state)
+	share := sampleSmbShare1()
+	share.Spec.TimeMachine = &sambaoperatorv1alpha1.SmbShareTimeMachineSpec{}
+	share.Spec.Snapshots = &sambaoperatorv1alpha1.SmbShareSnapshotsSpec{}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts := state.Shares["share1"].Options
+	assert.Equal(t,
+		"fruit streams_xattr shadow_copy2 fileid", opts[smbcc.VFSObjectsParam])
+	assert.Equal(t, "yes", opts["fruit:time machine"])
+	assert.NotContains(t, opts, "fruit:time machine max size")
+
+	maxSize := resource.MustParse("1Ti")
+	share.Spec.TimeMachine.MaxSize = &maxSize
+	share.Spec.Snapshots = nil
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	assert.Equal(t, "fruit streams_xattr fileid", opts[smbcc.VFSObjectsParam])
+	assert.Equal(t, "1099511627776", opts["fruit:time machine max size"])
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	share.Spec.TimeMachine = nil
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	assert.NotContains(t, opts, smbcc.VFSObjectsParam)
+	assert.NotContains(t, opts, "fruit:time machine")
+	assert.NotContains(t, opts, "fruit:time machine max size")
+}
+
+func testSecurity(t *testing.T, state *smbcc.SambaContainerConfig) {
+	share := sampleSmbShare1()
+	share.Spec.Encryption = "off"
+	common := &sambaoperatorv1alpha1.SmbCommonConfig{
+		Spec: sambaoperatorv1alpha1.SmbCommonConfigSpec{
+			Security: &sambaoperatorv1alpha1.SmbCommonSecuritySpec{
+				Encryption:  "required",
+				Signing:     "required",
+				MinProtocol: "SMB3",
+			},
+		},
+	}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		CommonConfig: common,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	globals := state.Globals[smbcc.Globals].Options
+	assert.Equal(t, "required", globals["server smb encrypt"])
+	assert.Equal(t, "mandatory", globals["server signing"])
+	assert.Equal(t, "SMB3", globals["server min protocol"])
+	assert.NotContains(t, globals, "server max protocol")
+	assert.Equal(t, "off", state.Shares["share1"].Options["server smb encrypt"])
+
+	// custom configs take precedence over the typed settings
+	common.Spec.Security.Signing = ""
+	common.Spec.CustomGlobalConfig = &sambaoperatorv1alpha1.SmbCommonConfigGlobalConfig{
+		UseUnsafeCustomConfig: true,
+		Configs:               map[string]string{"server min protocol": "SMB2"},
+	}
+	share.Spec.Encryption = ""
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	globals = state.Globals[smbcc.Globals].Options
+	assert.NotContains(t, globals, "server signing")
+	assert.Equal(t, "SMB2", globals["server min protocol"])
+	assert.NotContains(t, state.Shares["share1"].Options, "server smb encrypt")
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func testAudit(t *testing.T, state *smbcc.SambaContainerConfig) {
+	share := sampleSmbShare1()
+	share.Spec.Audit = &sambaoperatorv1alpha1.SmbShareAuditSpec{
+		SuccessOperations: []string{"openat", "renameat"},
+	}
+	share.Spec.TimeMachine = &sambaoperatorv1alpha1.SmbShareTimeMachineSpec{}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts := state.Shares["share1"].Options
+	globals := state.Globals[smbcc.Globals].Options
+	assert.Equal(t,
+		"full_audit fruit streams_xattr fileid", opts[smbcc.VFSObjectsParam])
+	assert.Equal(t, "openat renameat", opts["full_audit:success"])
+	assert.Equal(t, "openat renameat", opts["full_audit:failure"])
+	assert.Equal(t, "no", opts["full_audit:syslog"])
+	assert.Equal(t, "0 full_audit:1", globals["log level"])
+
+	// records sent to the sidecar go through syslog
+	share.Spec.Audit.Output = sambaoperatorv1alpha1.AuditOutputSidecar
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	globals = state.Globals[smbcc.Globals].Options
+	assert.Equal(t, "yes", opts["full_audit:syslog"])
+	assert.NotContains(t, globals, "log level")
+
+	share.Spec.Audit.Output = sambaoperatorv1alpha1.AuditOutputStdout
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	globals = state.Globals[smbcc.Globals].Options
+	assert.Equal(t, "0 full_audit:1", globals["log level"])
+
+	changed, err = p.Prune()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, globals, "log level")
+}
+
+func testRemoveCustomConfig(t *testing.T, state *smbcc.SambaContainerConfig) {
+	share := sampleSmbShare1()
+	share.Spec.CustomShareConfig = &sambaoperatorv1alpha1.SmbShareConfig{
+		UseUnsafeCustomConfig: true,
+		Configs: map[string]string{
+			"guest ok": "yes",
+			"path":     "/srv/elsewhere",
+		},
+	}
+	common := &sambaoperatorv1alpha1.SmbCommonConfig{
+		Spec: sambaoperatorv1alpha1.SmbCommonConfigSpec{
+			CustomGlobalConfig: &sambaoperatorv1alpha1.SmbCommonConfigGlobalConfig{
+				UseUnsafeCustomConfig: true,
+				Configs: map[string]string{
+					"log level":     "3",
+					"load printers": "yes",
+				},
+			},
+		},
+	}
+	p := New(InstanceConfiguration{
+		SmbShare:     share,
+		CommonConfig: common,
+		GlobalConfig: &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts := state.Shares["share1"].Options
+	globals := state.Globals[smbcc.Globals].Options
+	assert.Equal(t, "yes", opts["guest ok"])
+	assert.Equal(t, "/srv/elsewhere", opts["path"])
+	assert.Equal(t, "3", globals["log level"])
+	assert.Equal(t, "yes", globals["load printers"])
+
+	// removed keys are dropped, or reset to the operator default
+	delete(share.Spec.CustomShareConfig.Configs, "path")
+	share.Spec.CustomShareConfig.UseUnsafeCustomConfig = false
+	common.Spec.CustomGlobalConfig.Configs = map[string]string{"log level": "3"}
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	globals = state.Globals[smbcc.Globals].Options
+	assert.NotContains(t, opts, "guest ok")
+	assert.Equal(t, "/mnt/phonyuid1/share1", opts["path"])
+	assert.Equal(t, "3", globals["log level"])
+	assert.Equal(t, "no", globals["load printers"])
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	p.CommonConfig = nil
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	opts = state.Shares["share1"].Options
+	globals = state.Globals[smbcc.Globals].Options
+	assert.NotContains(t, globals, "log level")
+}
+
+func testChangeRealm(t *testing.T, state *smbcc.SambaContainerConfig) {
+	testADShare(t, state)
+	// stale keys of the stored configuration are not kept
+	state.Globals[smbcc.Globals].Options["stale option"] = "yes"
+	sc := sampleADSecConfig1()
+	sc.Spec.Realm = "BAR.TEST"
+	sc.Spec.Domains = []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "BAR", Backend: "ad", RangeStart: 100000, RangeSize: 5000},
+	}
+	p := New(InstanceConfiguration{
+		SmbShare:       sampleSmbShare3(),
+		SecurityConfig: sc,
+		GlobalConfig:   &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, state.Globals, smbcc.Key("FOO.TEST"))
+	assert.NotContains(t, state.Globals[smbcc.Globals].Options, "stale option")
+	assert.Equal(t,
+		[]smbcc.Key{smbcc.Globals, "BAR.TEST"},
+		state.Configs[p.instanceID()].Globals)
+	realm := state.Globals["BAR.TEST"].Options
+	assert.Equal(t, "BAR.TEST", realm["realm"])
+	assert.Equal(t, "BAR", realm["workgroup"])
+	assert.Equal(t, "ad", realm["idmap config BAR : backend"])
+	assert.Equal(t, "100000-104999", realm["idmap config BAR : range"])
+
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// leaving the domain drops the realm globals
+	sc.Spec.Mode = "user"
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, state.Globals, 1)
+	assert.Equal(t,
+		[]smbcc.Key{smbcc.Globals},
+		state.Configs[p.instanceID()].Globals)
+}
+
+func testWorkgroup(t *testing.T, state *smbcc.SambaContainerConfig) {
+	share := sampleSmbShare3()
+	share.Status.ServerGroup = "group1"
+	sc := sampleADSecConfig1()
+	sc.Status.Workgroup = "FOONET"
+	p := New(InstanceConfiguration{
+		SmbShare:       share,
+		SecurityConfig: sc,
+		GlobalConfig:   &conf.OperatorConfig{},
+	}, state)
+	changed, err := p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "FOONET", state.Globals["FOO.TEST"].Options["workgroup"])
+	assert.Equal(t, "group1", state.Configs["group1"].InstanceName)
+
+	// the specified workgroup takes precedence over the discovered one
+	sc.Spec.Workgroup = "foo-corp"
+	share.Spec.NetbiosName = "FILES"
+	changed, err = p.Update()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "FOO-CORP", state.Globals["FOO.TEST"].Options["workgroup"])
+	assert.Equal(t, "FILES", state.Configs["group1"].InstanceName)
+}
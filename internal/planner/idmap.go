@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"fmt"
+	"sort"
+
+	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+const (
+	// DefaultIDMapDomain is the name samba uses for the ID mapping of all
+	// domains without an ID mapping of their own.
+	DefaultIDMapDomain = "*"
+
+	// idmap ranges assigned by the operator, for domains without an
+	// explicit range, are consecutive blocks of idmapStep IDs.
+	idmapFirstID = 2000
+	idmapStep    = 10000
+	// idmapLastID is the last ID mapped when no domains are configured.
+	idmapLastID = 9999999
+)
+
+// IDMapRange is the range of IDs a domain's users and groups are mapped to.
+type IDMapRange struct {
+	Domain string
+	First  int
+	Last   int
+}
+
+// String returns the range in the format used by smb.conf.
+func (r IDMapRange) String() string {
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// Overlaps returns true if the ranges share any IDs.
+func (r IDMapRange) Overlaps(o IDMapRange) bool {
+	return r.First <= o.Last && o.First <= r.Last
+}
+
+// idmapDomains returns the configured domains along with the default
+// domain, which maps IDs with autorid if not configured explicitly.
+func idmapDomains(
+	domains []api.SmbSecurityDomainSpec) []api.SmbSecurityDomainSpec {
+	// ---
+	doms := []api.SmbSecurityDomainSpec{}
+	userDefault := false
+	for _, d := range domains {
+		doms = append(doms, d)
+		if d.Name == DefaultIDMapDomain {
+			userDefault = true
+		}
+	}
+	if !userDefault {
+		doms = append(doms, api.SmbSecurityDomainSpec{
+			Name:    DefaultIDMapDomain,
+			Backend: "autorid",
+		})
+	}
+	return doms
+}
+
+// IDMapRanges returns the ID ranges of the domains, including the default
+// domain. A domain keeps the range recorded for it, if any, or else uses the
+// range set in its spec. The other domains are assigned a block of IDs based
+// on their position in the list, or the lowest free block if that one is
+// taken, so that reordering the domains does not change recorded ranges.
+func IDMapRanges(
+	domains []api.SmbSecurityDomainSpec,
+	recorded []api.SmbSecurityIDMapRange) []IDMapRange {
+	// ---
+	if len(domains) == 0 && len(recorded) == 0 {
+		return []IDMapRange{{
+			Domain: DefaultIDMapDomain,
+			First:  idmapFirstID,
+			Last:   idmapLastID,
+		}}
+	}
+	prev := recordedIDMapRanges(recorded)
+	doms := idmapDomains(domains)
+	ranges := make([]IDMapRange, len(doms))
+	assigned := make([]bool, len(doms))
+	for i, d := range doms {
+		if r, found := prev[d.Name]; found {
+			ranges[i], assigned[i] = r, true
+		} else if d.RangeStart > 0 && d.RangeSize > 0 {
+			ranges[i], assigned[i] = specIDMapRange(d), true
+		}
+	}
+	for i, d := range doms {
+		if !assigned[i] {
+			first := (i * idmapStep) + idmapFirstID
+			ranges[i] = freeIDMapRange(d.Name, first, ranges, assigned)
+			assigned[i] = true
+		}
+	}
+	return ranges
+}
+
+// freeIDMapRange returns the block of IDs starting at first if it does not
+// overlap any of the assigned ranges, or else the lowest block that does
+// not.
+func freeIDMapRange(
+	domain string,
+	first int,
+	ranges []IDMapRange,
+	assigned []bool) IDMapRange {
+	// ---
+	overlaps := func(r IDMapRange) bool {
+		for i := range ranges {
+			if assigned[i] && ranges[i].Overlaps(r) {
+				return true
+			}
+		}
+		return false
+	}
+	r := IDMapRange{Domain: domain, First: first, Last: first + idmapStep - 1}
+	if !overlaps(r) {
+		return r
+	}
+	r.First, r.Last = idmapFirstID, idmapFirstID+idmapStep-1
+	for overlaps(r) {
+		r.First += idmapStep
+		r.Last += idmapStep
+	}
+	return r
+}
+
+func specIDMapRange(d api.SmbSecurityDomainSpec) IDMapRange {
+	return IDMapRange{
+		Domain: d.Name,
+		First:  d.RangeStart,
+		Last:   d.RangeStart + d.RangeSize - 1,
+	}
+}
+
+func recordedIDMapRanges(
+	recorded []api.SmbSecurityIDMapRange) map[string]IDMapRange {
+	// ---
+	prev := map[string]IDMapRange{}
+	for _, r := range recorded {
+		prev[r.Name] = IDMapRange{
+			Domain: r.Name,
+			First:  r.RangeStart,
+			Last:   r.RangeStart + r.RangeSize - 1,
+		}
+	}
+	return prev
+}
+
+// RecordIDMapRanges returns the ranges in the form they are recorded in the
+// status of a security config.
+func RecordIDMapRanges(ranges []IDMapRange) []api.SmbSecurityIDMapRange {
+	recorded := []api.SmbSecurityIDMapRange{}
+	for _, r := range ranges {
+		recorded = append(recorded, api.SmbSecurityIDMapRange{
+			Name:       r.Domain,
+			RangeStart: r.First,
+			RangeSize:  r.Last - r.First + 1,
+		})
+	}
+	return recorded
+}
+
+// ChangedIDMapDomains returns the names of the domains whose range, as set
+// in the spec, differs from the range recorded for the domain.
+func ChangedIDMapDomains(
+	domains []api.SmbSecurityDomainSpec,
+	recorded []api.SmbSecurityIDMapRange) []string {
+	// ---
+	prev := recordedIDMapRanges(recorded)
+	changed := []string{}
+	for _, d := range domains {
+		if d.RangeStart == 0 || d.RangeSize == 0 {
+			continue
+		}
+		if r, found := prev[d.Name]; found && r != specIDMapRange(d) {
+			changed = append(changed, d.Name)
+		}
+	}
+	return changed
+}
+
+// ValidateIDMapRanges returns an error if the ID ranges of any of the
+// domains overlap.
+func ValidateIDMapRanges(
+	domains []api.SmbSecurityDomainSpec,
+	recorded []api.SmbSecurityIDMapRange) error {
+	// ---
+	ranges := IDMapRanges(domains, recorded)
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].First < ranges[j].First
+	})
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i-1].Overlaps(ranges[i]) {
+			return fmt.Errorf(
+				"idmap range %s of domain %s overlaps range %s of domain %s",
+				ranges[i].String(), ranges[i].Domain,
+				ranges[i-1].String(), ranges[i-1].Domain)
+		}
+	}
+	return nil
+}
+
+// ValidateIDMapDomains returns an error if the domains are not consistent:
+// a domain is listed more than once, a backend is used for a domain it can
+// not map, a range is only partially specified, the RFC2307 options are used
+// with a backend not supporting them or the ID ranges overlap.
+func ValidateIDMapDomains(domains []api.SmbSecurityDomainSpec) error {
+	seen := map[string]bool{}
+	for _, d := range domains {
+		switch {
+		case seen[d.Name]:
+			return fmt.Errorf("domain %s is listed more than once", d.Name)
+		case d.Name == DefaultIDMapDomain && !isDefaultBackend(d.Backend):
+			return fmt.Errorf(
+				"backend %s may not be used for the default domain %s",
+				d.Backend, DefaultIDMapDomain)
+		case d.Name != DefaultIDMapDomain && isDefaultBackend(d.Backend):
+			return fmt.Errorf(
+				"domain %s uses backend %s, which may only be used for"+
+					" the default domain %s",
+				d.Name, d.Backend, DefaultIDMapDomain)
+		case (d.RangeStart == 0) != (d.RangeSize == 0):
+			return fmt.Errorf(
+				"domain %s must specify both rangeStart and rangeSize", d.Name)
+		case (d.UnixPrimaryGroup || d.UnixNSSInfo) && !IsADBackend(d.Backend):
+			return fmt.Errorf(
+				"domain %s uses RFC2307 options with backend %s",
+				d.Name, d.Backend)
+		}
+		seen[d.Name] = true
+	}
+	return ValidateIDMapRanges(domains, nil)
+}
+
+// isDefaultBackend returns true if the backend allocates the IDs it maps.
+// Such backends may only be used for the default domain, which in turn
+// requires one.
+func isDefaultBackend(backend string) bool {
+	return backend == "autorid" || backend == "tdb"
+}
+
+// IsADBackend returns true if the backend maps IDs with the RFC2307
+// attributes stored in the domain.
+func IsADBackend(backend string) bool {
+	return backend == "ad" || backend == "ad-rfc2307"
+}
+
+func (pl *Planner) idmapOptions() smbcc.SmbOptions {
+	var domains []api.SmbSecurityDomainSpec
+	var recorded []api.SmbSecurityIDMapRange
+	if pl.SecurityConfig != nil {
+		domains = pl.SecurityConfig.Spec.Domains
+		recorded = pl.SecurityConfig.Status.IDMapRanges
+	}
+	ranges := IDMapRanges(domains, recorded)
+	o := smbcc.SmbOptions{}
+	for i, d := range idmapDomains(domains) {
+		pfx := fmt.Sprintf("idmap config %s : ", d.Name)
+		switch {
+		case IsADBackend(d.Backend):
+			o[pfx+"backend"] = "ad"
+			o[pfx+"schema_mode"] = "rfc2307"
+			if d.UnixPrimaryGroup {
+				o[pfx+"unix_primary_group"] = smbcc.Yes
+			}
+			if d.UnixNSSInfo {
+				o[pfx+"unix_nss_info"] = smbcc.Yes
+			}
+		default:
+			o[pfx+"backend"] = d.Backend
+		}
+		o[pfx+"range"] = ranges[i].String()
+	}
+	return o
+}
@@ -41,6 +41,20 @@ type Planner struct {
 	// for managing the behavior of samba containers. The planner treats
 	// it as read/write.
 	ConfigState *smbcc.SambaContainerConfig
+
+	// Peers are the instance configurations of the other shares hosted
+	// by the same server group. They are used to generate the complete
+	// configuration of the group.
+	Peers []InstanceConfiguration
+
+	// Conversion is the progress of converting the server group between
+	// the standard and clustered backends.
+	Conversion ConversionPhase
+
+	// PrimaryVolume is the key of the volume of the server group that is
+	// mounted at the path of the group. The volumes of the group's other
+	// shares are mounted next to it.
+	PrimaryVolume string
 }
 
 // New instance of a planner based on the configuration CRs as well
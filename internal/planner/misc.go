@@ -15,12 +15,12 @@ limitations under the License.
 
 package planner
 
-// "cheat codes"
-const (
-	nodeSpreadKey     = "samba-operator.samba.org/node-spread"
-	nodeSpreadDisable = "false"
+import (
+	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
 )
 
+const nodeSpreadDisable = "false"
+
 // UserSecuritySource describes the location of user security configuration
 // metadata.
 type UserSecuritySource struct {
@@ -104,5 +104,5 @@ func (pl *Planner) MayCluster() bool {
 // NodeSpread returns true if pods are required to be spread over multiple
 // nodes.
 func (pl *Planner) NodeSpread() bool {
-	return pl.SmbShare.Annotations[nodeSpreadKey] != nodeSpreadDisable
+	return pl.SmbShare.Annotations[api.NodeSpreadAnnotation] != nodeSpreadDisable
 }
@@ -18,6 +18,7 @@ package planner
 import (
 	"fmt"
 	"path"
+	"time"
 )
 
 // Paths for relevant files and dirs within the containers.
@@ -30,7 +31,7 @@ func (planner *Planner) Paths() *Paths {
 	return &Paths{planner}
 }
 
-// ShareMountPath returns the mount path.
+// ShareMountPath returns the mount path of the volume holding the share.
 func (p *Paths) ShareMountPath() string {
 	// retain the previous approach to using UID for compatibility with
 	// older versions ONLY if grouping is disabled.
@@ -38,12 +39,44 @@ func (p *Paths) ShareMountPath() string {
 	if gmode == GroupModeNever {
 		return path.Join("/mnt", string(p.planner.SmbShare.UID))
 	}
-	return path.Join("/mnt", gname)
+	// a group may host shares from any number of volumes. the primary
+	// volume keeps the path used before, each other volume gets a path
+	// of its own. the volumes are not nested as that would expose the
+	// data of one volume through the shares of another
+	key := p.planner.VolumeKey()
+	if p.planner.PrimaryVolume == "" || p.planner.PrimaryVolume == key {
+		return path.Join("/mnt", gname)
+	}
+	return path.Join("/mnt", gname+"-"+key)
+}
+
+// SnapshotsDir returns the directory the snapshots of the share's volume
+// are mounted in.
+func (p *Paths) SnapshotsDir() string {
+	return path.Join("/snapshots", string(p.planner.SmbShare.UID))
+}
+
+// SnapshotDir returns the directory a snapshot of the share's volume taken
+// at the given time is mounted at.
+func (p *Paths) SnapshotDir(t time.Time) string {
+	return path.Join(p.SnapshotsDir(), t.UTC().Format(SnapshotTimeLayout))
+}
+
+// AuditDir returns the directory holding the socket the audit-log sidecar
+// receives audit records on.
+func (*Paths) AuditDir() string {
+	return "/run/samba-audit"
+}
+
+// AuditSocket returns the path of the syslog socket of the audit-log
+// sidecar.
+func (p *Paths) AuditSocket() string {
+	return path.Join(p.AuditDir(), "log")
 }
 
 // Share path.
 func (p *Paths) Share() string {
-	sharepath := p.planner.SmbShare.Spec.Storage.Pvc.Path
+	sharepath := p.planner.storagePath()
 	if sharepath != "" {
 		return path.Join(p.ShareMountPath(), "/", sharepath)
 	}
@@ -116,6 +149,26 @@ func (p *Paths) JoinJSONSource(index int) string {
 	return path.Join(p.JoinJSONSourceDir(index), p.JoinJSONBaseName())
 }
 
+// JoinKeytabBaseName file name component.
+func (*Paths) JoinKeytabBaseName() string {
+	return "krb5.keytab"
+}
+
+// JoinKeytabSource absolute path based on the given index value.
+func (p *Paths) JoinKeytabSource(index int) string {
+	return path.Join(p.JoinJSONSourceDir(index), p.JoinKeytabBaseName())
+}
+
+// JoinPasswordBaseName file name component.
+func (*Paths) JoinPasswordBaseName() string {
+	return "password"
+}
+
+// JoinPasswordSource absolute path based on the given index value.
+func (p *Paths) JoinPasswordSource(index int) string {
+	return path.Join(p.JoinJSONSourceDir(index), p.JoinPasswordBaseName())
+}
+
 // ServiceWatchStateDir absolute path.
 func (*Paths) ServiceWatchStateDir() string {
 	return "/var/lib/svcwatch"
@@ -28,5 +28,22 @@ func TestShareMountPath(t *testing.T) {
 		planner := New(ic, nil)
 		mp := planner.Paths().ShareMountPath()
 		assert.Equal(t, "/mnt/goodgroup", mp)
+		assert.Equal(t, "/mnt/goodgroup/share1", planner.Paths().Share())
+
+		planner.PrimaryVolume = "mydata"
+		assert.Equal(t, "/mnt/goodgroup", planner.Paths().ShareMountPath())
+	})
+	t.Run("explicitGroupInline", func(t *testing.T) {
+		ic2 := phonyInstanceConfiguration()
+		ic2.SmbShare.Spec.Scaling = ic.SmbShare.Spec.Scaling
+		ic2.SmbShare.Spec.Storage.Pvc = nil
+		ic2.SmbShare.Spec.Storage.NFS = &sambaoperatorv1alpha1.SmbShareNFSSpec{
+			Server: "nfs.example.com",
+			Path:   "/exports/data",
+		}
+		planner := New(ic2, nil)
+		planner.PrimaryVolume = "mydata"
+		mp := planner.Paths().ShareMountPath()
+		assert.Equal(t, "/mnt/goodgroup-share1-nfs", mp)
 	})
 }
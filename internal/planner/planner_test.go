@@ -17,8 +17,10 @@ package planner
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
 	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
@@ -155,3 +157,258 @@ func TestPlannerDNSRegisterArgs(t *testing.T) {
 		},
 		v)
 }
+
+func TestPlannerUNCPath(t *testing.T) {
+	planner := New(
+		InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "myshare",
+				},
+			},
+		},
+		&smbcc.SambaContainerConfig{})
+	assert.Equal(t, `\\example.com\myshare`, planner.UNCPath("example.com"))
+
+	planner.SmbShare.Spec.ShareName = "Data"
+	assert.Equal(t, `\\10.0.0.1\Data`, planner.UNCPath("10.0.0.1"))
+}
+
+func TestPlannerClusterSize(t *testing.T) {
+	planner := New(
+		InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{},
+		},
+		&smbcc.SambaContainerConfig{})
+	assert.Equal(t, int32(1), planner.ClusterSize())
+
+	planner.SmbShare.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+		AvailabilityMode: "clustered",
+		MinClusterSize:   3,
+	}
+	assert.Equal(t, int32(3), planner.ClusterSize())
+
+	planner.SmbShare.Spec.Scaling.MaxClusterSize = 5
+	assert.Equal(t, int32(3), planner.ClusterSize())
+
+	planner.SmbShare.Spec.Scaling.MaxClusterSize = 2
+	assert.Equal(t, int32(2), planner.ClusterSize())
+}
+
+func TestPlannerIDMapRanges(t *testing.T) {
+	planner := New(
+		InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{},
+			SecurityConfig: &sambaoperatorv1alpha1.SmbSecurityConfig{
+				Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+					Mode:  "active-directory",
+					Realm: "cool.example.net",
+					Domains: []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+						{
+							Name:    "COOL",
+							Backend: "ad-rfc2307",
+						},
+					},
+				},
+			},
+		},
+		&smbcc.SambaContainerConfig{})
+	o := planner.idmapOptions()
+	assert.Equal(t, "2000-11999", o["idmap config COOL : range"])
+	assert.Equal(t, "12000-21999", o["idmap config * : range"])
+
+	planner.SecurityConfig.Spec.Domains[0].RangeStart = 100000
+	planner.SecurityConfig.Spec.Domains[0].RangeSize = 50000
+	o = planner.idmapOptions()
+	assert.Equal(t, "100000-149999", o["idmap config COOL : range"])
+	assert.Equal(t, "12000-21999", o["idmap config * : range"])
+
+	// recorded ranges are kept, whatever the spec says
+	planner.SecurityConfig.Status.IDMapRanges = []sambaoperatorv1alpha1.SmbSecurityIDMapRange{
+		{Name: "COOL", RangeStart: 2000, RangeSize: 10000},
+		{Name: "*", RangeStart: 12000, RangeSize: 10000},
+	}
+	planner.SecurityConfig.Spec.Domains = append(
+		[]sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+			{Name: "WARM", Backend: "rid"},
+		},
+		planner.SecurityConfig.Spec.Domains...)
+	o = planner.idmapOptions()
+	assert.Equal(t, "2000-11999", o["idmap config COOL : range"])
+	assert.Equal(t, "12000-21999", o["idmap config * : range"])
+	// the first position is taken, so the lowest free block is used
+	assert.Equal(t, "22000-31999", o["idmap config WARM : range"])
+}
+
+func TestChangedIDMapDomains(t *testing.T) {
+	domains := []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "COOL", Backend: "ad"},
+		{Name: "WARM", Backend: "rid", RangeStart: 100000, RangeSize: 50000},
+	}
+	recorded := RecordIDMapRanges(IDMapRanges(domains, nil))
+	assert.Empty(t, ChangedIDMapDomains(domains, recorded))
+
+	// reordering keeps the ranges
+	domains[0], domains[1] = domains[1], domains[0]
+	assert.Empty(t, ChangedIDMapDomains(domains, recorded))
+	assert.Equal(t, IDMapRanges(domains[1:], recorded)[0],
+		IDMapRange{Domain: "COOL", First: 2000, Last: 11999})
+
+	domains[0].RangeSize = 1000
+	assert.Equal(t, []string{"WARM"}, ChangedIDMapDomains(domains, recorded))
+	// a domain without a recorded range has nothing to change
+	assert.Empty(t, ChangedIDMapDomains(domains, nil))
+}
+
+func TestPlannerIDMapBackends(t *testing.T) {
+	planner := New(
+		InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{},
+			SecurityConfig: &sambaoperatorv1alpha1.SmbSecurityConfig{
+				Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+					Mode:  "active-directory",
+					Realm: "cool.example.net",
+					Domains: []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+						{
+							Name:             "COOL",
+							Backend:          "ad",
+							UnixPrimaryGroup: true,
+							UnixNSSInfo:      true,
+						},
+						{Name: "WARM", Backend: "rid"},
+						{Name: "*", Backend: "tdb"},
+					},
+				},
+			},
+		},
+		&smbcc.SambaContainerConfig{})
+	o := planner.idmapOptions()
+	assert.Equal(t, "ad", o["idmap config COOL : backend"])
+	assert.Equal(t, "rfc2307", o["idmap config COOL : schema_mode"])
+	assert.Equal(t, "yes", o["idmap config COOL : unix_primary_group"])
+	assert.Equal(t, "yes", o["idmap config COOL : unix_nss_info"])
+	assert.Equal(t, "rid", o["idmap config WARM : backend"])
+	assert.NotContains(t, o, "idmap config WARM : schema_mode")
+	assert.Equal(t, "12000-21999", o["idmap config WARM : range"])
+	assert.Equal(t, "tdb", o["idmap config * : backend"])
+	assert.Equal(t, "22000-31999", o["idmap config * : range"])
+}
+
+func TestValidateIDMapRanges(t *testing.T) {
+	assert.NoError(t, ValidateIDMapRanges(nil, nil))
+	domains := []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "COOL", Backend: "ad", RangeStart: 100000, RangeSize: 50000},
+		{Name: "WARM", Backend: "rid", RangeStart: 150000, RangeSize: 50000},
+	}
+	assert.NoError(t, ValidateIDMapRanges(domains, nil))
+
+	domains[1].RangeStart = 149999
+	assert.Error(t, ValidateIDMapRanges(domains, nil))
+
+	// the operator assigns a range not overlapping the explicit ones
+	domains[1].RangeStart = 5000
+	assert.NoError(t, ValidateIDMapRanges(domains, nil))
+	assert.Equal(t,
+		IDMapRange{Domain: "*", First: 62000, Last: 71999},
+		IDMapRanges(domains, nil)[2])
+
+	// but an explicit range may overlap a recorded one
+	recorded := []sambaoperatorv1alpha1.SmbSecurityIDMapRange{
+		{Name: "*", RangeStart: 2000, RangeSize: 10000},
+	}
+	assert.Error(t, ValidateIDMapRanges(domains, recorded))
+}
+
+func TestValidateIDMapDomains(t *testing.T) {
+	domains := []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "COOL", Backend: "ad", UnixNSSInfo: true},
+		{Name: "WARM", Backend: "rid"},
+	}
+	assert.NoError(t, ValidateIDMapDomains(domains))
+
+	domains[1].UnixPrimaryGroup = true
+	assert.Error(t, ValidateIDMapDomains(domains))
+
+	domains[1].UnixPrimaryGroup = false
+	domains[1].RangeStart = 500000
+	assert.Error(t, ValidateIDMapDomains(domains))
+
+	domains[1].RangeStart = 0
+	domains = append(domains, domains[0])
+	assert.Error(t, ValidateIDMapDomains(domains))
+
+	for _, backend := range []string{"autorid", "tdb"} {
+		domains = []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+			{Name: DefaultIDMapDomain, Backend: backend},
+		}
+		assert.NoError(t, ValidateIDMapDomains(domains), backend)
+		domains[0].Name = "COOL"
+		assert.Error(t, ValidateIDMapDomains(domains), backend)
+	}
+	for _, backend := range []string{"ad", "ad-rfc2307", "rid", "nss"} {
+		domains = []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+			{Name: DefaultIDMapDomain, Backend: backend},
+		}
+		assert.Error(t, ValidateIDMapDomains(domains), backend)
+	}
+}
+
+func TestComputerAccountGroups(t *testing.T) {
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "addc"},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "cool.example.com",
+			JoinOptions: &sambaoperatorv1alpha1.SmbSecurityJoinOptionsSpec{
+				ComputerAccountName: "FILES1",
+			},
+		},
+	}
+	older := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := metav1.NewTime(older.Add(time.Hour))
+	shares := []sambaoperatorv1alpha1.SmbShare{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "share1",
+				CreationTimestamp: newer,
+			},
+			Spec: sambaoperatorv1alpha1.SmbShareSpec{SecurityConfig: "addc"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "share2",
+				CreationTimestamp: older,
+			},
+			Spec: sambaoperatorv1alpha1.SmbShareSpec{
+				SecurityConfig: "addc",
+				Scaling: &sambaoperatorv1alpha1.SmbShareScalingSpec{
+					GroupMode: "explicit",
+					Group:     "files",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "share3"},
+			Spec: sambaoperatorv1alpha1.SmbShareSpec{
+				SecurityConfig: "addc",
+				NetbiosName:    "FILES3",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "share4"},
+			Spec:       sambaoperatorv1alpha1.SmbShareSpec{SecurityConfig: "other"},
+		},
+	}
+	assert.Equal(t, []string{"files", "share1"},
+		ComputerAccountGroups(sc, shares))
+	assert.Error(t, ValidateComputerAccount(sc, shares))
+
+	shares[0].Status.ServerGroup = "files"
+	assert.Equal(t, []string{"files"}, ComputerAccountGroups(sc, shares))
+	assert.NoError(t, ValidateComputerAccount(sc, shares))
+
+	shares[0].Status.ServerGroup = ""
+	sc.Spec.JoinOptions.ComputerAccountName = ""
+	assert.Empty(t, ComputerAccountGroups(sc, shares))
+	assert.NoError(t, ValidateComputerAccount(sc, shares))
+}
@@ -16,7 +16,11 @@ limitations under the License.
 package planner
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+
+	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
 )
 
 // The core properties of an instance are:
@@ -78,14 +82,152 @@ func (pl *Planner) Realm() string {
 	return strings.ToUpper(pl.SecurityConfig.Spec.Realm)
 }
 
-// Workgroup returns the name of the workgroup. This may be automatically
-// derived from the realm.
+// Workgroup returns the name of the workgroup. The name specified in the
+// security config takes precedence over the name discovered by the
+// operator. If neither is known yet the name is derived from the realm.
 func (pl *Planner) Workgroup() string {
-	// todo: this is a big hack. needs thought and cleanup
+	if pl.SecurityConfig != nil {
+		if wg := pl.SecurityConfig.Spec.Workgroup; wg != "" {
+			return strings.ToUpper(wg)
+		}
+		if wg := pl.SecurityConfig.Status.Workgroup; wg != "" {
+			return strings.ToUpper(wg)
+		}
+	}
 	parts := strings.SplitN(pl.Realm(), ".", 2)
 	return parts[0]
 }
 
+// NetbiosName returns the NetBIOS name of the servers of the instance.
+// sambacc uses the instance name of a configuration as the NetBIOS name.
+// Samba joins the domain with a computer account named after the NetBIOS
+// name, so the computer account name of the join options is used if set.
+func (pl *Planner) NetbiosName() string {
+	if pl.SmbShare.Spec.NetbiosName != "" {
+		return pl.SmbShare.Spec.NetbiosName
+	}
+	if opts := pl.joinOptions(); opts != nil && opts.ComputerAccountName != "" {
+		return opts.ComputerAccountName
+	}
+	return pl.InstanceName()
+}
+
+// JoinOrganizationalUnit returns the organizational unit the computer
+// account is created in when joining the domain.
+func (pl *Planner) JoinOrganizationalUnit() string {
+	if opts := pl.joinOptions(); opts != nil {
+		return opts.OrganizationalUnit
+	}
+	return ""
+}
+
+func (pl *Planner) joinOptions() *api.SmbSecurityJoinOptionsSpec {
+	if pl.SecurityConfig == nil || pl.SecurityMode() != ADMode {
+		return nil
+	}
+	return pl.SecurityConfig.Spec.JoinOptions
+}
+
+// ComputerAccountGroups returns the names of the server groups, among the
+// given shares, that join the domain with the computer account name of the
+// security config. Shares that specify a NetBIOS name, or are being
+// deleted, are ignored. The server group of the oldest share comes first.
+func ComputerAccountGroups(
+	sc *api.SmbSecurityConfig,
+	shares []api.SmbShare) []string {
+	// ---
+	planner := New(InstanceConfiguration{SecurityConfig: sc}, nil)
+	opts := planner.joinOptions()
+	if opts == nil || opts.ComputerAccountName == "" {
+		return nil
+	}
+	sorted := make([]*api.SmbShare, 0, len(shares))
+	for i := range shares {
+		s := &shares[i]
+		if s.Spec.SecurityConfig != sc.Name || s.Spec.NetbiosName != "" ||
+			s.GetDeletionTimestamp() != nil {
+			continue
+		}
+		sorted = append(sorted, s)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp, sorted[j].CreationTimestamp
+		// shares that are not created yet come last
+		if ti.IsZero() || tj.IsZero() {
+			return !ti.IsZero() && tj.IsZero()
+		}
+		return ti.Before(&tj)
+	})
+	groups := []string{}
+	seen := map[string]bool{}
+	for _, s := range sorted {
+		g := serverGroupOf(s)
+		if !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// ValidateComputerAccount returns an error if the computer account name of
+// the security config is used by more than one server group of the given
+// shares. The servers of each group would join the domain with the same
+// computer account.
+func ValidateComputerAccount(
+	sc *api.SmbSecurityConfig,
+	shares []api.SmbShare) error {
+	// ---
+	groups := ComputerAccountGroups(sc, shares)
+	if len(groups) > 1 {
+		return fmt.Errorf(
+			"computer account name %s may only be used by a single server"+
+				" group, but is used by server groups %s",
+			sc.Spec.JoinOptions.ComputerAccountName,
+			strings.Join(groups, ", "))
+	}
+	return nil
+}
+
+// serverGroupOf returns the name of the server group hosting the share. A
+// share that is not assigned to a server group yet is named after the group
+// it will be assigned to.
+func serverGroupOf(s *api.SmbShare) string {
+	if s.Status.ServerGroup != "" {
+		return s.Status.ServerGroup
+	}
+	mode, group := New(InstanceConfiguration{SmbShare: s}, nil).Grouping()
+	if mode == GroupModeExplicit {
+		return group
+	}
+	return s.Name
+}
+
+// ConversionPhase describes how far the conversion of a server group
+// between the standard and clustered backends has progressed.
+type ConversionPhase int
+
+const (
+	// NotConverting indicates that no conversion is in progress.
+	NotConverting = ConversionPhase(iota)
+	// ConvertingServers indicates that servers of the old backend are still
+	// running. They keep using the configuration of the server group while
+	// the servers of the new backend use a separate one.
+	ConvertingServers
+	// ConvertingConfig indicates that only servers of the new backend
+	// remain. They are moved to the configuration of the server group.
+	ConvertingConfig
+)
+
+// ContainerID returns the key of the configuration the samba containers
+// of the instance are started with.
+func (pl *Planner) ContainerID() string {
+	if pl.Conversion == ConvertingServers {
+		return string(pl.conversionID())
+	}
+	return pl.InstanceName()
+}
+
 // IsClustered returns true if the instance is configured for clustering.
 func (pl *Planner) IsClustered() bool {
 	if pl.SmbShare.Spec.Scaling == nil {
@@ -94,12 +236,18 @@ func (pl *Planner) IsClustered() bool {
 	return pl.SmbShare.Spec.Scaling.AvailabilityMode == "clustered"
 }
 
-// ClusterSize returns the (minimum) size of the cluster.
+// ClusterSize returns the desired size of the cluster. This is the minimum
+// cluster size, limited by the maximum cluster size if one is set.
 func (pl *Planner) ClusterSize() int32 {
 	if pl.SmbShare.Spec.Scaling == nil {
 		return 1
 	}
-	return int32(pl.SmbShare.Spec.Scaling.MinClusterSize) // #nosec G115
+	size := pl.SmbShare.Spec.Scaling.MinClusterSize
+	maxSize := pl.SmbShare.Spec.Scaling.MaxClusterSize
+	if maxSize > 0 && size > maxSize {
+		size = maxSize
+	}
+	return int32(size) // #nosec G115
 }
 
 // Grouping returns the logical grouping mode and group name.
@@ -122,3 +270,27 @@ func (pl *Planner) Grouping() (GroupMode, string) {
 		return GroupModeNever, ""
 	}
 }
+
+// PVCName returns the name of the PVC holding the data of the share.
+func (pl *Planner) PVCName() string {
+	if pvc := pl.SmbShare.Spec.Storage.Pvc; pvc != nil && pvc.Name != "" {
+		return pvc.Name
+	}
+	if pv := pl.SmbShare.Spec.Storage.PersistentVolume; pv != nil {
+		// a volume can only be bound by one claim, the claim is shared
+		// by all shares of the server group using the volume
+		return pl.InstanceName() + "-" + pv.Name
+	}
+	return pl.SmbShare.Name + "-pvc"
+}
+
+// ShareName returns the name of the share as it appears in the smb.conf.
+func (pl *Planner) ShareName() string {
+	return pl.shareName()
+}
+
+// UNCPath returns the UNC path (\\host\share) for accessing the share on
+// the given host.
+func (pl *Planner) UNCPath(host string) string {
+	return fmt.Sprintf(`\\%s\%s`, host, pl.shareName())
+}
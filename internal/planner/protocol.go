@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"fmt"
+
+	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+const (
+	serverSmbEncryptParam  = "server smb encrypt"
+	serverSigningParam     = "server signing"
+	serverMinProtocolParam = "server min protocol"
+	serverMaxProtocolParam = "server max protocol"
+)
+
+// signingValues maps the signing settings of the API to the values of the
+// smb.conf "server signing" parameter.
+var signingValues = map[string]string{
+	"off":      "disabled",
+	"desired":  "auto",
+	"required": "mandatory",
+}
+
+// protocolLevels orders the protocol versions. Like samba, SMB2 and SMB3
+// stand for the latest dialect of the version.
+var protocolLevels = map[string]int{
+	"NT1":     1,
+	"SMB2_02": 2,
+	"SMB2_10": 3,
+	"SMB2":    3,
+	"SMB3_00": 4,
+	"SMB3_02": 5,
+	"SMB3_11": 6,
+	"SMB3":    6,
+}
+
+// ValidateProtocolRange returns an error if the protocol versions are
+// unknown or if the minimum version is newer than the maximum version.
+// Empty values are not limiting.
+func ValidateProtocolRange(minProto, maxProto string) error {
+	for _, p := range []string{minProto, maxProto} {
+		if _, found := protocolLevels[p]; p != "" && !found {
+			return fmt.Errorf("unknown protocol version: %q", p)
+		}
+	}
+	if minProto == "" || maxProto == "" {
+		return nil
+	}
+	if protocolLevels[minProto] > protocolLevels[maxProto] {
+		return fmt.Errorf(
+			"minimum protocol %s is newer than maximum protocol %s",
+			minProto, maxProto)
+	}
+	return nil
+}
+
+// securityGlobalOptions returns the [global] options matching the security
+// settings of a common config. Unset settings have an empty value.
+func securityGlobalOptions(sec *api.SmbCommonSecuritySpec) smbcc.SmbOptions {
+	if sec == nil {
+		sec = &api.SmbCommonSecuritySpec{}
+	}
+	return smbcc.SmbOptions{
+		serverSmbEncryptParam:  sec.Encryption,
+		serverSigningParam:     signingValues[sec.Signing],
+		serverMinProtocolParam: sec.MinProtocol,
+		serverMaxProtocolParam: sec.MaxProtocol,
+	}
+}
+
+// applyShareEncryption sets the encryption of the share if it overrides
+// the encryption of the common config.
+func applyShareEncryption(share smbcc.ShareConfig, spec api.SmbShareSpec) bool {
+	return applyOptions(share.Options,
+		smbcc.SmbOptions{serverSmbEncryptParam: spec.Encryption},
+		func(k string) bool { return customParam(spec, k) })
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"time"
+
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+const (
+	// SnapshotFormat is the strftime format of the snapshot directory
+	// names that vfs_shadow_copy2 looks for.
+	SnapshotFormat = "@GMT-%Y.%m.%d-%H.%M.%S"
+	// SnapshotTimeLayout is the Go time layout equivalent to
+	// SnapshotFormat.
+	SnapshotTimeLayout = "@GMT-2006.01.02-15.04.05"
+
+	defaultSnapshotInterval  = 24 * time.Hour
+	defaultSnapshotRetention = 7
+)
+
+// SnapshotsEnabled returns true if snapshots of the share's PVC are taken
+// and offered to clients as previous versions of the share's files.
+func (pl *Planner) SnapshotsEnabled() bool {
+	return pl.SmbShare.Spec.Snapshots != nil && pl.UsesPVC()
+}
+
+// SnapshotInterval returns the time between two snapshots of the share.
+func (pl *Planner) SnapshotInterval() time.Duration {
+	if s := pl.SmbShare.Spec.Snapshots; s != nil && s.Interval.Duration > 0 {
+		return s.Interval.Duration
+	}
+	return defaultSnapshotInterval
+}
+
+// SnapshotRetention returns the number of snapshots to keep.
+func (pl *Planner) SnapshotRetention() int {
+	if s := pl.SmbShare.Spec.Snapshots; s != nil && s.Retention > 0 {
+		return s.Retention
+	}
+	return defaultSnapshotRetention
+}
+
+// SnapshotClassName returns the name of the VolumeSnapshotClass used to
+// take snapshots. An empty string selects the default class.
+func (pl *Planner) SnapshotClassName() string {
+	if s := pl.SmbShare.Spec.Snapshots; s != nil {
+		return s.VolumeSnapshotClassName
+	}
+	return ""
+}
+
+// shadowCopyOptions returns the share options configuring vfs_shadow_copy2
+// to find the snapshots in the directories they are mounted at.
+func (pl *Planner) shadowCopyOptions() smbcc.SmbOptions {
+	return smbcc.SmbOptions{
+		"shadow:snapdir":    pl.Paths().SnapshotsDir(),
+		"shadow:mountpoint": pl.Paths().ShareMountPath(),
+		"shadow:format":     SnapshotFormat,
+		"shadow:sort":       "desc",
+		"shadow:localtime":  smbcc.No,
+	}
+}
+
+// applyShadowCopy adds the vfs_shadow_copy2 options to the share if
+// snapshots are enabled and removes them otherwise.
+func (pl *Planner) applyShadowCopy(share smbcc.ShareConfig) bool {
+	return applyFeatureOptions(share, pl.SmbShare.Spec,
+		pl.shadowCopyOptions(), pl.SnapshotsEnabled())
+}
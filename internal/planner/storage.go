@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"strings"
+
+	api "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+// StorageKind identifies the source of the storage backing a share.
+type StorageKind string
+
+const (
+	// StorageNone means no storage has been specified.
+	StorageNone = StorageKind("")
+	// StoragePVC means the share is backed by a PVC.
+	StoragePVC = StorageKind("pvc")
+	// StoragePersistentVolume means the share is backed by an existing
+	// PersistentVolume bound by a PVC of the operator.
+	StoragePersistentVolume = StorageKind("persistentVolume")
+	// StorageEphemeral means the share is backed by a generic ephemeral
+	// volume.
+	StorageEphemeral = StorageKind("ephemeral")
+	// StorageCSI means the share is backed by an inline CSI volume.
+	StorageCSI = StorageKind("csi")
+	// StorageNFS means the share is backed by an NFS export.
+	StorageNFS = StorageKind("nfs")
+)
+
+// StorageKinds returns the kinds of all the storage sources specified for
+// the share. A valid share specifies exactly one.
+func StorageKinds(s *api.SmbShare) []StorageKind {
+	kinds := []StorageKind{}
+	st := s.Spec.Storage
+	if st.Pvc != nil {
+		kinds = append(kinds, StoragePVC)
+	}
+	if st.PersistentVolume != nil {
+		kinds = append(kinds, StoragePersistentVolume)
+	}
+	if st.Ephemeral != nil {
+		kinds = append(kinds, StorageEphemeral)
+	}
+	if st.CSI != nil {
+		kinds = append(kinds, StorageCSI)
+	}
+	if st.NFS != nil {
+		kinds = append(kinds, StorageNFS)
+	}
+	return kinds
+}
+
+// StorageKind returns the kind of storage backing the share.
+func (pl *Planner) StorageKind() StorageKind {
+	kinds := StorageKinds(pl.SmbShare)
+	if len(kinds) == 0 {
+		return StorageNone
+	}
+	return kinds[0]
+}
+
+// UsesPVC returns true if the share's storage is accessed through a PVC in
+// the namespace of the share.
+func (pl *Planner) UsesPVC() bool {
+	k := pl.StorageKind()
+	return k == StoragePVC || k == StoragePersistentVolume
+}
+
+// SharedStorage returns true if the share's storage can be accessed by
+// the servers of a cluster at the same time. Each pod gets its own
+// ephemeral volume.
+func (pl *Planner) SharedStorage() bool {
+	return pl.StorageKind() != StorageEphemeral
+}
+
+// VolumeKey returns a name identifying the volume holding the share's data
+// within the server group. Shares backed by the same PVC have the same key,
+// inline volumes are specific to the share defining them.
+func (pl *Planner) VolumeKey() string {
+	if pl.UsesPVC() {
+		return pl.PVCName()
+	}
+	return pl.SmbShare.Name + "-" + strings.ToLower(string(pl.StorageKind()))
+}
+
+// storagePath returns the path within the volume which is to be shared.
+func (pl *Planner) storagePath() string {
+	st := pl.SmbShare.Spec.Storage
+	switch pl.StorageKind() {
+	case StoragePVC:
+		return st.Pvc.Path
+	case StoragePersistentVolume:
+		return st.PersistentVolume.Path
+	case StorageEphemeral:
+		return st.Ephemeral.Path
+	case StorageCSI:
+		return st.CSI.Path
+	case StorageNone, StorageNFS:
+	}
+	return ""
+}
+
+// storageMismatch returns a reason if the shares resolve to the same
+// volume of the server group but specify it using different kinds of
+// storage, or an empty string if they don't.
+func storageMismatch(current, existing InstanceConfiguration) string {
+	p1, p2 := New(current, nil), New(existing, nil)
+	if p1.VolumeKey() != p2.VolumeKey() {
+		return ""
+	}
+	if p1.StorageKind() != p2.StorageKind() {
+		return "storage source mismatch"
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+func nfsShare(name, server, path string) *sambaoperatorv1alpha1.SmbShare {
+	return &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "smbshares",
+			UID:       "phonyuid1",
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				NFS: &sambaoperatorv1alpha1.SmbShareNFSSpec{
+					Server: server,
+					Path:   path,
+				},
+			},
+		},
+	}
+}
+
+func TestStorageKind(t *testing.T) {
+	planner := New(InstanceConfiguration{
+		SmbShare: nfsShare("share1", "nfs1", "/exports/a"),
+	}, nil)
+	assert.Equal(t, StorageNFS, planner.StorageKind())
+	assert.False(t, planner.UsesPVC())
+	assert.True(t, planner.SharedStorage())
+	assert.Equal(t, "/mnt/phonyuid1", planner.Paths().Share())
+
+	planner.SmbShare.Spec.Storage.NFS = nil
+	assert.Equal(t, StorageNone, planner.StorageKind())
+
+	planner.SmbShare.Spec.Storage.PersistentVolume =
+		&sambaoperatorv1alpha1.SmbSharePersistentVolumeSpec{
+			Name: "pv1",
+			Path: "data",
+		}
+	planner.SmbShare.Status.ServerGroup = "group1"
+	assert.Equal(t, StoragePersistentVolume, planner.StorageKind())
+	assert.True(t, planner.UsesPVC())
+	assert.Equal(t, "group1-pv1", planner.PVCName())
+	assert.Equal(t, "/mnt/phonyuid1/data", planner.Paths().Share())
+
+	planner.SmbShare.Spec.Storage.PersistentVolume = nil
+	planner.SmbShare.Spec.Storage.Ephemeral =
+		&sambaoperatorv1alpha1.SmbShareEphemeralSpec{
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{
+					corev1.ReadWriteOnce,
+				},
+			},
+		}
+	assert.Equal(t, StorageEphemeral, planner.StorageKind())
+	assert.False(t, planner.SharedStorage())
+}
+
+func TestVolumeKey(t *testing.T) {
+	planner := New(InstanceConfiguration{
+		SmbShare: nfsShare("share1", "nfs1", "/exports/a"),
+	}, nil)
+	assert.Equal(t, "share1-nfs", planner.VolumeKey())
+
+	planner.SmbShare.Spec.Storage.NFS = nil
+	planner.SmbShare.Spec.Storage.Pvc = &sambaoperatorv1alpha1.SmbSharePvcSpec{
+		Name: "data1",
+	}
+	assert.Equal(t, "data1", planner.VolumeKey())
+
+	planner.SmbShare.Spec.Storage.Pvc = nil
+	planner.SmbShare.Spec.Storage.PersistentVolume =
+		&sambaoperatorv1alpha1.SmbSharePersistentVolumeSpec{
+			Name: "pv1",
+		}
+	planner.SmbShare.Status.ServerGroup = "group1"
+	assert.Equal(t, "group1-pv1", planner.VolumeKey())
+}
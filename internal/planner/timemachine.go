@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"strconv"
+
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+const (
+	timeMachineParam        = "fruit:time machine"
+	timeMachineMaxSizeParam = "fruit:time machine max size"
+)
+
+// TimeMachineEnabled returns true if the share is offered to macOS clients
+// as a Time Machine backup destination.
+func (pl *Planner) TimeMachineEnabled() bool {
+	return pl.SmbShare.Spec.TimeMachine != nil
+}
+
+// timeMachineOptions returns the share options configuring vfs_fruit for
+// Time Machine backups.
+func (pl *Planner) timeMachineOptions() smbcc.SmbOptions {
+	maxSize := ""
+	if tm := pl.SmbShare.Spec.TimeMachine; tm != nil && tm.MaxSize != nil {
+		// samba takes a plain number as a size in bytes
+		maxSize = strconv.FormatInt(tm.MaxSize.Value(), 10)
+	}
+	return smbcc.SmbOptions{
+		timeMachineParam:        smbcc.Yes,
+		timeMachineMaxSizeParam: maxSize,
+	}
+}
+
+// applyTimeMachine adds the Time Machine options to the share if the
+// share is a Time Machine destination and removes them otherwise.
+func (pl *Planner) applyTimeMachine(share smbcc.ShareConfig) bool {
+	return applyFeatureOptions(share, pl.SmbShare.Spec,
+		pl.timeMachineOptions(), pl.TimeMachineEnabled())
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxShareNameLength is the longest share name supported by smb clients.
+const maxShareNameLength = 80
+
+// invalidShareNameChars may not appear in an SMB share name.
+const invalidShareNameChars = `"\/[]:|<>+=;,*?`
+
+// reservedShareNames are section names with a special meaning in smb.conf.
+var reservedShareNames = []string{"global", "homes", "printers", "ipc$"}
+
+// ValidateShareName returns an error if the given name can not be used as
+// the name of an SMB share.
+func ValidateShareName(name string) error {
+	if name == "" {
+		return fmt.Errorf("share name may not be empty")
+	}
+	if utf8.RuneCountInString(name) > maxShareNameLength {
+		return fmt.Errorf("share name may not be longer than %d characters",
+			maxShareNameLength)
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf(
+			"share name may not start or end with whitespace")
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf(
+				"share name may not contain control characters")
+		}
+		if strings.ContainsRune(invalidShareNameChars, r) {
+			return fmt.Errorf(
+				"share name may not contain any of the characters %s",
+				invalidShareNameChars)
+		}
+	}
+	for _, reserved := range reservedShareNames {
+		if strings.EqualFold(name, reserved) {
+			return fmt.Errorf("share name %q is reserved", name)
+		}
+	}
+	return nil
+}
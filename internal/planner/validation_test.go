@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateShareName(t *testing.T) {
+	valid := []string{
+		"share1",
+		"My Documents",
+		"data$",
+		"Café",
+		strings.Repeat("x", 80),
+	}
+	for _, name := range valid {
+		assert.NoError(t, ValidateShareName(name), name)
+	}
+
+	invalid := []string{
+		"",
+		" leading",
+		"trailing ",
+		"a/b",
+		`a\b`,
+		"a:b",
+		"what?",
+		"tab\there",
+		"Global",
+		"IPC$",
+		strings.Repeat("x", 81),
+	}
+	for _, name := range invalid {
+		assert.Error(t, ValidateShareName(name), name)
+	}
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+// constants for condition reasons.
+const (
+	ReasonReconciled          = "Reconciled"
+	ReasonProgressing         = "Progressing"
+	ReasonReconcileFailed     = "ReconcileFailed"
+	ReasonConfigApplied       = "ConfigApplied"
+	ReasonPVCBound            = "PVCBound"
+	ReasonPVCNotBound         = "PVCNotBound"
+	ReasonNoStorage           = "NoStorage"
+	ReasonInlineVolume        = "InlineVolume"
+	ReasonServerReady         = "ServerReady"
+	ReasonServerNotReady      = "ServerNotReady"
+	ReasonReplicasNotReady    = "ReplicasNotReady"
+	ReasonValid               = "Valid"
+	ReasonSecretNotFound      = "SecretNotFound"
+	ReasonInvalidSecret       = "InvalidSecret"
+	ReasonInvalidDomains      = "InvalidDomains"
+	ReasonIDMapRangeInUse     = "IDMapRangeInUse"
+	ReasonInvalidJoinOptions  = "InvalidJoinOptions"
+	ReasonConfigFailed        = "ConfigFailed"
+	ReasonStorageFailed       = "StorageFailed"
+	ReasonServerFailed        = "ServerFailed"
+	ReasonMaxClusterSize      = "MaxClusterSizeExceeded"
+	ReasonWithinLimits        = "WithinLimits"
+	ReasonWaitingForWorkgroup = "WaitingForWorkgroup"
+)
+
+// stepFailureReasons maps the conditions tracking individual steps of the
+// share reconciliation to the reason recorded when that step fails.
+var stepFailureReasons = map[string]string{
+	sambaoperatorv1alpha1.ShareConditionConfigApplied:   ReasonConfigFailed,
+	sambaoperatorv1alpha1.ShareConditionStorageBound:    ReasonStorageFailed,
+	sambaoperatorv1alpha1.ShareConditionServerAvailable: ReasonServerFailed,
+}
+
+func setShareCondition(
+	s *sambaoperatorv1alpha1.SmbShare,
+	ctype string,
+	status metav1.ConditionStatus,
+	reason, message string) {
+	// ---
+	meta.SetStatusCondition(&s.Status.Conditions, metav1.Condition{
+		Type:               ctype,
+		Status:             status,
+		ObservedGeneration: s.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func setSecurityConfigCondition(
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig,
+	ctype string,
+	status metav1.ConditionStatus,
+	reason, message string) {
+	// ---
+	meta.SetStatusCondition(&sc.Status.Conditions, metav1.Condition{
+		Type:               ctype,
+		Status:             status,
+		ObservedGeneration: sc.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// markIncomplete records a False condition of the given type for a step
+// of the reconciliation that either failed or must be revisited. The result
+// is returned unchanged.
+func markIncomplete(
+	s *sambaoperatorv1alpha1.SmbShare,
+	ctype string,
+	result Result) Result {
+	// ---
+	if result.err != nil {
+		reason, ok := stepFailureReasons[ctype]
+		if !ok {
+			reason = ReasonReconcileFailed
+		}
+		setShareCondition(s, ctype, metav1.ConditionFalse,
+			reason, result.err.Error())
+		return result
+	}
+	setShareCondition(s, ctype, metav1.ConditionFalse,
+		ReasonProgressing, "waiting for resources to be updated")
+	return result
+}
+
+// failureReason returns the reason recorded by the step that produced err,
+// falling back to a generic reason for errors outside of the tracked steps.
+func failureReason(s *sambaoperatorv1alpha1.SmbShare, err error) string {
+	for _, c := range s.Status.Conditions {
+		if _, ok := stepFailureReasons[c.Type]; !ok {
+			continue
+		}
+		if c.Status == metav1.ConditionFalse &&
+			c.ObservedGeneration == s.Generation &&
+			c.Reason == stepFailureReasons[c.Type] &&
+			c.Message == err.Error() {
+			return c.Reason
+		}
+	}
+	return ReasonReconcileFailed
+}
+
+// markReadiness derives the Ready and Degraded conditions from the
+// overall result of an update and the conditions set by individual steps.
+func markReadiness(s *sambaoperatorv1alpha1.SmbShare, result Result) {
+	switch {
+	case result.err != nil:
+		reason := failureReason(s, result.err)
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionReady,
+			metav1.ConditionFalse, reason, result.err.Error())
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionDegraded,
+			metav1.ConditionTrue, reason, result.err.Error())
+		return
+	case result.requeue:
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionReady,
+			metav1.ConditionFalse, ReasonProgressing,
+			"share resources are being reconciled")
+		return
+	}
+
+	s.Status.ObservedGeneration = s.Generation
+	required := []string{
+		sambaoperatorv1alpha1.ShareConditionConfigApplied,
+		sambaoperatorv1alpha1.ShareConditionStorageBound,
+		sambaoperatorv1alpha1.ShareConditionServerAvailable,
+	}
+	for _, ctype := range required {
+		c := meta.FindStatusCondition(s.Status.Conditions, ctype)
+		if c == nil {
+			setShareCondition(s, sambaoperatorv1alpha1.ShareConditionReady,
+				metav1.ConditionUnknown, ReasonProgressing,
+				fmt.Sprintf("%s has not been determined", ctype))
+			return
+		}
+		if c.Status != metav1.ConditionTrue {
+			setShareCondition(s, sambaoperatorv1alpha1.ShareConditionReady,
+				metav1.ConditionFalse, c.Reason, c.Message)
+			return
+		}
+	}
+	setShareCondition(s, sambaoperatorv1alpha1.ShareConditionReady,
+		metav1.ConditionTrue, ReasonReconciled, "share is available")
+}
+
+// markServerAvailability sets the ServerAvailable condition (and the
+// Degraded condition) based on the ready and desired replica counts of the
+// resource hosting the smb servers.
+func markServerAvailability(
+	s *sambaoperatorv1alpha1.SmbShare,
+	kind string,
+	ready, desired int32) {
+	// ---
+	msg := fmt.Sprintf("%s has %d of %d replicas ready", kind, ready, desired)
+	if ready < 1 {
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionServerAvailable,
+			metav1.ConditionFalse, ReasonServerNotReady, msg)
+	} else {
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionServerAvailable,
+			metav1.ConditionTrue, ReasonServerReady, msg)
+	}
+	if ready > 0 && ready < desired {
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionDegraded,
+			metav1.ConditionTrue, ReasonReplicasNotReady, msg)
+	} else {
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionDegraded,
+			metav1.ConditionFalse, ReasonReconciled, "")
+	}
+}
+
+func (m *SmbShareManager) updateStatus(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare,
+	previous *sambaoperatorv1alpha1.SmbShareStatus) error {
+	// ---
+	if equality.Semantic.DeepEqual(previous, &s.Status) {
+		return nil
+	}
+	err := m.client.Status().Update(ctx, s)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update SmbShare status",
+			"SmbShare.Namespace", s.Namespace,
+			"SmbShare.Name", s.Name,
+			"SmbShare.UID", s.UID)
+	}
+	return err
+}
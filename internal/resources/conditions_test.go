@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+func TestMarkReadiness(t *testing.T) {
+	newShare := func() *sambaoperatorv1alpha1.SmbShare {
+		return &sambaoperatorv1alpha1.SmbShare{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "wilma",
+				Namespace:  "bedrock",
+				Generation: 3,
+			},
+		}
+	}
+
+	t.Run("error", func(t *testing.T) {
+		s := newShare()
+		markIncomplete(s, sambaoperatorv1alpha1.ShareConditionConfigApplied,
+			Result{err: fmt.Errorf("oops")})
+		markReadiness(s, Result{err: fmt.Errorf("oops")})
+		c := meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionConfigApplied)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, metav1.ConditionFalse, c.Status)
+			assert.Equal(t, ReasonConfigFailed, c.Reason)
+			assert.Equal(t, "oops", c.Message)
+		}
+		c = meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionReady)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, metav1.ConditionFalse, c.Status)
+			assert.Equal(t, ReasonConfigFailed, c.Reason)
+			assert.Equal(t, "oops", c.Message)
+		}
+		assert.True(t, meta.IsStatusConditionTrue(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionDegraded))
+		assert.Equal(t, int64(0), s.Status.ObservedGeneration)
+	})
+
+	t.Run("untrackedError", func(t *testing.T) {
+		s := newShare()
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionConfigApplied,
+			metav1.ConditionTrue, ReasonConfigApplied, "")
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionReady,
+			metav1.ConditionTrue, ReasonReconciled, "share is available")
+		markReadiness(s, Result{err: fmt.Errorf("metrics")})
+		c := meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionReady)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, metav1.ConditionFalse, c.Status)
+			assert.Equal(t, ReasonReconcileFailed, c.Reason)
+			assert.Equal(t, "metrics", c.Message)
+		}
+	})
+
+	t.Run("requeue", func(t *testing.T) {
+		s := newShare()
+		markReadiness(s, Requeue)
+		c := meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionReady)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, metav1.ConditionFalse, c.Status)
+			assert.Equal(t, ReasonProgressing, c.Reason)
+		}
+		assert.Equal(t, int64(0), s.Status.ObservedGeneration)
+	})
+
+	t.Run("serverNotReady", func(t *testing.T) {
+		s := newShare()
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionConfigApplied,
+			metav1.ConditionTrue, ReasonConfigApplied, "")
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionTrue, ReasonPVCBound, "")
+		markServerAvailability(s, "Deployment", 0, 1)
+		markReadiness(s, Done)
+		c := meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionReady)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, metav1.ConditionFalse, c.Status)
+			assert.Equal(t, ReasonServerNotReady, c.Reason)
+		}
+		assert.Equal(t, int64(3), s.Status.ObservedGeneration)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		s := newShare()
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionConfigApplied,
+			metav1.ConditionTrue, ReasonConfigApplied, "")
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionTrue, ReasonPVCBound, "")
+		markServerAvailability(s, "StatefulSet", 2, 3)
+		markReadiness(s, Done)
+		assert.True(t, meta.IsStatusConditionTrue(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionReady))
+		assert.True(t, meta.IsStatusConditionTrue(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionDegraded))
+		c := meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionReady)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, int64(3), c.ObservedGeneration)
+		}
+	})
+}
+
+func TestMarkConversionDone(t *testing.T) {
+	s := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "betty",
+			Namespace:   "bedrock",
+			Annotations: map[string]string{serverBackend: clusteredBackend},
+		},
+	}
+	markConversionDone(s)
+	assert.Nil(t, meta.FindStatusCondition(
+		s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionConverting))
+
+	markConversion(s, ReasonWaitingForServers, "waiting")
+	assert.True(t, meta.IsStatusConditionTrue(
+		s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionConverting))
+	markConversionDone(s)
+	c := meta.FindStatusCondition(
+		s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionConverting)
+	if assert.NotNil(t, c) {
+		assert.Equal(t, metav1.ConditionFalse, c.Status)
+		assert.Equal(t, ReasonConversionComplete, c.Reason)
+		assert.Contains(t, c.Message, clusteredBackend)
+	}
+}
@@ -30,6 +30,10 @@ const (
 
 	// ConfigJSONKey is the name of the key our json is under.
 	ConfigJSONKey = "config.json"
+
+	// primaryVolumeAnnotation records the key of the volume that is
+	// mounted at the path of the server group.
+	primaryVolumeAnnotation = "samba-operator.samba.org/primary-volume"
 )
 
 func newDefaultConfigMap(name, ns string) (*corev1.ConfigMap, error) {
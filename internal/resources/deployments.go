@@ -30,16 +30,22 @@ var (
 	serviceLabel        = "samba-operator.samba.org/service"
 	commonConfigLabel   = "samba-operator.samba.org/common-config-from"
 	securityConfigLabel = "samba-operator.samba.org/security-config-from"
+	serverBackendLabel  = "samba-operator.samba.org/server-backend"
+)
+
+const (
+	standardBackendLabelValue  = "standard"
+	clusteredBackendLabelValue = "clustered"
 )
 
 // buildDeployment returns a samba server deployment object
 func buildDeployment(cfg *conf.OperatorConfig,
-	planner *pln.Planner, pvcName, ns string) *appsv1.Deployment {
+	planner *pln.Planner, shareVols []volMount, ns string) *appsv1.Deployment {
 	// construct a deployment based on the following labels
 	labels := labelsForSmbServer(planner)
 	var size int32 = 1
 
-	podSpec := buildPodSpec(planner, cfg, pvcName)
+	podSpec := buildPodSpec(planner, cfg, shareVols)
 	podSpec.Affinity = affinityForSmbPod(planner)
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -57,7 +63,7 @@ func buildDeployment(cfg *conf.OperatorConfig,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      labels,
+					Labels:      podLabelsForSmbServer(planner),
 					Annotations: annotationsForSmbPod(cfg),
 				},
 				Spec: podSpec,
@@ -80,6 +86,23 @@ func labelsForSmbServer(planner *pln.Planner) map[string]string {
 	return labels
 }
 
+// podLabelsForSmbServer returns the labels for pods belonging to the given
+// CR name. In addition to the server labels the pods are labeled with the
+// kind of backend hosting them so that the service can be pointed at
+// one backend or the other when converting between availability modes.
+func podLabelsForSmbServer(planner *pln.Planner) map[string]string {
+	labels := labelsForSmbServer(planner)
+	labels[serverBackendLabel] = serverBackendLabelValue(planner)
+	return labels
+}
+
+func serverBackendLabelValue(planner *pln.Planner) string {
+	if planner.IsClustered() {
+		return clusteredBackendLabelValue
+	}
+	return standardBackendLabelValue
+}
+
 func labelsForManagedResource(name string) map[string]string {
 	return map[string]string{
 		// top level labes
@@ -24,8 +24,32 @@ const (
 
 // constants for event reasons.
 const (
-	ReasonCreatedPersistentVolumeClaim = "CreatedPersistentVolumeClaim"
-	ReasonCreatedDeployment            = "CreatedDeployment"
-	ReasonCreatedStatefulSet           = "CreatedStatefulSet"
-	ReasonInvalidConfiguration         = "InvalidConfiguration"
+	ReasonCreatedPersistentVolumeClaim  = "CreatedPersistentVolumeClaim"
+	ReasonRetainedPersistentVolumeClaim = "RetainedPersistentVolumeClaim"
+	ReasonAdoptedPersistentVolumeClaim  = "AdoptedPersistentVolumeClaim"
+	ReasonCreatedDeployment             = "CreatedDeployment"
+	ReasonCreatedStatefulSet            = "CreatedStatefulSet"
+	ReasonInvalidConfiguration          = "InvalidConfiguration"
+	ReasonConvertedBackend              = "ConvertedBackend"
+	ReasonDeletedDeployment             = "DeletedDeployment"
+	ReasonDeletedStatefulSet            = "DeletedStatefulSet"
+	ReasonRemovingClusterNode           = "RemovingClusterNode"
+	ReasonScaledDownCluster             = "ScaledDownCluster"
+	ReasonRenamedShare                  = "RenamedShare"
+	ReasonExpandingVolume               = "ExpandingVolume"
+	ReasonVolumeResizing                = "VolumeResizing"
+	ReasonVolumeResized                 = "VolumeResized"
+	ReasonFileSystemResizePending       = "FileSystemResizePending"
+	ReasonExpansionNotSupported         = "ExpansionNotSupported"
+	ReasonCreatedVolumeSnapshot         = "CreatedVolumeSnapshot"
+	ReasonDeletedVolumeSnapshot         = "DeletedVolumeSnapshot"
+	ReasonSnapshotReady                 = "SnapshotReady"
+	ReasonSnapshotPending               = "SnapshotPending"
+	ReasonSnapshotTimedOut              = "SnapshotTimedOut"
+	ReasonSnapshotAPIUnavailable        = "VolumeSnapshotAPIUnavailable"
+	ReasonDiscoveringWorkgroup          = "DiscoveringWorkgroup"
+	ReasonDiscoveredWorkgroup           = "DiscoveredWorkgroup"
+	ReasonWorkgroupDiscoveryFailed      = "WorkgroupDiscoveryFailed"
+	ReasonLeavingDomain                 = "LeavingDomain"
+	ReasonLeftDomain                    = "LeftDomain"
 )
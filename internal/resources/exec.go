@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// PodExecer runs commands in the containers of running pods.
+type PodExecer interface {
+	// Exec runs the command in the container of the pod and returns the
+	// output of the command.
+	Exec(ctx context.Context,
+		ns, pod, container string, cmd []string) ([]byte, error)
+}
+
+type podExecer struct {
+	cfg    *rest.Config
+	client rest.Interface
+}
+
+// NewPodExecer returns a PodExecer running commands through the API
+// server of the given config.
+func NewPodExecer(cfg *rest.Config) (PodExecer, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &podExecer{cfg: cfg, client: clientset.CoreV1().RESTClient()}, nil
+}
+
+func (e *podExecer) Exec(
+	ctx context.Context,
+	ns, pod, container string,
+	cmd []string) ([]byte, error) {
+	// ---
+	req := e.client.Post().
+		Resource("pods").
+		Namespace(ns).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(
+		e.cfg, http.MethodPost, req.URL())
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %v in %s/%s container %s: %w: %s",
+			cmd, ns, pod, container, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
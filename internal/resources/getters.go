@@ -6,6 +6,7 @@ import (
 	"context"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	kresource "k8s.io/apimachinery/pkg/api/resource"
@@ -62,8 +63,11 @@ func (m *SmbShareManager) getOrCreateDeployment(
 
 	// not found - define a new deployment
 	// labels - do I need them?
-	dep := buildDeployment(
-		m.cfg, planner, planner.SmbShare.Spec.Storage.Pvc.Name, ns)
+	shareVols, err := m.shareVolumes(ctx, planner)
+	if err != nil {
+		return nil, false, err
+	}
+	dep := buildDeployment(m.cfg, planner, shareVols, ns)
 	// set the smbshare instance as the owner and controller
 	err = controllerutil.SetControllerReference(
 		planner.SmbShare, dep, m.scheme)
@@ -133,7 +137,20 @@ func (m *SmbShareManager) getOrCreatePvc(
 	ns string) (*corev1.PersistentVolumeClaim, bool, error) {
 	// ---
 	name := pvcName(smbShare)
-	spec := smbShare.Spec.Storage.Pvc.Spec
+	var spec *corev1.PersistentVolumeClaimSpec
+	if pv := smbShare.Spec.Storage.PersistentVolume; pv != nil {
+		// only look up the volume if the claim is yet to be created
+		pvc, err := m.getExistingPVC(ctx, name, ns)
+		if err != nil || pvc != nil {
+			return pvc, false, err
+		}
+		spec, err = m.persistentVolumeClaimSpec(ctx, pv.Name)
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		spec = smbShare.Spec.Storage.Pvc.Spec
+	}
 	pvc, cr, err := m.getOrCreateGenericPVC(
 		ctx, smbShare, spec, name, ns)
 	if err != nil {
@@ -403,9 +420,13 @@ func (m *SmbShareManager) getOrCreateStatefulSet(
 	}
 
 	// not found - define a new stateful set
+	shareVols, err := m.shareVolumes(ctx, planner)
+	if err != nil {
+		return nil, false, err
+	}
 	ss := buildStatefulSet(
 		planner,
-		planner.SmbShare.Spec.Storage.Pvc.Name,
+		shareVols,
 		sharedStatePVCName(planner),
 		ns)
 	// set the smbshare instance as the owner/controller
@@ -442,6 +463,124 @@ func (m *SmbShareManager) getOrCreateStatefulSet(
 	return ss, true, err
 }
 
+func (m *SmbShareManager) getOrCreateCTDBRemoveNodeJob(
+	ctx context.Context,
+	planner *pln.Planner,
+	ns string,
+	pnn int32) (*batchv1.Job, bool, error) {
+	// ---
+	found := &batchv1.Job{}
+	jobKey := types.NamespacedName{
+		Name:      ctdbRemoveNodeJobName(planner, pnn),
+		Namespace: ns,
+	}
+	err := m.client.Get(ctx, jobKey, found)
+	if err == nil {
+		return found, false, nil
+	}
+	if !errors.IsNotFound(err) {
+		m.logger.Error(
+			err,
+			"Failed to get Job",
+			"SmbShare.Namespace", planner.SmbShare.Namespace,
+			"SmbShare.Name", planner.SmbShare.Name,
+			"Job.Namespace", jobKey.Namespace,
+			"Job.Name", jobKey.Name)
+		return nil, false, err
+	}
+
+	job := buildCTDBRemoveNodeJob(
+		planner, sharedStatePVCName(planner), ns, pnn)
+	err = controllerutil.SetControllerReference(
+		planner.SmbShare, job, m.scheme)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to set controller reference",
+			"SmbShare.Namespace", planner.SmbShare.Namespace,
+			"SmbShare.Name", planner.SmbShare.Name,
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return job, false, err
+	}
+	m.logger.Info(
+		"Creating a new Job",
+		"SmbShare.Namespace", planner.SmbShare.Namespace,
+		"SmbShare.Name", planner.SmbShare.Name,
+		"Job.Namespace", job.Namespace,
+		"Job.Name", job.Name)
+	err = m.client.Create(ctx, job)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to create new Job",
+			"SmbShare.Namespace", planner.SmbShare.Namespace,
+			"SmbShare.Name", planner.SmbShare.Name,
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
+func (m *SmbShareManager) getOrCreateDomainLeaveJob(
+	ctx context.Context,
+	planner *pln.Planner,
+	ns string) (*batchv1.Job, bool, error) {
+	// ---
+	found := &batchv1.Job{}
+	jobKey := types.NamespacedName{
+		Name:      domainLeaveJobName(planner),
+		Namespace: ns,
+	}
+	err := m.client.Get(ctx, jobKey, found)
+	if err == nil {
+		return found, false, nil
+	}
+	if !errors.IsNotFound(err) {
+		m.logger.Error(
+			err,
+			"Failed to get Job",
+			"SmbShare.Namespace", planner.SmbShare.Namespace,
+			"SmbShare.Name", planner.SmbShare.Name,
+			"Job.Namespace", jobKey.Namespace,
+			"Job.Name", jobKey.Name)
+		return nil, false, err
+	}
+
+	job := buildDomainLeaveJob(planner, ns)
+	err = controllerutil.SetControllerReference(
+		planner.SmbShare, job, m.scheme)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to set controller reference",
+			"SmbShare.Namespace", planner.SmbShare.Namespace,
+			"SmbShare.Name", planner.SmbShare.Name,
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return job, false, err
+	}
+	m.logger.Info(
+		"Creating a new Job",
+		"SmbShare.Namespace", planner.SmbShare.Namespace,
+		"SmbShare.Name", planner.SmbShare.Name,
+		"Job.Namespace", job.Namespace,
+		"Job.Name", job.Name)
+	err = m.client.Create(ctx, job)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to create new Job",
+			"SmbShare.Namespace", planner.SmbShare.Namespace,
+			"SmbShare.Name", planner.SmbShare.Name,
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return job, false, err
+	}
+	return job, true, nil
+}
+
 func (m *SmbShareManager) getSecurityConfig(
 	ctx context.Context, s *sambaoperatorv1alpha1.SmbShare) (
 	*sambaoperatorv1alpha1.SmbSecurityConfig, error) {
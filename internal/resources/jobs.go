@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+func ctdbRemoveNodeJobName(planner *pln.Planner, pnn int32) string {
+	return fmt.Sprintf("%s-rmnode-%d", planner.InstanceName(), pnn)
+}
+
+// labelsForJob returns the labels for jobs run on behalf of a server group.
+// Unlike the server labels these must not match the selectors of the
+// service or the server pods.
+func labelsForJob(planner *pln.Planner, component string) map[string]string {
+	return jobLabels(planner.InstanceName(), component)
+}
+
+func jobLabels(instance, component string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "samba",
+		"app.kubernetes.io/instance":   labelValue("samba", instance),
+		"app.kubernetes.io/component":  component,
+		"app.kubernetes.io/part-of":    "samba",
+		"app.kubernetes.io/managed-by": "samba-operator",
+	}
+}
+
+func buildCTDBRemoveNodeJob(
+	planner *pln.Planner,
+	statePVCName, ns string,
+	pnn int32) *batchv1.Job {
+	// ---
+	var backoffLimit int32 = 3
+	labels := labelsForJob(planner, "ctdb-remove-node")
+	ctdbSharedVol := ctdbSharedStateVolumeAndMount(planner, statePVCName)
+	vols := newVolKeeper().
+		add(configVolumeAndMount(planner)).
+		add(ctdbSharedVol)
+	// nolint:gocritic
+	env := append(defaultPodEnv(planner), ctdbHostnameEnv(planner)...)
+
+	podSpec := defaultPodSpec(planner)
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.Volumes = getVolumes(vols.all())
+	podSpec.Containers = []corev1.Container{{
+		Image:           planner.GlobalConfig.SmbdContainerImage,
+		ImagePullPolicy: imagePullPolicy(planner),
+		Name:            "ctdb-remove-node",
+		Args:            planner.Args().CTDBRemoveNode(pnn),
+		Env:             env,
+		VolumeMounts:    getMounts(vols.all()),
+	}}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ctdbRemoveNodeJobName(planner, pnn),
+			Namespace: ns,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+func workgroupJobName(sc *sambaoperatorv1alpha1.SmbSecurityConfig) string {
+	return sc.Name + "-workgroup"
+}
+
+// buildWorkgroupJob returns a job looking up the NetBIOS name of the realm
+// of the security config. The planner only needs to hold the security and
+// operator configs.
+func buildWorkgroupJob(planner *pln.Planner, ns string) *batchv1.Job {
+	var backoffLimit int32 = 3
+	sc := planner.SecurityConfig
+	labels := jobLabels(sc.Name, "workgroup-lookup")
+	podSpec := defaultPodSpec(planner)
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.Containers = []corev1.Container{{
+		Image:           planner.GlobalConfig.SmbdContainerImage,
+		ImagePullPolicy: imagePullPolicy(planner),
+		Name:            "workgroup-lookup",
+		Command:         planner.Args().WorkgroupLookup(planner.Realm()),
+	}}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workgroupJobName(sc),
+			Namespace: ns,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+func domainLeaveJobName(planner *pln.Planner) string {
+	return planner.InstanceName() + "-leave"
+}
+
+// buildDomainLeaveJob returns a job removing the computer account of the
+// server group from the domain. The job uses the join sources the servers
+// were built with and gives up after domainLeaveTimeout.
+func buildDomainLeaveJob(planner *pln.Planner, ns string) *batchv1.Job {
+	var backoffLimit int32 = 3
+	deadline := int64(domainLeaveTimeout.Seconds())
+	labels := labelsForJob(planner, "domain-leave")
+	jsrc := getJoinSources(planner)
+	vols := newVolKeeper().
+		add(configVolumeAndMount(planner)).
+		extend(jsrc.volumes)
+	// nolint:gocritic
+	env := append(defaultPodEnv(planner), joinSourceEnv(jsrc)...)
+
+	podSpec := defaultPodSpec(planner)
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.Volumes = getVolumes(vols.all())
+	podSpec.Containers = []corev1.Container{{
+		Image:           planner.GlobalConfig.SmbdContainerImage,
+		ImagePullPolicy: imagePullPolicy(planner),
+		Name:            "domain-leave",
+		Args:            planner.Args().DomainLeave(jsrc.keytabs),
+		Env:             env,
+		VolumeMounts:    getMounts(vols.all()),
+	}}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      domainLeaveJobName(planner),
+			Namespace: ns,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+// jobFinished returns true if the job has finished and the condition type
+// (Complete or Failed) it finished with.
+func jobFinished(job *batchv1.Job) (bool, *batchv1.JobCondition) {
+	for i := range job.Status.Conditions {
+		c := &job.Status.Conditions[i]
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) &&
+			c.Status == corev1.ConditionTrue {
+			return true, c
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+func TestBuildCTDBRemoveNodeJob(t *testing.T) {
+	planner := pln.New(
+		pln.InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "fred",
+					Namespace: "bedrock",
+				},
+				Status: sambaoperatorv1alpha1.SmbShareStatus{
+					ServerGroup: "fred",
+				},
+			},
+			GlobalConfig: &conf.OperatorConfig{},
+		},
+		&smbcc.SambaContainerConfig{})
+
+	job := buildCTDBRemoveNodeJob(planner, "fred-state", "bedrock", 2)
+	assert.Equal(t, "fred-rmnode-2", job.Name)
+	assert.Equal(t, corev1.RestartPolicyNever,
+		job.Spec.Template.Spec.RestartPolicy)
+	if assert.Len(t, job.Spec.Template.Spec.Containers, 1) {
+		assert.Equal(t,
+			[]string{"ctdb-remove-node", "--node-number=2"},
+			job.Spec.Template.Spec.Containers[0].Args)
+	}
+	if assert.Len(t, job.Spec.Template.Spec.Volumes, 2) {
+		assert.Equal(t, "fred-state",
+			job.Spec.Template.Spec.Volumes[1].PersistentVolumeClaim.ClaimName)
+	}
+	// the job's pods must not be picked up by the service
+	_, found := job.Spec.Template.Labels[svcSelectorKey]
+	assert.False(t, found)
+}
+
+func TestJobFinished(t *testing.T) {
+	job := &batchv1.Job{}
+	finished, _ := jobFinished(job)
+	assert.False(t, finished)
+
+	job.Status.Conditions = []batchv1.JobCondition{{
+		Type:   batchv1.JobFailed,
+		Status: corev1.ConditionTrue,
+	}}
+	finished, c := jobFinished(job)
+	assert.True(t, finished)
+	if assert.NotNil(t, c) {
+		assert.Equal(t, batchv1.JobFailed, c.Type)
+	}
+}
+
+func TestBuildWorkgroupJob(t *testing.T) {
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+		},
+	}
+	assert.True(t, needsWorkgroup(sc))
+	planner := pln.New(
+		pln.InstanceConfiguration{
+			SmbShare:       &sambaoperatorv1alpha1.SmbShare{},
+			SecurityConfig: sc,
+			GlobalConfig:   &conf.OperatorConfig{},
+		},
+		nil)
+
+	job := buildWorkgroupJob(planner, "bedrock")
+	assert.Equal(t, "addc-workgroup", job.Name)
+	assert.Equal(t, "bedrock", job.Namespace)
+	if assert.Len(t, job.Spec.Template.Spec.Containers, 1) {
+		cmd := job.Spec.Template.Spec.Containers[0].Command
+		assert.Equal(t, "BEDROCK.EXAMPLE.COM", cmd[len(cmd)-1])
+	}
+	_, found := job.Spec.Template.Labels[svcSelectorKey]
+	assert.False(t, found)
+
+	sc.Status.Workgroup = "BEDROCK"
+	assert.False(t, needsWorkgroup(sc))
+	sc.Status.Workgroup = ""
+	sc.Spec.Workgroup = "BEDROCK"
+	assert.False(t, needsWorkgroup(sc))
+}
+
+func TestBuildDomainLeaveJob(t *testing.T) {
+	planner := pln.New(
+		pln.InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "fred",
+					Namespace: "bedrock",
+				},
+				Status: sambaoperatorv1alpha1.SmbShareStatus{
+					ServerGroup: "fred",
+				},
+			},
+			SecurityConfig: &sambaoperatorv1alpha1.SmbSecurityConfig{
+				Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+					Mode:      "active-directory",
+					Realm:     "bedrock.example.com",
+					Workgroup: "BEDROCK",
+					JoinSources: []sambaoperatorv1alpha1.SmbSecurityJoinSpec{{
+						KeytabJoin: &sambaoperatorv1alpha1.SmbSecurityKeytabJoinSpec{
+							Secret: "keytab1",
+							Key:    "krb5.keytab",
+						},
+					}},
+				},
+			},
+			GlobalConfig: &conf.OperatorConfig{},
+		},
+		nil)
+
+	job := buildDomainLeaveJob(planner, "bedrock")
+	assert.Equal(t, "fred-leave", job.Name)
+	assert.Equal(t, int64(domainLeaveTimeout.Seconds()),
+		*job.Spec.ActiveDeadlineSeconds)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, corev1.RestartPolicyNever, podSpec.RestartPolicy)
+	if assert.Len(t, podSpec.Volumes, 2) {
+		assert.NotNil(t, podSpec.Volumes[0].ConfigMap)
+		assert.Equal(t, "keytab1", podSpec.Volumes[1].Secret.SecretName)
+	}
+	if assert.Len(t, podSpec.Containers, 1) {
+		ctr := podSpec.Containers[0]
+		assert.Equal(t,
+			[]string{"leave", "--keytab=/var/tmp/join/0/krb5.keytab"},
+			ctr.Args)
+		assert.Contains(t, ctr.Env, corev1.EnvVar{
+			Name:  "SAMBACC_JOIN_FILES",
+			Value: "",
+		})
+		assert.Len(t, ctr.VolumeMounts, 2)
+	}
+}
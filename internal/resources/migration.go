@@ -0,0 +1,437 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+// constants for backend conversion condition reasons.
+const (
+	ReasonCreatingStatePVC   = "CreatingStatePVC"
+	ReasonStartingServers    = "StartingServers"
+	ReasonWaitingForServers  = "WaitingForServers"
+	ReasonSwitchingService   = "SwitchingService"
+	ReasonRemovingOldServers = "RemovingOldServers"
+	ReasonMovingConfig       = "MovingConfig"
+	ReasonConversionComplete = "ConversionComplete"
+)
+
+func markConversion(
+	s *sambaoperatorv1alpha1.SmbShare,
+	reason, message string) {
+	// ---
+	setShareCondition(s, sambaoperatorv1alpha1.ShareConditionConverting,
+		metav1.ConditionTrue, reason, message)
+}
+
+// markConversionDone records that a previously started conversion is
+// complete. Nothing is recorded if no conversion was ever started.
+func markConversionDone(s *sambaoperatorv1alpha1.SmbShare) {
+	if !meta.IsStatusConditionTrue(
+		s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionConverting) {
+		return
+	}
+	setShareCondition(s, sambaoperatorv1alpha1.ShareConditionConverting,
+		metav1.ConditionFalse, ReasonConversionComplete,
+		fmt.Sprintf("share is hosted by backend %s",
+			s.Annotations[serverBackend]))
+}
+
+// convertBackend converts the server group hosting the share between the
+// standard (Deployment) and clustered (CTDB StatefulSet) backends. The new
+// backend is brought up next to the old one and the service is switched
+// over before the old backend is removed. Each call performs at most one
+// step of the conversion. Once all steps are complete the new backend is
+// recorded on the SmbShare.
+func (m *SmbShareManager) convertBackend(
+	ctx context.Context,
+	planner *pln.Planner) Result {
+	// ---
+	smbshare := planner.SmbShare
+	if err := m.checkConvertible(ctx, planner); err != nil {
+		m.logger.Error(
+			err,
+			"Unable to convert backend",
+			"SmbShare.Namespace", smbshare.Namespace,
+			"SmbShare.Name", smbshare.Name,
+			"SmbShare.UID", smbshare.UID)
+		m.recorder.Event(
+			smbshare,
+			EventWarning,
+			ReasonInvalidConfiguration,
+			err.Error())
+		return Result{err: err}
+	}
+
+	var result Result
+	if planner.IsClustered() {
+		result = m.convertToClustered(ctx, planner)
+	} else {
+		result = m.convertToStandard(ctx, planner)
+	}
+	if result.Yield() {
+		return result
+	}
+
+	m.recorder.Eventf(smbshare,
+		EventNormal,
+		ReasonConvertedBackend,
+		"Converted backend from %s", smbshare.Annotations[serverBackend])
+	return m.updateBackend(ctx, planner)
+}
+
+func (m *SmbShareManager) checkConvertible(
+	ctx context.Context,
+	planner *pln.Planner) error {
+	// ---
+	smbshare := planner.SmbShare
+	b := smbshare.Annotations[serverBackend]
+	if b != standardBackend && b != clusteredBackend {
+		return fmt.Errorf("Can not convert unknown backend: %s", b)
+	}
+	if planner.IsClustered() && !planner.MayCluster() {
+		return fmt.Errorf(
+			"CTDB clustering not enabled in ClusterSupport: %v",
+			planner.GlobalConfig.ClusterSupport)
+	}
+	if planner.IsClustered() && !planner.SharedStorage() {
+		return fmt.Errorf(
+			"Can not convert to clustered: storage kind %s"+
+				" can not be used by clustered shares",
+			planner.StorageKind())
+	}
+	if planner.IsClustered() && planner.UsesPVC() {
+		if err := m.checkSharedPVC(ctx, planner); err != nil {
+			return err
+		}
+	}
+	cm, err := m.getConfigMap(ctx, smbshare, smbshare.Namespace)
+	if err != nil {
+		return err
+	}
+	otherShares, err := ownerSharesExcluding(cm, smbshare)
+	if err != nil {
+		return err
+	}
+	if len(otherShares) > 0 {
+		return fmt.Errorf(
+			"Can not convert backend of server group %s:"+
+				" group hosts more than one share",
+			planner.InstanceName())
+	}
+	return nil
+}
+
+// checkSharedPVC returns an error unless the PVC holding the share's data
+// can be mounted by the servers of a cluster at the same time.
+func (m *SmbShareManager) checkSharedPVC(
+	ctx context.Context,
+	planner *pln.Planner) error {
+	// ---
+	pvc, err := m.getExistingPVC(
+		ctx, planner.PVCName(), planner.SmbShare.Namespace)
+	if err != nil {
+		return err
+	}
+	if pvc == nil {
+		return fmt.Errorf(
+			"Can not convert to clustered: PVC %s not found",
+			planner.PVCName())
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteMany {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"Can not convert to clustered: PVC %s does not support"+
+			" access mode %s",
+		pvc.Name, corev1.ReadWriteMany)
+}
+
+// conversionPhase returns the progress of the conversion of the server
+// group between backends, based on the recorded backend and the presence of
+// its servers.
+func (m *SmbShareManager) conversionPhase(
+	ctx context.Context,
+	planner *pln.Planner) (pln.ConversionPhase, error) {
+	// ---
+	ns := planner.SmbShare.Namespace
+	oldServersFound := false
+	switch planner.SmbShare.Annotations[serverBackend] {
+	case standardBackend:
+		if !planner.IsClustered() {
+			return pln.NotConverting, nil
+		}
+		d, err := m.getExistingDeployment(ctx, planner, ns)
+		if err != nil {
+			return pln.NotConverting, err
+		}
+		oldServersFound = d != nil
+	case clusteredBackend:
+		if planner.IsClustered() {
+			return pln.NotConverting, nil
+		}
+		ss, err := m.getExistingStatefulSet(ctx, planner, ns)
+		if err != nil {
+			return pln.NotConverting, err
+		}
+		oldServersFound = ss != nil
+	default:
+		return pln.NotConverting, nil
+	}
+	if oldServersFound {
+		return pln.ConvertingServers, nil
+	}
+	return pln.ConvertingConfig, nil
+}
+
+func (m *SmbShareManager) convertToClustered(
+	ctx context.Context,
+	planner *pln.Planner) Result {
+	// ---
+	smbshare := planner.SmbShare
+	ns := smbshare.Namespace
+	_, created, err := m.getOrCreateStatePVC(ctx, planner, ns)
+	if err != nil {
+		return Result{err: err}
+	}
+	if created {
+		m.logger.Info("Created shared state PVC")
+		markConversion(smbshare, ReasonCreatingStatePVC,
+			"created shared state PVC")
+		return Requeue
+	}
+
+	statefulSet, created, err := m.getOrCreateStatefulSet(ctx, planner, ns)
+	if err != nil {
+		return Result{err: err}
+	}
+	if created {
+		m.logger.Info("Created StatefulSet")
+		m.recorder.Eventf(smbshare,
+			EventNormal,
+			ReasonCreatedStatefulSet,
+			"Created stateful set %s for SmbShare", statefulSet.Name)
+		markConversion(smbshare, ReasonStartingServers,
+			"created stateful set "+statefulSet.Name)
+		return Requeue
+	}
+	if statefulSet.Status.ReadyReplicas < 1 {
+		markConversion(smbshare, ReasonWaitingForServers,
+			"waiting for stateful set "+statefulSet.Name+" to become ready")
+		return Requeue
+	}
+
+	if result := m.switchServiceBackend(ctx, planner); result.Yield() {
+		return result
+	}
+
+	deployment, err := m.getExistingDeployment(ctx, planner, ns)
+	if err != nil {
+		return Result{err: err}
+	}
+	if deployment != nil {
+		if err := m.deleteServerResource(ctx, deployment); err != nil {
+			return Result{err: err}
+		}
+		m.recorder.Eventf(smbshare,
+			EventNormal,
+			ReasonDeletedDeployment,
+			"Deleted deployment %s for SmbShare", deployment.Name)
+		markConversion(smbshare, ReasonRemovingOldServers,
+			"deleted deployment "+deployment.Name)
+		return Requeue
+	}
+	return m.moveToSharedConfig(ctx, planner, statefulSet,
+		&statefulSet.Spec.Template,
+		statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+			statefulSet.Status.UpdatedReplicas >= statefulSet.Status.Replicas)
+}
+
+func (m *SmbShareManager) convertToStandard(
+	ctx context.Context,
+	planner *pln.Planner) Result {
+	// ---
+	smbshare := planner.SmbShare
+	ns := smbshare.Namespace
+	deployment, created, err := m.getOrCreateDeployment(ctx, planner, ns)
+	if err != nil {
+		return Result{err: err}
+	}
+	if created {
+		m.logger.Info("Created deployment")
+		m.recorder.Eventf(smbshare,
+			EventNormal,
+			ReasonCreatedDeployment,
+			"Created deployment %s for SmbShare", deployment.Name)
+		markConversion(smbshare, ReasonStartingServers,
+			"created deployment "+deployment.Name)
+		return Requeue
+	}
+	if deployment.Status.ReadyReplicas < 1 {
+		markConversion(smbshare, ReasonWaitingForServers,
+			"waiting for deployment "+deployment.Name+" to become ready")
+		return Requeue
+	}
+
+	if result := m.switchServiceBackend(ctx, planner); result.Yield() {
+		return result
+	}
+
+	statefulSet, err := m.getExistingStatefulSet(ctx, planner, ns)
+	if err != nil {
+		return Result{err: err}
+	}
+	if statefulSet != nil {
+		if err := m.deleteServerResource(ctx, statefulSet); err != nil {
+			return Result{err: err}
+		}
+		m.recorder.Eventf(smbshare,
+			EventNormal,
+			ReasonDeletedStatefulSet,
+			"Deleted stateful set %s for SmbShare", statefulSet.Name)
+		markConversion(smbshare, ReasonRemovingOldServers,
+			"deleted stateful set "+statefulSet.Name)
+		return Requeue
+	}
+
+	// the shared state is only meaningful to the ctdb cluster
+	sspvc, err := m.getExistingPVC(ctx, sharedStatePVCName(planner), ns)
+	if err != nil {
+		return Result{err: err}
+	}
+	if sspvc != nil {
+		if err := m.deleteServerResource(ctx, sspvc); err != nil {
+			return Result{err: err}
+		}
+		markConversion(smbshare, ReasonRemovingOldServers,
+			"deleted shared state PVC "+sspvc.Name)
+		return Requeue
+	}
+	return m.moveToSharedConfig(ctx, planner, deployment,
+		&deployment.Spec.Template,
+		deployment.Status.ObservedGeneration >= deployment.Generation &&
+			deployment.Status.UpdatedReplicas >= deployment.Status.Replicas)
+}
+
+// switchServiceBackend points the service's selector at the pods of the
+// backend the planner calls for.
+func (m *SmbShareManager) switchServiceBackend(
+	ctx context.Context,
+	planner *pln.Planner) Result {
+	// ---
+	svc, created, err := m.getOrCreateService(
+		ctx, planner, planner.SmbShare.Namespace)
+	if err != nil {
+		return Result{err: err}
+	}
+	if created {
+		m.logger.Info("Created service")
+		return Requeue
+	}
+	value := serverBackendLabelValue(planner)
+	if svc.Spec.Selector[serverBackendLabel] == value {
+		return Done
+	}
+	if svc.Spec.Selector == nil {
+		svc.Spec.Selector = map[string]string{}
+	}
+	svc.Spec.Selector[serverBackendLabel] = value
+	err = m.client.Update(ctx, svc)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update Service",
+			"Service.Namespace", svc.Namespace,
+			"Service.Name", svc.Name)
+		return Result{err: err}
+	}
+	markConversion(planner.SmbShare, ReasonSwitchingService,
+		fmt.Sprintf("service %s now selects %s servers", svc.Name, value))
+	return Requeue
+}
+
+// moveToSharedConfig points the servers of the new backend at the
+// configuration of the server group, once the servers of the old backend
+// are gone, and waits for the servers to be restarted with it.
+func (m *SmbShareManager) moveToSharedConfig(
+	ctx context.Context,
+	planner *pln.Planner,
+	obj rtclient.Object,
+	tmpl *corev1.PodTemplateSpec,
+	rolledOut bool) Result {
+	// ---
+	id := planner.ContainerID()
+	changed := setContainerID(tmpl.Spec.InitContainers, id)
+	if setContainerID(tmpl.Spec.Containers, id) {
+		changed = true
+	}
+	if changed {
+		err := m.client.Update(ctx, obj)
+		if err != nil {
+			m.logger.Error(
+				err,
+				"Failed to update server configuration",
+				"Namespace", obj.GetNamespace(),
+				"Name", obj.GetName())
+			return Result{err: err}
+		}
+		markConversion(planner.SmbShare, ReasonMovingConfig,
+			"restarting servers of "+obj.GetName()+
+				" with the server group configuration")
+		return Requeue
+	}
+	if !rolledOut {
+		markConversion(planner.SmbShare, ReasonMovingConfig,
+			"waiting for servers of "+obj.GetName()+" to restart")
+		return Requeue
+	}
+	return Done
+}
+
+// setContainerID sets the configuration key the containers are started
+// with. Returns true if any container was changed.
+func setContainerID(ctrs []corev1.Container, id string) bool {
+	changed := false
+	for i := range ctrs {
+		for j := range ctrs[i].Env {
+			env := &ctrs[i].Env[j]
+			if env.Name == "SAMBA_CONTAINER_ID" && env.Value != id {
+				env.Value = id
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (m *SmbShareManager) deleteServerResource(
+	ctx context.Context,
+	obj rtclient.Object) error {
+	// ---
+	m.logger.Info("Deleting resource of previous backend",
+		"Namespace", obj.GetNamespace(),
+		"Name", obj.GetName())
+	err := m.client.Delete(ctx, obj)
+	if err != nil && !errors.IsNotFound(err) {
+		m.logger.Error(
+			err,
+			"Failed to delete resource",
+			"Namespace", obj.GetNamespace(),
+			"Name", obj.GetName())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+func TestCheckSharedPVC(t *testing.T) {
+	ctx := context.Background()
+	m := testingManager()
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare: &sambaoperatorv1alpha1.SmbShare{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "gumby",
+				Namespace: "clayland",
+			},
+			Spec: sambaoperatorv1alpha1.SmbShareSpec{
+				Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+					Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+						Name: "data",
+					},
+				},
+			},
+		},
+	}, nil)
+	modes := []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	m.client.(*fakeClient).clientGet = func(
+		_ context.Context,
+		nn types.NamespacedName,
+		obj rtclient.Object) error {
+		// ---
+		out := obj.(*corev1.PersistentVolumeClaim)
+		out.Name = nn.Name
+		out.Spec.AccessModes = modes
+		return nil
+	}
+	assert.Error(t, m.checkSharedPVC(ctx, planner))
+
+	modes = append(modes, corev1.ReadWriteMany)
+	assert.NoError(t, m.checkSharedPVC(ctx, planner))
+}
+
+func TestSetContainerID(t *testing.T) {
+	ctrs := []corev1.Container{
+		{
+			Name: "samba",
+			Env: []corev1.EnvVar{
+				{Name: "SAMBA_CONTAINER_ID", Value: "gumby-conversion"},
+				{Name: "SAMBACC_CONFIG", Value: "/etc/container-config"},
+			},
+		},
+		{Name: "wb"},
+	}
+	assert.True(t, setContainerID(ctrs, "gumby"))
+	assert.Equal(t, "gumby", ctrs[0].Env[0].Value)
+	assert.Equal(t, "/etc/container-config", ctrs[0].Env[1].Value)
+	assert.False(t, setContainerID(ctrs, "gumby"))
+}
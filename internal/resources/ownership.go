@@ -12,22 +12,46 @@ import (
 
 func smbShareOwnerRefs(obj metav1.Object) ([]metav1.OwnerReference, error) {
 	found := []metav1.OwnerReference{}
-	smbgv := sambaoperatorv1alpha1.GroupVersion
 	refs := obj.GetOwnerReferences()
 	for _, ref := range refs {
-		refgv, err := schema.ParseGroupVersion(ref.APIVersion)
+		isShare, err := isSmbShareRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		if isShare {
+			found = append(found, ref)
+		}
+	}
+	return found, nil
+}
+
+// nonSmbShareOwnerRefs returns the owner references of the object that do
+// not refer to an SmbShare.
+func nonSmbShareOwnerRefs(obj metav1.Object) ([]metav1.OwnerReference, error) {
+	found := []metav1.OwnerReference{}
+	for _, ref := range obj.GetOwnerReferences() {
+		isShare, err := isSmbShareRef(ref)
 		if err != nil {
 			return nil, err
 		}
-		// we intentionally don't check the version as it can change
-		// but the resource would still be "our" SmbShare
-		if refgv.Group == smbgv.Group && ref.Kind == "SmbShare" {
+		if !isShare {
 			found = append(found, ref)
 		}
 	}
 	return found, nil
 }
 
+func isSmbShareRef(ref metav1.OwnerReference) (bool, error) {
+	smbgv := sambaoperatorv1alpha1.GroupVersion
+	refgv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false, err
+	}
+	// we intentionally don't check the version as it can change
+	// but the resource would still be "our" SmbShare
+	return refgv.Group == smbgv.Group && ref.Kind == "SmbShare", nil
+}
+
 func ownerRefsToNames(
 	refs []metav1.OwnerReference, ns string) []types.NamespacedName {
 	// ---
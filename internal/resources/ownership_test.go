@@ -114,6 +114,25 @@ func TestSmbShareOwnerRefs(t *testing.T) {
 	})
 }
 
+func TestNonSmbShareOwnerRefs(t *testing.T) {
+	cm := sampleConfigMap()
+	refs, err := nonSmbShareOwnerRefs(cm)
+	assert.NoError(t, err)
+	assert.Len(t, refs, 0)
+
+	cm.OwnerReferences = append(cm.OwnerReferences, metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       "foobar",
+		UID:        "pretendapplezebra",
+	})
+	refs, err = nonSmbShareOwnerRefs(cm)
+	assert.NoError(t, err)
+	if assert.Len(t, refs, 1) {
+		assert.Equal(t, "Pod", refs[0].Kind)
+	}
+}
+
 func TestOwnerRefsToNames(t *testing.T) {
 	cm := sampleConfigMap()
 	names := ownerRefsToNames(cm.GetOwnerReferences(), cm.GetNamespace())
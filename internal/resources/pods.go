@@ -29,28 +29,29 @@ import (
 func buildPodSpec(
 	planner *pln.Planner,
 	cfg *conf.OperatorConfig,
-	pvcName string) corev1.PodSpec {
+	shareVols []volMount) corev1.PodSpec {
 	// ---
 	if planner.SecurityMode() == pln.ADMode {
-		return buildADPodSpec(planner, cfg, pvcName)
+		return buildADPodSpec(planner, cfg, shareVols)
 	}
-	return buildUserPodSpec(planner, cfg, pvcName)
+	return buildUserPodSpec(planner, cfg, shareVols)
 }
 
 func buildClusteredPodSpec(
 	planner *pln.Planner,
-	dataPVCName, statePVCName string) corev1.PodSpec {
+	shareVols []volMount,
+	statePVCName string) corev1.PodSpec {
 	// ---
 	if planner.SecurityMode() == pln.ADMode {
-		return buildClusteredADPodSpec(planner, dataPVCName, statePVCName)
+		return buildClusteredADPodSpec(planner, shareVols, statePVCName)
 	}
-	return buildClusteredUserPodSpec(planner, dataPVCName, statePVCName)
+	return buildClusteredUserPodSpec(planner, shareVols, statePVCName)
 }
 
 func buildADPodSpec(
 	planner *pln.Planner,
 	_ *conf.OperatorConfig,
-	pvcName string) corev1.PodSpec {
+	shareVols []volMount) corev1.PodSpec {
 	// ---
 	volumes := newVolKeeper()
 	smbInitVols := newVolKeeper()
@@ -69,9 +70,13 @@ func buildADPodSpec(
 	smbServerVols := smbInitVols.clone().add(wbSockVol)
 
 	// for smbd only
-	shareVol := shareVolumeAndMount(planner, pvcName)
-	volumes.add(shareVol)
-	smbdVols := smbServerVols.clone().add(shareVol)
+	volumes.extend(shareVols)
+	smbdVols := smbServerVols.clone().extend(shareVols)
+	if planner.AuditSidecar() {
+		auditVol := auditVolumeAndMount(planner)
+		volumes.add(auditVol)
+		smbdVols.add(auditVol)
+	}
 
 	jsrc := getJoinSources(planner)
 	volumes.extend(jsrc.volumes)
@@ -79,13 +84,7 @@ func buildADPodSpec(
 
 	podEnv := defaultPodEnv(planner)
 	// nolint:gocritic
-	joinEnv := append(
-		podEnv,
-		corev1.EnvVar{
-			Name:  "SAMBACC_JOIN_FILES",
-			Value: joinEnvPaths(jsrc.paths),
-		},
-	)
+	joinEnv := append(podEnv, joinSourceEnv(jsrc)...)
 
 	containers := buildSmbdCtrs(planner, podEnv, smbdVols)
 	containers = append(containers,
@@ -110,7 +109,7 @@ func buildADPodSpec(
 	podSpec.InitContainers = []corev1.Container{
 		buildInitCtr(planner, podEnv, smbInitVols),
 		buildEnsureShareCtr(planner, podEnv, smbdVols),
-		buildMustJoinCtr(planner, joinEnv, joinVols),
+		buildMustJoinCtr(planner, jsrc, joinEnv, joinVols),
 	}
 	podSpec.Containers = containers
 	// we have no logger to log the json syntax error to. have to ignore it for now
@@ -121,13 +120,12 @@ func buildADPodSpec(
 func buildUserPodSpec(
 	planner *pln.Planner,
 	_ *conf.OperatorConfig,
-	pvcName string) corev1.PodSpec {
+	shareVols []volMount) corev1.PodSpec {
 	// ---
 	volumes := newVolKeeper()
 	initContainers := []corev1.Container{}
 
-	shareVol := shareVolumeAndMount(planner, pvcName)
-	volumes.add(shareVol)
+	volumes.extend(shareVols)
 
 	stateVol := sambaStateVolumeAndMount(planner)
 	volumes.add(stateVol)
@@ -146,6 +144,9 @@ func buildUserPodSpec(
 		v := userConfigVolumeAndMount(planner)
 		volumes.add(v)
 	}
+	if planner.AuditSidecar() {
+		volumes.add(auditVolumeAndMount(planner))
+	}
 	podSpec := defaultPodSpec(planner)
 	podSpec.Volumes = getVolumes(volumes.all())
 	podSpec.Containers = buildSmbdCtrs(planner, podEnv, volumes)
@@ -157,7 +158,8 @@ func buildUserPodSpec(
 
 func buildClusteredUserPodSpec(
 	planner *pln.Planner,
-	dataPVCName, statePVCName string) corev1.PodSpec {
+	shareVols []volMount,
+	statePVCName string) corev1.PodSpec {
 	// ---
 	var (
 		volumes        = newVolKeeper()
@@ -166,8 +168,7 @@ func buildClusteredUserPodSpec(
 		containers     []corev1.Container
 	)
 
-	shareVol := shareVolumeAndMount(planner, dataPVCName)
-	volumes.add(shareVol)
+	volumes.extend(shareVols)
 
 	configVol := configVolumeAndMount(planner)
 	volumes.add(configVol)
@@ -215,7 +216,7 @@ func buildClusteredUserPodSpec(
 		buildEnsureShareCtr(
 			planner,
 			podEnv,
-			podCfgVols.clone().add(stateVol).add(shareVol),
+			podCfgVols.clone().add(stateVol).extend(shareVols),
 		))
 
 	ctdbMigrateVols := podCfgVols.clone().
@@ -257,6 +258,9 @@ func buildClusteredUserPodSpec(
 		buildCTDBManageNodesCtr(planner, ctdbEnv, ctdbManageNodesVols))
 
 	// smbd
+	if planner.AuditSidecar() {
+		volumes.add(auditVolumeAndMount(planner))
+	}
 	containers = append(
 		containers,
 		buildSmbdCtrs(planner, podEnv, volumes)...)
@@ -272,7 +276,8 @@ func buildClusteredUserPodSpec(
 
 func buildClusteredADPodSpec(
 	planner *pln.Planner,
-	dataPVCName, statePVCName string) corev1.PodSpec {
+	shareVols []volMount,
+	statePVCName string) corev1.PodSpec {
 	// ---
 	var (
 		volumes        = newVolKeeper()
@@ -281,8 +286,7 @@ func buildClusteredADPodSpec(
 		containers     []corev1.Container
 	)
 
-	shareVol := shareVolumeAndMount(planner, dataPVCName)
-	volumes.add(shareVol)
+	volumes.extend(shareVols)
 
 	configVol := configVolumeAndMount(planner)
 	volumes.add(configVol)
@@ -311,10 +315,7 @@ func buildClusteredADPodSpec(
 	volumes.add(wbSockVol)
 
 	jsrc := getJoinSources(planner)
-	joinEnv := []corev1.EnvVar{{
-		Name:  "SAMBACC_JOIN_FILES",
-		Value: joinEnvPaths(jsrc.paths),
-	}}
+	joinEnv := joinSourceEnv(jsrc)
 	volumes.extend(jsrc.volumes)
 
 	podEnv := defaultPodEnv(planner)
@@ -335,7 +336,7 @@ func buildClusteredADPodSpec(
 		buildEnsureShareCtr(
 			planner,
 			podEnv,
-			podCfgVols.clone().add(stateVol).add(shareVol),
+			podCfgVols.clone().add(stateVol).extend(shareVols),
 		))
 
 	joinVols := podCfgVols.clone().
@@ -344,7 +345,7 @@ func buildClusteredADPodSpec(
 		extend(jsrc.volumes)
 	initContainers = append(
 		initContainers,
-		buildMustJoinCtr(planner, joinEnv, joinVols),
+		buildMustJoinCtr(planner, jsrc, joinEnv, joinVols),
 	)
 
 	ctdbMigrateVols := podCfgVols.clone().
@@ -399,6 +400,9 @@ func buildClusteredADPodSpec(
 		buildWinbinddCtr(planner, podEnv, wbVols))
 
 	// smbd
+	if planner.AuditSidecar() {
+		volumes.add(auditVolumeAndMount(planner))
+	}
 	containers = append(
 		containers,
 		buildSmbdCtrs(planner, podEnv, volumes)...)
@@ -446,6 +450,10 @@ func buildSmbdCtrs(
 		ctrs = append(ctrs, buildSmbdMetricsCtr(
 			planner, metaPodEnv(), metaOnlyVols))
 	}
+	if planner.AuditSidecar() {
+		ctrs = append(ctrs, buildAuditLogCtr(
+			planner, newVolKeeper().add(auditVolumeAndMount(planner))))
+	}
 	return ctrs
 }
 
@@ -460,7 +468,7 @@ func buildSmbdCtr(
 		Image:           planner.GlobalConfig.SmbdContainerImage,
 		ImagePullPolicy: imagePullPolicy(planner),
 		Name:            planner.GlobalConfig.SmbdContainerName,
-		Command:         []string{"samba-container"},
+		Command:         smbdCommand(planner),
 		Args:            planner.Args().Run("smbd"),
 		Env:             env,
 		Ports: []corev1.ContainerPort{{
@@ -486,6 +494,88 @@ func buildSmbdCtr(
 	}
 }
 
+// smbdCommand returns the command of the smbd container. When the audit-log
+// container is present, syslog messages sent to /dev/log by smbd must reach
+// the socket on the volume shared with that container.
+func smbdCommand(planner *pln.Planner) []string {
+	if planner.AuditSidecar() {
+		return planner.Args().SyslogCommand()
+	}
+	return []string{"samba-container"}
+}
+
+// auditLogCtrName is the name of the container writing the audit records
+// of the shares to its output.
+const auditLogCtrName = "audit-log"
+
+// auditLogScript receives syslog messages on the socket given as its
+// argument and writes them to stdout.
+const auditLogScript = `
+import os, socket, sys
+path = sys.argv[1]
+if os.path.exists(path):
+    os.unlink(path)
+sock = socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM)
+sock.bind(path)
+os.chmod(path, 0o666)
+while True:
+    msg = sock.recv(65536).decode(errors="replace").rstrip("\n")
+    print(msg, flush=True)
+`
+
+// buildAuditLogCtr returns a container writing the audit records of the
+// shares, sent through syslog by vfs_full_audit, to its output.
+func buildAuditLogCtr(
+	planner *pln.Planner,
+	vols *volKeeper) corev1.Container {
+	// ---
+	mounts := getMounts(vols.all())
+	return corev1.Container{
+		Image:           planner.GlobalConfig.SmbdContainerImage,
+		ImagePullPolicy: imagePullPolicy(planner),
+		Name:            auditLogCtrName,
+		Command:         []string{"python3", "-c", auditLogScript},
+		Args:            []string{planner.Paths().AuditSocket()},
+		VolumeMounts:    mounts,
+	}
+}
+
+// applyAuditLog adds or removes the audit-log container of the current pod
+// spec, and updates the command of the smbd container, to match the desired
+// pod spec. The server group needs the audit-log container as long as any
+// of its shares sends audit records to it.
+func applyAuditLog(
+	planner *pln.Planner,
+	current, desired *corev1.PodSpec) {
+	// ---
+	smbdName := planner.GlobalConfig.SmbdContainerName
+	var smbd, auditLog *corev1.Container
+	for i := range desired.Containers {
+		switch desired.Containers[i].Name {
+		case smbdName:
+			smbd = &desired.Containers[i]
+		case auditLogCtrName:
+			auditLog = &desired.Containers[i]
+		}
+	}
+	ctrs := make([]corev1.Container, 0, len(current.Containers)+1)
+	for _, ctr := range current.Containers {
+		switch {
+		case ctr.Name == smbdName && smbd != nil:
+			ctr.Command = smbd.Command
+		case ctr.Name == auditLogCtrName && auditLog == nil:
+			continue
+		case ctr.Name == auditLogCtrName:
+			auditLog = nil
+		}
+		ctrs = append(ctrs, ctr)
+	}
+	if auditLog != nil {
+		ctrs = append(ctrs, *auditLog)
+	}
+	current.Containers = ctrs
+}
+
 func buildSmbdMetricsCtr(
 	planner *pln.Planner,
 	env []corev1.EnvVar,
@@ -629,6 +719,7 @@ func buildEnsureShareCtr(
 
 func buildMustJoinCtr(
 	planner *pln.Planner,
+	jsrc joinSources,
 	env []corev1.EnvVar,
 	vols *volKeeper) corev1.Container {
 	// ---
@@ -637,7 +728,7 @@ func buildMustJoinCtr(
 		Image:           planner.GlobalConfig.SmbdContainerImage,
 		ImagePullPolicy: imagePullPolicy(planner),
 		Name:            "must-join",
-		Args:            planner.Args().Initializer("must-join"),
+		Args:            planner.Args().MustJoin(jsrc.keytabs, jsrc.passwords),
 		Env:             env,
 		VolumeMounts:    mounts,
 	}
@@ -740,7 +831,7 @@ func defaultPodEnv(planner *pln.Planner) []corev1.EnvVar {
 	env := []corev1.EnvVar{
 		{
 			Name:  "SAMBA_CONTAINER_ID",
-			Value: planner.InstanceName(),
+			Value: planner.ContainerID(),
 		},
 		{
 			Name:  "SAMBACC_CONFIG",
@@ -817,25 +908,46 @@ func ctdbHostnameEnv(_ *pln.Planner) []corev1.EnvVar {
 }
 
 type joinSources struct {
-	volumes []volMount
-	paths   []string
+	volumes   []volMount
+	paths     []string
+	keytabs   []string
+	passwords []string
 }
 
 func getJoinSources(planner *pln.Planner) joinSources {
 	src := joinSources{
-		volumes: []volMount{},
-		paths:   []string{},
+		volumes:   []volMount{},
+		paths:     []string{},
+		keytabs:   []string{},
+		passwords: []string{},
 	}
 	for i, js := range planner.SecurityConfig.Spec.JoinSources {
-		if js.UserJoin != nil {
-			vm := joinJSONFileVolumeAndMount(planner, i)
-			src.volumes = append(src.volumes, vm)
+		switch {
+		case js.UserJoin != nil:
 			src.paths = append(src.paths, planner.Paths().JoinJSONSource(i))
+		case js.KeytabJoin != nil:
+			src.keytabs = append(src.keytabs,
+				planner.Paths().JoinKeytabSource(i))
+		case js.ComputerAccountJoin != nil:
+			src.passwords = append(src.passwords,
+				planner.Paths().JoinPasswordSource(i))
+		default:
+			continue
 		}
+		src.volumes = append(src.volumes, joinSourceVolumeAndMount(planner, i))
 	}
 	return src
 }
 
+// joinSourceEnv returns the environment variable pointing sambacc at the
+// user join sources.
+func joinSourceEnv(src joinSources) []corev1.EnvVar {
+	return []corev1.EnvVar{{
+		Name:  "SAMBACC_JOIN_FILES",
+		Value: joinEnvPaths(src.paths),
+	}}
+}
+
 func joinEnvPaths(p []string) string {
 	return strings.Join(p, ":")
 }
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+func findContainer(ctrs []corev1.Container, name string) *corev1.Container {
+	for i := range ctrs {
+		if ctrs[i].Name == name {
+			return &ctrs[i]
+		}
+	}
+	return nil
+}
+
+func TestAuditLogSidecar(t *testing.T) {
+	smbshare := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "wilma",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{Name: "quarry"},
+			},
+			Audit: &sambaoperatorv1alpha1.SmbShareAuditSpec{},
+		},
+		Status: sambaoperatorv1alpha1.SmbShareStatus{ServerGroup: "wilma"},
+	}
+	cfg := &conf.OperatorConfig{SmbdContainerName: "samba"}
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare:     smbshare,
+		GlobalConfig: cfg,
+	}, nil)
+
+	// audit records written to stdout need no sidecar
+	podSpec := buildUserPodSpec(planner, cfg, nil)
+	assert.Nil(t, findContainer(podSpec.Containers, "audit-log"))
+	assert.Equal(t,
+		[]string{"samba-container"}, findContainer(podSpec.Containers, "samba").Command)
+
+	smbshare.Spec.Audit.Output = sambaoperatorv1alpha1.AuditOutputSidecar
+	podSpec = buildUserPodSpec(planner, cfg, nil)
+	sidecar := findContainer(podSpec.Containers, "audit-log")
+	if assert.NotNil(t, sidecar) {
+		assert.Equal(t, []string{"/run/samba-audit/log"}, sidecar.Args)
+		if assert.Len(t, sidecar.VolumeMounts, 1) {
+			assert.Equal(t, auditVolName, sidecar.VolumeMounts[0].Name)
+		}
+	}
+	smbd := findContainer(podSpec.Containers, "samba")
+	assert.Nil(t, smbd.Lifecycle)
+	assert.Equal(t, planner.Args().SyslogCommand(), smbd.Command)
+	assert.Contains(t, smbd.Command, "/run/samba-audit/log")
+	assert.Contains(t, smbd.VolumeMounts, auditVolumeAndMount(planner).mount)
+	assert.Contains(t, podSpec.Volumes, auditVolumeAndMount(planner).volume)
+
+	// a share sending records to the sidecar may join the group later
+	smbshare.Spec.Audit.Output = sambaoperatorv1alpha1.AuditOutputStdout
+	current := buildUserPodSpec(planner, cfg, nil)
+	applyAuditLog(planner, &current, &podSpec)
+	assert.NotNil(t, findContainer(current.Containers, "audit-log"))
+	assert.Equal(t, planner.Args().SyslogCommand(),
+		findContainer(current.Containers, "samba").Command)
+
+	// and leave it again
+	desired := buildUserPodSpec(planner, cfg, nil)
+	applyAuditLog(planner, &current, &desired)
+	assert.Nil(t, findContainer(current.Containers, "audit-log"))
+	assert.Equal(t,
+		[]string{"samba-container"}, findContainer(current.Containers, "samba").Command)
+	assert.Len(t, current.Containers, len(desired.Containers))
+}
+
+func TestJoinSources(t *testing.T) {
+	smbshare := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "fred",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{Name: "quarry"},
+			},
+		},
+		Status: sambaoperatorv1alpha1.SmbShareStatus{ServerGroup: "fred"},
+	}
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+			JoinSources: []sambaoperatorv1alpha1.SmbSecurityJoinSpec{
+				{
+					UserJoin: &sambaoperatorv1alpha1.SmbSecurityUserJoinSpec{
+						Secret: "join1",
+						Key:    "join.json",
+					},
+				},
+				{
+					KeytabJoin: &sambaoperatorv1alpha1.SmbSecurityKeytabJoinSpec{
+						Secret: "keytab1",
+						Key:    "krb5.keytab",
+					},
+				},
+				{
+					ComputerAccountJoin: &sambaoperatorv1alpha1.SmbSecurityComputerAccountJoinSpec{
+						Secret: "otp1",
+						Key:    "otp",
+					},
+				},
+			},
+			JoinOptions: &sambaoperatorv1alpha1.SmbSecurityJoinOptionsSpec{
+				OrganizationalUnit:  "Computers/Samba",
+				ComputerAccountName: "FILES1",
+			},
+		},
+	}
+	cfg := &conf.OperatorConfig{SmbdContainerName: "samba"}
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare:       smbshare,
+		SecurityConfig: sc,
+		GlobalConfig:   cfg,
+	}, nil)
+	assert.Equal(t, "FILES1", planner.NetbiosName())
+
+	jsrc := getJoinSources(planner)
+	assert.Len(t, jsrc.volumes, 3)
+	assert.Equal(t, []string{"/var/tmp/join/0/join.json"}, jsrc.paths)
+	assert.Equal(t, []string{"/var/tmp/join/1/krb5.keytab"}, jsrc.keytabs)
+	assert.Equal(t, []string{"/var/tmp/join/2/password"}, jsrc.passwords)
+	otp := jsrc.volumes[2].volume.Secret
+	assert.Equal(t, "otp1", otp.SecretName)
+	assert.Equal(t,
+		[]corev1.KeyToPath{{Key: "otp", Path: "password"}}, otp.Items)
+
+	podSpec := buildADPodSpec(planner, cfg, nil)
+	mustJoin := findContainer(podSpec.InitContainers, "must-join")
+	if assert.NotNil(t, mustJoin) {
+		assert.Equal(t,
+			[]string{
+				"must-join",
+				"--keytab=/var/tmp/join/1/krb5.keytab",
+				"--machine-password-file=/var/tmp/join/2/password",
+				"--ou=Computers/Samba",
+			},
+			mustJoin.Args)
+		assert.Contains(t, mustJoin.Env, corev1.EnvVar{
+			Name:  "SAMBACC_JOIN_FILES",
+			Value: "/var/tmp/join/0/join.json",
+		})
+		assert.Contains(t, mustJoin.VolumeMounts, jsrc.volumes[1].mount)
+	}
+}
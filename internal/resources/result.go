@@ -15,10 +15,13 @@ limitations under the License.
 
 package resources
 
+import "time"
+
 // Result encapsulates the result of the work performed by a resource update.
 type Result struct {
-	err     error
-	requeue bool
+	err          error
+	requeue      bool
+	requeueAfter time.Duration
 }
 
 // Err returns any error associated with the result.
@@ -31,6 +34,13 @@ func (r Result) Requeue() bool {
 	return r.requeue
 }
 
+// RequeueAfter returns the time after which the resource should be
+// processed again, if no other change triggers processing earlier. Zero if
+// no later processing is needed.
+func (r Result) RequeueAfter() time.Duration {
+	return r.requeueAfter
+}
+
 // Yield returns true if current processing should be discontinued.
 func (r Result) Yield() bool {
 	return r.requeue || r.err != nil
@@ -42,3 +52,9 @@ var (
 	// Requeue is a result that needs to be re-queued.
 	Requeue = Result{requeue: true}
 )
+
+// requeueAfter returns a result that is complete for now but must be
+// processed again after the given time.
+func requeueAfter(d time.Duration) Result {
+	return Result{requeueAfter: d}
+}
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+// ctdbNodeWaitInterval is the time after which the remaining nodes of a
+// cluster are checked again for having dropped a removed node.
+const ctdbNodeWaitInterval = 10 * time.Second
+
+// updateClusterSize grows or shrinks the StatefulSet of a clustered
+// instance to match the desired cluster size. Growing the cluster only
+// requires raising the number of replicas, the new pods add themselves to
+// the CTDB nodes. Shrinking the cluster is done one node at a time: the
+// highest numbered node is first removed from the CTDB nodes and only once
+// CTDB has dropped the node is the number of replicas reduced.
+func (m *SmbShareManager) updateClusterSize(
+	ctx context.Context,
+	planner *pln.Planner,
+	statefulSet *appsv1.StatefulSet) Result {
+	// ---
+	size := planner.ClusterSize()
+	if size < 1 {
+		// an unset minimum cluster size leaves the cluster as it is
+		return Done
+	}
+	current := *statefulSet.Spec.Replicas
+	if current < size {
+		resized, err := m.updateStatefulSetSize(ctx, statefulSet, size)
+		if err != nil {
+			return Result{err: err}
+		}
+		if resized {
+			m.logger.Info("Resized statefulSet")
+			return Requeue
+		}
+	}
+	if current > size {
+		return m.shrinkCluster(ctx, planner, statefulSet)
+	}
+	return Done
+}
+
+func (m *SmbShareManager) shrinkCluster(
+	ctx context.Context,
+	planner *pln.Planner,
+	statefulSet *appsv1.StatefulSet) Result {
+	// ---
+	ns := planner.SmbShare.Namespace
+	pnn := *statefulSet.Spec.Replicas - 1
+	job, created, err := m.getOrCreateCTDBRemoveNodeJob(ctx, planner, ns, pnn)
+	if err != nil {
+		return Result{err: err}
+	}
+	if created {
+		m.recorder.Eventf(planner.SmbShare,
+			EventNormal,
+			ReasonRemovingClusterNode,
+			"Removing node %d from cluster %s", pnn, statefulSet.Name)
+		// the share is processed again once the job finishes
+		return Done
+	}
+	finished, cond := jobFinished(job)
+	if !finished {
+		return Done
+	}
+	if cond.Type == batchv1.JobFailed {
+		err = fmt.Errorf("failed to remove node %d from cluster %s: %s",
+			pnn, statefulSet.Name, cond.Message)
+		m.recorder.Event(planner.SmbShare,
+			EventWarning,
+			ReasonRemovingClusterNode,
+			err.Error())
+		return Result{err: err}
+	}
+	dropped, err := m.ctdbNodeDropped(ctx, planner, statefulSet, pnn)
+	if err != nil {
+		return Result{err: err}
+	}
+	if !dropped {
+		m.logger.Info("Waiting for CTDB to drop node",
+			"StatefulSet.Namespace", statefulSet.Namespace,
+			"StatefulSet.Name", statefulSet.Name,
+			"Node", pnn)
+		return Result{requeue: true, requeueAfter: ctdbNodeWaitInterval}
+	}
+
+	statefulSet.Spec.Replicas = &pnn
+	err = m.client.Update(ctx, statefulSet)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update StatefulSet",
+			"StatefulSet.Namespace", statefulSet.Namespace,
+			"StatefulSet.Name", statefulSet.Name)
+		return Result{err: err}
+	}
+	m.recorder.Eventf(planner.SmbShare,
+		EventNormal,
+		ReasonScaledDownCluster,
+		"Scaled down cluster %s to %d nodes", statefulSet.Name, pnn)
+
+	err = m.client.Delete(ctx, job,
+		rtclient.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		m.logger.Error(
+			err,
+			"Failed to delete Job",
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return Result{err: err}
+	}
+	return Requeue
+}
+
+// ctdbNodeDropped returns true if the first node of the cluster, which is
+// never removed, reports the given node as disconnected or deleted.
+func (m *SmbShareManager) ctdbNodeDropped(
+	ctx context.Context,
+	planner *pln.Planner,
+	statefulSet *appsv1.StatefulSet,
+	pnn int32) (bool, error) {
+	// ---
+	pod := statefulSet.Name + "-0"
+	out, err := m.execer.Exec(ctx,
+		statefulSet.Namespace, pod, "ctdb", planner.Args().CTDBStatus())
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to get CTDB status",
+			"Pod.Namespace", statefulSet.Namespace,
+			"Pod.Name", pod)
+		return false, err
+	}
+	return ctdbNodeDisconnected(out, pnn)
+}
+
+// ctdbNodeDisconnected parses the machine readable output of ctdb status
+// and returns true if the node is disconnected or not listed.
+func ctdbNodeDisconnected(out []byte, pnn int32) (bool, error) {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	header := strings.Split(strings.Trim(lines[0], "|"), "|")
+	nodeCol, disconnectedCol := -1, -1
+	for i, h := range header {
+		switch h {
+		case "Node":
+			nodeCol = i
+		case "Disconnected":
+			disconnectedCol = i
+		}
+	}
+	if nodeCol < 0 || disconnectedCol < 0 {
+		return false, fmt.Errorf("unexpected ctdb status output: %q", lines[0])
+	}
+	node := strconv.Itoa(int(pnn))
+	for _, line := range lines[1:] {
+		fields := strings.Split(strings.Trim(line, "|"), "|")
+		if len(fields) != len(header) || fields[nodeCol] != node {
+			continue
+		}
+		return fields[disconnectedCol] == "1", nil
+	}
+	return true, nil
+}
+
+// markClusterSize sets the SizeLimited condition if the requested cluster
+// size exceeds the maximum cluster size. The webhook rejects such shares,
+// but the scale subresource updates the requested size without validation.
+func markClusterSize(s *sambaoperatorv1alpha1.SmbShare) {
+	scaling := s.Spec.Scaling
+	if scaling == nil {
+		return
+	}
+	if scaling.MaxClusterSize > 0 &&
+		scaling.MinClusterSize > scaling.MaxClusterSize {
+		setShareCondition(s, sambaoperatorv1alpha1.ShareConditionSizeLimited,
+			metav1.ConditionTrue, ReasonMaxClusterSize,
+			fmt.Sprintf("requested cluster size %d is limited to"+
+				" maxClusterSize %d",
+				scaling.MinClusterSize, scaling.MaxClusterSize))
+		return
+	}
+	setShareCondition(s, sambaoperatorv1alpha1.ShareConditionSizeLimited,
+		metav1.ConditionFalse, ReasonWithinLimits,
+		"requested cluster size is within limits")
+}
+
+// setScaleStatus records the number of server instances and the selector
+// matching their pods for use by the scale subresource.
+func setScaleStatus(planner *pln.Planner, replicas int32) {
+	planner.SmbShare.Status.Replicas = replicas
+	planner.SmbShare.Status.Selector = metav1.FormatLabelSelector(
+		&metav1.LabelSelector{MatchLabels: labelsForSmbServer(planner)})
+}
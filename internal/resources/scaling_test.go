@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+func TestSetScaleStatus(t *testing.T) {
+	planner := pln.New(
+		pln.InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "barney",
+					Namespace: "bedrock",
+				},
+				Status: sambaoperatorv1alpha1.SmbShareStatus{
+					ServerGroup: "barney",
+				},
+			},
+			GlobalConfig: &conf.OperatorConfig{},
+		},
+		&smbcc.SambaContainerConfig{})
+
+	setScaleStatus(planner, 3)
+	assert.Equal(t, int32(3), planner.SmbShare.Status.Replicas)
+
+	sel, err := labels.Parse(planner.SmbShare.Status.Selector)
+	assert.NoError(t, err)
+	assert.True(t, sel.Matches(labels.Set(podLabelsForSmbServer(planner))))
+	assert.False(t, sel.Matches(labels.Set(labelsForJob(planner, "x"))))
+}
+
+func TestShrinkCluster(t *testing.T) {
+	ctx := context.Background()
+	m := testingManager()
+	m.recorder = record.NewFakeRecorder(10)
+	planner := pln.New(
+		pln.InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "barney",
+					Namespace: "bedrock",
+				},
+				Status: sambaoperatorv1alpha1.SmbShareStatus{
+					ServerGroup: "barney",
+				},
+			},
+			GlobalConfig: &conf.OperatorConfig{},
+		},
+		&smbcc.SambaContainerConfig{})
+	newStatefulSet := func() *appsv1.StatefulSet {
+		replicas := int32(3)
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "barney",
+				Namespace: "bedrock",
+			},
+			Spec: appsv1.StatefulSetSpec{Replicas: &replicas},
+		}
+	}
+	var jobConditions []batchv1.JobCondition
+	ctdbStatus := "|Node|IP|Disconnected|Unknown|Banned|Disabled|Unhealthy|" +
+		"Stopped|Inactive|PartiallyOnline|ThisNode|\n" +
+		"|0|10.1.1.1|0|0|0|0|0|0|0|0|Y|\n" +
+		"|1|10.1.1.2|0|0|0|0|0|0|0|0|N|\n" +
+		"|2|10.1.1.3|0|0|0|0|0|0|0|0|N|\n"
+	m.execer = &fakeExecer{
+		exec: func(pod, container string, _ []string) ([]byte, error) {
+			if pod != "barney-0" || container != "ctdb" {
+				return nil, fmt.Errorf("unexpected pod: %s/%s", pod, container)
+			}
+			return []byte(ctdbStatus), nil
+		},
+	}
+	m.client.(*fakeClient).clientGet = func(
+		_ context.Context,
+		nn types.NamespacedName,
+		obj rtclient.Object) error {
+		// ---
+		if job, ok := obj.(*batchv1.Job); ok && nn.Name == "barney-rmnode-2" {
+			job.Name = nn.Name
+			job.Namespace = nn.Namespace
+			job.Status.Conditions = jobConditions
+			return nil
+		}
+		return fmt.Errorf("unexpected name: %s/%s", nn.Namespace, nn.Name)
+	}
+
+	t.Run("jobRunning", func(t *testing.T) {
+		statefulSet := newStatefulSet()
+		result := m.shrinkCluster(ctx, planner, statefulSet)
+		assert.NoError(t, result.err)
+		// the job watch triggers processing once the job finishes
+		assert.False(t, result.Yield())
+		assert.Equal(t, int32(3), *statefulSet.Spec.Replicas)
+	})
+
+	jobConditions = []batchv1.JobCondition{{
+		Type:               batchv1.JobComplete,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+	}}
+
+	t.Run("nodeConnected", func(t *testing.T) {
+		statefulSet := newStatefulSet()
+		result := m.shrinkCluster(ctx, planner, statefulSet)
+		assert.NoError(t, result.err)
+		assert.True(t, result.requeue)
+		assert.Equal(t, ctdbNodeWaitInterval, result.requeueAfter)
+		assert.Equal(t, int32(3), *statefulSet.Spec.Replicas)
+	})
+
+	t.Run("nodeDropped", func(t *testing.T) {
+		ctdbStatus = strings.Replace(ctdbStatus,
+			"|2|10.1.1.3|0|", "|2|10.1.1.3|1|", 1)
+		statefulSet := newStatefulSet()
+		result := m.shrinkCluster(ctx, planner, statefulSet)
+		assert.NoError(t, result.err)
+		assert.True(t, result.requeue)
+		assert.Equal(t, int32(2), *statefulSet.Spec.Replicas)
+	})
+
+	t.Run("jobFailed", func(t *testing.T) {
+		jobConditions = []batchv1.JobCondition{{
+			Type:    batchv1.JobFailed,
+			Status:  corev1.ConditionTrue,
+			Message: "BackoffLimitExceeded",
+		}}
+		statefulSet := newStatefulSet()
+		result := m.shrinkCluster(ctx, planner, statefulSet)
+		assert.Error(t, result.err)
+		assert.Equal(t, int32(3), *statefulSet.Spec.Replicas)
+	})
+}
+
+func TestCTDBNodeDisconnected(t *testing.T) {
+	header := "|Node|IP|Disconnected|Unknown|Banned|Disabled|Unhealthy|" +
+		"Stopped|Inactive|PartiallyOnline|ThisNode|\n"
+	out := header +
+		"|0|10.1.1.1|0|0|0|0|0|0|0|0|Y|\n" +
+		"|1|10.1.1.2|1|0|0|0|0|0|0|0|N|\n"
+	dropped, err := ctdbNodeDisconnected([]byte(out), 0)
+	assert.NoError(t, err)
+	assert.False(t, dropped)
+	dropped, err = ctdbNodeDisconnected([]byte(out), 1)
+	assert.NoError(t, err)
+	assert.True(t, dropped)
+	// deleted nodes are not listed
+	dropped, err = ctdbNodeDisconnected([]byte(out), 2)
+	assert.NoError(t, err)
+	assert.True(t, dropped)
+
+	_, err = ctdbNodeDisconnected([]byte("connection refused\n"), 1)
+	assert.Error(t, err)
+}
+
+func TestMarkClusterSize(t *testing.T) {
+	s := &sambaoperatorv1alpha1.SmbShare{
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Scaling: &sambaoperatorv1alpha1.SmbShareScalingSpec{
+				AvailabilityMode: "clustered",
+				MinClusterSize:   5,
+				MaxClusterSize:   3,
+			},
+		},
+	}
+	markClusterSize(s)
+	c := meta.FindStatusCondition(
+		s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionSizeLimited)
+	if assert.NotNil(t, c) {
+		assert.Equal(t, metav1.ConditionTrue, c.Status)
+		assert.Equal(t, ReasonMaxClusterSize, c.Reason)
+	}
+
+	s.Spec.Scaling.MinClusterSize = 3
+	markClusterSize(s)
+	assert.True(t, meta.IsStatusConditionFalse(
+		s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionSizeLimited))
+}
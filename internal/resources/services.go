@@ -16,10 +16,13 @@ limitations under the License.
 package resources
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
 	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
 )
 
@@ -64,3 +67,38 @@ func toServiceType(s string) corev1.ServiceType {
 	}
 	return svcType
 }
+
+// endpointForService returns the endpoint status clients can use to reach
+// the share via the given Service.
+func endpointForService(
+	planner *pln.Planner,
+	svc *corev1.Service) *sambaoperatorv1alpha1.SmbShareEndpointStatus {
+	// ---
+	ep := &sambaoperatorv1alpha1.SmbShareEndpointStatus{
+		ServiceName: svc.Name,
+		ClusterDNSName: fmt.Sprintf("%s.%s.svc.%s",
+			svc.Name, svc.Namespace, planner.GlobalConfig.ClusterDomain),
+		ClusterIP: svc.Spec.ClusterIP,
+	}
+	if len(svc.Spec.Ports) > 0 {
+		ep.Port = svc.Spec.Ports[0].Port
+	}
+	host := ep.ClusterDNSName
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP == "" && ingress.Hostname == "" {
+				continue
+			}
+			ep.ExternalIP = ingress.IP
+			ep.ExternalHostname = ingress.Hostname
+			break
+		}
+		if ep.ExternalHostname != "" {
+			host = ep.ExternalHostname
+		} else if ep.ExternalIP != "" {
+			host = ep.ExternalIP
+		}
+	}
+	ep.UNC = planner.UNCPath(host)
+	return ep
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+func TestEndpointForService(t *testing.T) {
+	cfg := conf.DefaultOperatorConfig
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare: &sambaoperatorv1alpha1.SmbShare{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "stuff",
+				Namespace: "bulk",
+			},
+			Spec: sambaoperatorv1alpha1.SmbShareSpec{
+				ShareName: "Stuff",
+			},
+			Status: sambaoperatorv1alpha1.SmbShareStatus{
+				ServerGroup: "stuff",
+			},
+		},
+		GlobalConfig: &cfg,
+	}, nil)
+	svc := newServiceForSmb(planner, "bulk")
+	svc.Spec.ClusterIP = "10.96.1.10"
+
+	t.Run("clusterIP", func(t *testing.T) {
+		ep := endpointForService(planner, svc)
+		assert.Equal(t, "stuff", ep.ServiceName)
+		assert.Equal(t, "stuff.bulk.svc.cluster.local", ep.ClusterDNSName)
+		assert.Equal(t, "10.96.1.10", ep.ClusterIP)
+		assert.Equal(t, int32(445), ep.Port)
+		assert.Equal(t, "", ep.ExternalIP)
+		assert.Equal(t, `\\stuff.bulk.svc.cluster.local\Stuff`, ep.UNC)
+	})
+
+	t.Run("loadBalancerPending", func(t *testing.T) {
+		svc2 := svc.DeepCopy()
+		svc2.Spec.Type = corev1.ServiceTypeLoadBalancer
+		ep := endpointForService(planner, svc2)
+		assert.Equal(t, "", ep.ExternalIP)
+		assert.Equal(t, `\\stuff.bulk.svc.cluster.local\Stuff`, ep.UNC)
+	})
+
+	t.Run("loadBalancerIP", func(t *testing.T) {
+		svc2 := svc.DeepCopy()
+		svc2.Spec.Type = corev1.ServiceTypeLoadBalancer
+		svc2.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{
+			IP: "192.168.76.20",
+		}}
+		ep := endpointForService(planner, svc2)
+		assert.Equal(t, "192.168.76.20", ep.ExternalIP)
+		assert.Equal(t, `\\192.168.76.20\Stuff`, ep.UNC)
+	})
+
+	t.Run("loadBalancerHostname", func(t *testing.T) {
+		svc2 := svc.DeepCopy()
+		svc2.Spec.Type = corev1.ServiceTypeLoadBalancer
+		svc2.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{
+			Hostname: "lb.example.com",
+		}}
+		ep := endpointForService(planner, svc2)
+		assert.Equal(t, "lb.example.com", ep.ExternalHostname)
+		assert.Equal(t, `\\lb.example.com\Stuff`, ep.UNC)
+	})
+}
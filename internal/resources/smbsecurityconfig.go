@@ -0,0 +1,629 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+// invalidConfigRetryInterval is the time after which an invalid security
+// config is validated again. The secrets it refers to are not watched, so
+// fixing a secret is only noticed on the next validation.
+const invalidConfigRetryInterval = time.Minute
+
+// workgroupRetryInterval is the time after which a failed lookup of the
+// workgroup is retried.
+const workgroupRetryInterval = 5 * time.Minute
+
+// SmbSecurityConfigManager is used to manage SmbSecurityConfig resources.
+type SmbSecurityConfigManager struct {
+	client   rtclient.Client
+	reader   rtclient.Reader
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	logger   Logger
+	cfg      *conf.OperatorConfig
+}
+
+// NewSmbSecurityConfigManager creates a SmbSecurityConfigManager. Secrets
+// are read with the given reader, so that they need not be cached.
+func NewSmbSecurityConfigManager(
+	client rtclient.Client,
+	reader rtclient.Reader,
+	scheme *runtime.Scheme,
+	recorder record.EventRecorder,
+	logger Logger) *SmbSecurityConfigManager {
+	// ---
+	return &SmbSecurityConfigManager{
+		client:   client,
+		reader:   reader,
+		scheme:   scheme,
+		recorder: recorder,
+		logger:   logger,
+		cfg:      conf.Get(),
+	}
+}
+
+// Process is called by the controller on any type of reconciliation.
+func (m *SmbSecurityConfigManager) Process(
+	ctx context.Context,
+	nsname types.NamespacedName) Result {
+	// ---
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{}
+	err := m.client.Get(ctx, nsname, sc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return Done
+		}
+		m.logger.Error(
+			err,
+			"Failed to get SmbSecurityConfig",
+			"SmbSecurityConfig.Namespace", nsname.Namespace,
+			"SmbSecurityConfig.Name", nsname.Name)
+		return Result{err: err}
+	}
+	if sc.GetDeletionTimestamp() != nil {
+		// jobs are garbage collected along with the config
+		return Done
+	}
+	result := m.updateStatus(ctx, sc)
+	if result.Yield() {
+		return result
+	}
+	if res := m.discoverWorkgroup(ctx, sc); res.Yield() {
+		return res
+	}
+	return result
+}
+
+// updateStatus validates the security config and the secrets it refers to
+// and records the outcome, along with the shares using the config, in the
+// status of the security config.
+func (m *SmbSecurityConfigManager) updateStatus(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) Result {
+	// ---
+	previous := sc.Status.DeepCopy()
+	shares, err := m.referencingShares(ctx, sc)
+	if err != nil {
+		return Result{err: err}
+	}
+	sc.Status.Shares = shareNames(shares)
+
+	result := Done
+	err = recordIDMapRanges(sc, len(shares) > 0)
+	if err == nil {
+		err = m.validate(ctx, sc, shares)
+	}
+	if invalid, ok := err.(*invalidConfigError); ok {
+		if !meta.IsStatusConditionTrue(previous.Conditions,
+			sambaoperatorv1alpha1.SecurityConfigConditionInvalid) {
+			m.recorder.Event(sc,
+				EventWarning,
+				ReasonInvalidConfiguration,
+				invalid.Error())
+		}
+		setSecurityConfigCondition(sc,
+			sambaoperatorv1alpha1.SecurityConfigConditionReady,
+			metav1.ConditionFalse, invalid.reason, invalid.Error())
+		setSecurityConfigCondition(sc,
+			sambaoperatorv1alpha1.SecurityConfigConditionInvalid,
+			metav1.ConditionTrue, invalid.reason, invalid.Error())
+		result = requeueAfter(invalidConfigRetryInterval)
+	} else if err != nil {
+		return Result{err: err}
+	} else {
+		setSecurityConfigCondition(sc,
+			sambaoperatorv1alpha1.SecurityConfigConditionReady,
+			metav1.ConditionTrue, ReasonValid, "security config is valid")
+		setSecurityConfigCondition(sc,
+			sambaoperatorv1alpha1.SecurityConfigConditionInvalid,
+			metav1.ConditionFalse, ReasonValid, "")
+	}
+
+	if equality.Semantic.DeepEqual(previous, &sc.Status) {
+		return result
+	}
+	if err := m.client.Status().Update(ctx, sc); err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update SmbSecurityConfig status",
+			"SmbSecurityConfig.Namespace", sc.Namespace,
+			"SmbSecurityConfig.Name", sc.Name)
+		return Result{err: err}
+	}
+	return result
+}
+
+// referencingShares returns the shares using the security config.
+func (m *SmbSecurityConfigManager) referencingShares(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) (
+	[]sambaoperatorv1alpha1.SmbShare, error) {
+	// ---
+	shares := &sambaoperatorv1alpha1.SmbShareList{}
+	err := m.client.List(ctx, shares, rtclient.InNamespace(sc.Namespace))
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to list SmbShares",
+			"SmbSecurityConfig.Namespace", sc.Namespace,
+			"SmbSecurityConfig.Name", sc.Name)
+		return nil, err
+	}
+	var found []sambaoperatorv1alpha1.SmbShare
+	for _, s := range shares.Items {
+		if s.Spec.SecurityConfig == sc.Name {
+			found = append(found, s)
+		}
+	}
+	return found, nil
+}
+
+// shareNames returns the sorted names of the shares.
+func shareNames(shares []sambaoperatorv1alpha1.SmbShare) []string {
+	var names []string
+	for _, s := range shares {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recordIDMapRanges records the ID ranges of the domains in the status of
+// the security config, which the shares using the config map IDs with. A
+// recorded range is only replaced by a different range set in the spec if
+// no share uses the config or ForceIDMapChangeAnnotation is set to "true",
+// as changing the range changes the owners of the files of the shares.
+func recordIDMapRanges(
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig, inUse bool) error {
+	// ---
+	if pln.ValidateIDMapDomains(sc.Spec.Domains) != nil {
+		// reported by validate
+		return nil
+	}
+	recorded := sc.Status.IDMapRanges
+	changed := pln.ChangedIDMapDomains(sc.Spec.Domains, recorded)
+	var err error
+	force := sc.Annotations[sambaoperatorv1alpha1.ForceIDMapChangeAnnotation]
+	if len(changed) > 0 && inUse && force != "true" {
+		err = invalidConfig(ReasonIDMapRangeInUse,
+			"the idmap ranges of domains %v may not be changed while in"+
+				" use by SmbShares, unless annotation %s is set to \"true\"",
+			changed, sambaoperatorv1alpha1.ForceIDMapChangeAnnotation)
+	} else if len(changed) > 0 {
+		drop := map[string]bool{}
+		for _, name := range changed {
+			drop[name] = true
+		}
+		kept := []sambaoperatorv1alpha1.SmbSecurityIDMapRange{}
+		for _, r := range recorded {
+			if !drop[r.Name] {
+				kept = append(kept, r)
+			}
+		}
+		recorded = kept
+	}
+	if verr := pln.ValidateIDMapRanges(sc.Spec.Domains, recorded); verr != nil {
+		if err == nil {
+			err = invalidConfig(ReasonInvalidDomains, "%s", verr.Error())
+		}
+		return err
+	}
+	sc.Status.IDMapRanges = pln.RecordIDMapRanges(
+		pln.IDMapRanges(sc.Spec.Domains, recorded))
+	return err
+}
+
+// invalidConfigError is returned by validate if the security config or one
+// of the secrets it refers to is missing or invalid. The reason is used
+// for the conditions of the security config.
+type invalidConfigError struct {
+	reason string
+	msg    string
+}
+
+func (e *invalidConfigError) Error() string {
+	return e.msg
+}
+
+func invalidConfig(reason, format string, a ...interface{}) error {
+	return &invalidConfigError{reason: reason, msg: fmt.Sprintf(format, a...)}
+}
+
+// validate returns an invalidConfigError if the security config can not be
+// used by the given shares, or any other error if the validation itself
+// failed.
+func (m *SmbSecurityConfigManager) validate(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig,
+	shares []sambaoperatorv1alpha1.SmbShare) error {
+	// ---
+	switch pln.SecurityMode(sc.Spec.Mode) {
+	case pln.UserMode:
+		if users := sc.Spec.Users; users != nil {
+			data, err := m.secretData(ctx, sc.Namespace, users.Secret, users.Key)
+			if err != nil {
+				return err
+			}
+			if _, err := smbcc.ParseUsers(data); err != nil {
+				return invalidConfig(ReasonInvalidSecret,
+					"invalid users in key %s of secret %s: %s",
+					users.Key, users.Secret, err.Error())
+			}
+		}
+	case pln.ADMode:
+		for i, js := range sc.Spec.JoinSources {
+			if err := m.validateJoinSource(ctx, sc.Namespace, js); err != nil {
+				if invalid, ok := err.(*invalidConfigError); ok {
+					invalid.msg = fmt.Sprintf(
+						"join source %d: %s", i, invalid.msg)
+				}
+				return err
+			}
+		}
+		if err := pln.ValidateComputerAccount(sc, shares); err != nil {
+			return invalidConfig(ReasonInvalidJoinOptions, "%s", err.Error())
+		}
+	}
+	if err := pln.ValidateIDMapDomains(sc.Spec.Domains); err != nil {
+		return invalidConfig(ReasonInvalidDomains, "%s", err.Error())
+	}
+	return nil
+}
+
+func (m *SmbSecurityConfigManager) validateJoinSource(
+	ctx context.Context,
+	ns string,
+	js sambaoperatorv1alpha1.SmbSecurityJoinSpec) error {
+	// ---
+	switch {
+	case js.UserJoin != nil:
+		src := js.UserJoin
+		data, err := m.secretData(ctx, ns, src.Secret, src.Key)
+		if err != nil {
+			return err
+		}
+		creds := struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}{}
+		err = json.Unmarshal(data, &creds)
+		if err != nil || creds.Username == "" || creds.Password == "" {
+			return invalidConfig(ReasonInvalidSecret,
+				"key %s of secret %s does not hold a username and password",
+				src.Key, src.Secret)
+		}
+	case js.KeytabJoin != nil:
+		src := js.KeytabJoin
+		data, err := m.secretData(ctx, ns, src.Secret, src.Key)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return invalidConfig(ReasonInvalidSecret,
+				"key %s of secret %s holds an empty keytab",
+				src.Key, src.Secret)
+		}
+	case js.ComputerAccountJoin != nil:
+		src := js.ComputerAccountJoin
+		data, err := m.secretData(ctx, ns, src.Secret, src.Key)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return invalidConfig(ReasonInvalidSecret,
+				"key %s of secret %s holds an empty password",
+				src.Key, src.Secret)
+		}
+	}
+	return nil
+}
+
+// secretData returns the value of the key of the named secret.
+func (m *SmbSecurityConfigManager) secretData(
+	ctx context.Context,
+	ns, name, key string) ([]byte, error) {
+	// ---
+	secret := &corev1.Secret{}
+	err := m.reader.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, secret)
+	if errors.IsNotFound(err) {
+		return nil, invalidConfig(ReasonSecretNotFound,
+			"secret %s not found", name)
+	} else if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to get Secret",
+			"Secret.Namespace", ns,
+			"Secret.Name", name)
+		return nil, err
+	}
+	data, found := secret.Data[key]
+	if !found {
+		return nil, invalidConfig(ReasonInvalidSecret,
+			"key %s not found in secret %s", key, name)
+	}
+	return data, nil
+}
+
+// needsWorkgroup returns true if the NetBIOS name of the domain is neither
+// specified nor discovered yet.
+func needsWorkgroup(sc *sambaoperatorv1alpha1.SmbSecurityConfig) bool {
+	return pln.SecurityMode(sc.Spec.Mode) == pln.ADMode &&
+		sc.Spec.Realm != "" &&
+		sc.Spec.Workgroup == "" &&
+		sc.Status.Workgroup == ""
+}
+
+// discoverWorkgroup looks up the NetBIOS name of the domain with a job
+// and records it in the status of the security config. The progress of the
+// lookup is reported by the WorkgroupDiscovered condition. A failed job is
+// kept until the lookup is retried, after workgroupRetryInterval.
+func (m *SmbSecurityConfigManager) discoverWorkgroup(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) Result {
+	// ---
+	if !needsWorkgroup(sc) {
+		if sc.Spec.Workgroup != "" {
+			// the workgroup is not looked up when it is specified
+			if err := m.removeWorkgroupCondition(ctx, sc); err != nil {
+				return Result{err: err}
+			}
+		}
+		// a job is left behind if the workgroup was specified during a
+		// lookup or the job could not be deleted after the lookup
+		job, err := m.getWorkgroupJob(ctx, sc)
+		if err != nil || job == nil {
+			return Result{err: err}
+		}
+		return m.deleteWorkgroupJob(ctx, job)
+	}
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare:       &sambaoperatorv1alpha1.SmbShare{},
+		SecurityConfig: sc,
+		GlobalConfig:   m.cfg,
+	}, nil)
+	job, created, err := m.getOrCreateWorkgroupJob(ctx, planner)
+	if err != nil {
+		return Result{err: err}
+	}
+	if created {
+		m.recorder.Eventf(sc,
+			EventNormal,
+			ReasonDiscoveringWorkgroup,
+			"Looking up the workgroup of realm %s", planner.Realm())
+		err := m.setWorkgroupCondition(ctx, sc,
+			metav1.ConditionFalse, ReasonDiscoveringWorkgroup,
+			fmt.Sprintf("looking up the workgroup of realm %s", planner.Realm()))
+		return Result{err: err}
+	}
+	finished, cond := jobFinished(job)
+	if !finished {
+		return Done
+	}
+	if cond.Type == batchv1.JobFailed {
+		return m.retryWorkgroup(ctx, sc, planner, job, cond)
+	}
+	wg, err := m.jobTerminationMessage(ctx, job)
+	if err != nil {
+		return Result{err: err}
+	}
+	sc.Status.Workgroup = wg
+	err = m.setWorkgroupCondition(ctx, sc,
+		metav1.ConditionTrue, ReasonDiscoveredWorkgroup,
+		fmt.Sprintf("discovered workgroup %s", wg))
+	if err != nil {
+		return Result{err: err}
+	}
+	m.recorder.Eventf(sc,
+		EventNormal,
+		ReasonDiscoveredWorkgroup,
+		"Discovered workgroup %s of realm %s", wg, planner.Realm())
+	return m.deleteWorkgroupJob(ctx, job)
+}
+
+// retryWorkgroup reports the failed lookup of the workgroup and removes the
+// failed job once the lookup is to be retried, so that a new job is created.
+func (m *SmbSecurityConfigManager) retryWorkgroup(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig,
+	planner *pln.Planner,
+	job *batchv1.Job,
+	cond *batchv1.JobCondition) Result {
+	// ---
+	c := meta.FindStatusCondition(sc.Status.Conditions,
+		sambaoperatorv1alpha1.SecurityConfigConditionWorkgroupDiscovered)
+	if c == nil || c.Reason != ReasonWorkgroupDiscoveryFailed {
+		m.logger.Info("Failed to look up workgroup",
+			"Job.Name", job.Name,
+			"Message", cond.Message)
+		m.recorder.Eventf(sc,
+			EventWarning,
+			ReasonWorkgroupDiscoveryFailed,
+			"Failed to look up the workgroup of realm %s: %s",
+			planner.Realm(), cond.Message)
+		err := m.setWorkgroupCondition(ctx, sc,
+			metav1.ConditionFalse, ReasonWorkgroupDiscoveryFailed,
+			fmt.Sprintf("failed to look up the workgroup of realm %s,"+
+				" set the workgroup of the security config to skip the"+
+				" lookup: %s", planner.Realm(), cond.Message))
+		if err != nil {
+			return Result{err: err}
+		}
+	}
+	wait := time.Until(cond.LastTransitionTime.Add(workgroupRetryInterval))
+	if wait > 0 {
+		return requeueAfter(wait)
+	}
+	// the deletion of the job triggers the next lookup
+	return m.deleteWorkgroupJob(ctx, job)
+}
+
+// setWorkgroupCondition sets the WorkgroupDiscovered condition and updates
+// the status of the security config if it changed.
+func (m *SmbSecurityConfigManager) setWorkgroupCondition(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig,
+	status metav1.ConditionStatus,
+	reason, message string) error {
+	// ---
+	previous := sc.Status.DeepCopy()
+	setSecurityConfigCondition(sc,
+		sambaoperatorv1alpha1.SecurityConfigConditionWorkgroupDiscovered,
+		status, reason, message)
+	return m.updateWorkgroupStatus(ctx, sc, previous)
+}
+
+func (m *SmbSecurityConfigManager) removeWorkgroupCondition(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) error {
+	// ---
+	previous := sc.Status.DeepCopy()
+	meta.RemoveStatusCondition(&sc.Status.Conditions,
+		sambaoperatorv1alpha1.SecurityConfigConditionWorkgroupDiscovered)
+	return m.updateWorkgroupStatus(ctx, sc, previous)
+}
+
+func (m *SmbSecurityConfigManager) updateWorkgroupStatus(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig,
+	previous *sambaoperatorv1alpha1.SmbSecurityConfigStatus) error {
+	// ---
+	if equality.Semantic.DeepEqual(previous, &sc.Status) {
+		return nil
+	}
+	err := m.client.Status().Update(ctx, sc)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update SmbSecurityConfig status",
+			"SmbSecurityConfig.Namespace", sc.Namespace,
+			"SmbSecurityConfig.Name", sc.Name)
+	}
+	return err
+}
+
+// getWorkgroupJob returns the job looking up the workgroup of the security
+// config, or nil if there is none.
+func (m *SmbSecurityConfigManager) getWorkgroupJob(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) (*batchv1.Job, error) {
+	// ---
+	found := &batchv1.Job{}
+	jobKey := types.NamespacedName{
+		Name:      workgroupJobName(sc),
+		Namespace: sc.Namespace,
+	}
+	err := m.client.Get(ctx, jobKey, found)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to get Job",
+			"Job.Namespace", jobKey.Namespace,
+			"Job.Name", jobKey.Name)
+		return nil, err
+	}
+	return found, nil
+}
+
+func (m *SmbSecurityConfigManager) getOrCreateWorkgroupJob(
+	ctx context.Context,
+	planner *pln.Planner) (*batchv1.Job, bool, error) {
+	// ---
+	sc := planner.SecurityConfig
+	found, err := m.getWorkgroupJob(ctx, sc)
+	if err != nil || found != nil {
+		return found, false, err
+	}
+
+	job := buildWorkgroupJob(planner, sc.Namespace)
+	err = controllerutil.SetControllerReference(sc, job, m.scheme)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to set controller reference",
+			"SmbSecurityConfig.Namespace", sc.Namespace,
+			"SmbSecurityConfig.Name", sc.Name,
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return nil, false, err
+	}
+	err = m.client.Create(ctx, job)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to create new Job",
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+func (m *SmbSecurityConfigManager) deleteWorkgroupJob(
+	ctx context.Context,
+	job *batchv1.Job) Result {
+	// ---
+	err := m.client.Delete(ctx, job,
+		rtclient.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		m.logger.Error(
+			err,
+			"Failed to delete Job",
+			"Job.Namespace", job.Namespace,
+			"Job.Name", job.Name)
+		return Result{err: err}
+	}
+	return Done
+}
+
+// jobTerminationMessage returns the termination message of the container
+// of the job's successfully completed pod.
+func (m *SmbSecurityConfigManager) jobTerminationMessage(
+	ctx context.Context,
+	job *batchv1.Job) (string, error) {
+	// ---
+	pods := &corev1.PodList{}
+	err := m.client.List(ctx, pods,
+		rtclient.InNamespace(job.Namespace),
+		rtclient.MatchingLabels{"job-name": job.Name})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if t := cs.State.Terminated; t != nil && t.Message != "" {
+				return strings.TrimSpace(t.Message), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no result found for job %s", job.Name)
+}
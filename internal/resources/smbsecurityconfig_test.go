@@ -0,0 +1,270 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+)
+
+func TestValidateSmbSecurityConfig(t *testing.T) {
+	ctx := context.Background()
+	secrets := map[string]map[string][]byte{
+		"users1": {
+			"demousers": []byte(`{"samba-container-config": "v0",
+				"users": {"all_entries": [{"name": "fred", "password": "x"}]}}`),
+			"broken": []byte(`{"users": []}`),
+		},
+		"join1": {
+			"join.json": []byte(`{"username": "Administrator", "password": "x"}`),
+			"nopass":    []byte(`{"username": "Administrator"}`),
+		},
+		"keytab1": {"krb5.keytab": []byte{0x05, 0x02}},
+		"otp1":    {"password": []byte("x"), "blank": []byte(" \n")},
+	}
+	client := &fakeClient{
+		clientGet: func(
+			_ context.Context,
+			nn types.NamespacedName,
+			obj rtclient.Object) error {
+			// ---
+			data, found := secrets[nn.Name]
+			if !found {
+				return errors.NewNotFound(
+					schema.GroupResource{Resource: "secrets"}, nn.Name)
+			}
+			obj.(*corev1.Secret).Data = data
+			return nil
+		},
+	}
+	m := &SmbSecurityConfigManager{
+		client: client,
+		reader: client,
+		logger: &fakeLogger{},
+	}
+	reason := func(err error) string {
+		if invalid, ok := err.(*invalidConfigError); ok {
+			return invalid.reason
+		}
+		return ""
+	}
+
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "users",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode: "user",
+			Users: &sambaoperatorv1alpha1.SmbSecurityUsersSpec{
+				Secret: "users1",
+				Key:    "demousers",
+			},
+		},
+	}
+	assert.NoError(t, m.validate(ctx, sc, nil))
+	sc.Spec.Users.Key = "broken"
+	assert.Equal(t, ReasonInvalidSecret, reason(m.validate(ctx, sc, nil)))
+	sc.Spec.Users.Key = "missing"
+	assert.Equal(t, ReasonInvalidSecret, reason(m.validate(ctx, sc, nil)))
+	sc.Spec.Users.Secret = "usres1"
+	assert.Equal(t, ReasonSecretNotFound, reason(m.validate(ctx, sc, nil)))
+
+	sc = &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+			JoinSources: []sambaoperatorv1alpha1.SmbSecurityJoinSpec{
+				{
+					UserJoin: &sambaoperatorv1alpha1.SmbSecurityUserJoinSpec{
+						Secret: "join1",
+						Key:    "join.json",
+					},
+				},
+				{
+					KeytabJoin: &sambaoperatorv1alpha1.SmbSecurityKeytabJoinSpec{
+						Secret: "keytab1",
+						Key:    "krb5.keytab",
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, m.validate(ctx, sc, nil))
+	sc.Spec.JoinSources[0].UserJoin.Key = "nopass"
+	err := m.validate(ctx, sc, nil)
+	assert.Equal(t, ReasonInvalidSecret, reason(err))
+	assert.Contains(t, err.Error(), "join source 0")
+	sc.Spec.JoinSources[0].UserJoin.Key = "join.json"
+	sc.Spec.JoinSources[1].KeytabJoin.Secret = "keytab2"
+	err = m.validate(ctx, sc, nil)
+	assert.Equal(t, ReasonSecretNotFound, reason(err))
+	assert.Contains(t, err.Error(), "join source 1")
+	sc.Spec.JoinSources[1].KeytabJoin.Secret = "keytab1"
+
+	sc.Spec.JoinSources = append(sc.Spec.JoinSources,
+		sambaoperatorv1alpha1.SmbSecurityJoinSpec{
+			ComputerAccountJoin: &sambaoperatorv1alpha1.SmbSecurityComputerAccountJoinSpec{
+				Secret: "otp1",
+				Key:    "password",
+			},
+		})
+	assert.NoError(t, m.validate(ctx, sc, nil))
+	sc.Spec.JoinSources[2].ComputerAccountJoin.Key = "blank"
+	err = m.validate(ctx, sc, nil)
+	assert.Equal(t, ReasonInvalidSecret, reason(err))
+	assert.Contains(t, err.Error(), "join source 2")
+	sc.Spec.JoinSources = sc.Spec.JoinSources[:2]
+
+	sc.Spec.JoinOptions = &sambaoperatorv1alpha1.SmbSecurityJoinOptionsSpec{
+		ComputerAccountName: "FILES1",
+	}
+	shares := []sambaoperatorv1alpha1.SmbShare{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+			Spec:       sambaoperatorv1alpha1.SmbShareSpec{SecurityConfig: "addc"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "share2"},
+			Spec: sambaoperatorv1alpha1.SmbShareSpec{
+				SecurityConfig: "addc",
+				NetbiosName:    "FILES2",
+			},
+		},
+	}
+	assert.NoError(t, m.validate(ctx, sc, shares))
+	shares[1].Spec.NetbiosName = ""
+	assert.Equal(t, ReasonInvalidJoinOptions,
+		reason(m.validate(ctx, sc, shares)))
+
+	sc.Spec.JoinSources = nil
+	sc.Spec.Domains = []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "BEDROCK", Backend: "rid", UnixNSSInfo: true},
+	}
+	assert.Equal(t, ReasonInvalidDomains, reason(m.validate(ctx, sc, nil)))
+}
+
+func TestRecordIDMapRanges(t *testing.T) {
+	reason := func(err error) string {
+		if invalid, ok := err.(*invalidConfigError); ok {
+			return invalid.reason
+		}
+		return ""
+	}
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+			Domains: []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+				{Name: "BEDROCK", Backend: "ad"},
+				{Name: "QUARRY", Backend: "rid"},
+			},
+		},
+	}
+	assert.NoError(t, recordIDMapRanges(sc, true))
+	recorded := []sambaoperatorv1alpha1.SmbSecurityIDMapRange{
+		{Name: "BEDROCK", RangeStart: 2000, RangeSize: 10000},
+		{Name: "QUARRY", RangeStart: 12000, RangeSize: 10000},
+		{Name: "*", RangeStart: 22000, RangeSize: 10000},
+	}
+	assert.Equal(t, recorded, sc.Status.IDMapRanges)
+
+	// reordering the domains keeps the recorded ranges
+	sc.Spec.Domains[0], sc.Spec.Domains[1] =
+		sc.Spec.Domains[1], sc.Spec.Domains[0]
+	assert.NoError(t, recordIDMapRanges(sc, true))
+	assert.ElementsMatch(t, recorded, sc.Status.IDMapRanges)
+
+	// a range set in the spec does not replace one in use
+	sc.Spec.Domains[0].RangeStart = 100000
+	sc.Spec.Domains[0].RangeSize = 50000
+	assert.Equal(t, ReasonIDMapRangeInUse,
+		reason(recordIDMapRanges(sc, true)))
+	assert.ElementsMatch(t, recorded, sc.Status.IDMapRanges)
+
+	sc.Annotations = map[string]string{
+		sambaoperatorv1alpha1.ForceIDMapChangeAnnotation: "true",
+	}
+	assert.NoError(t, recordIDMapRanges(sc, true))
+	assert.Contains(t, sc.Status.IDMapRanges,
+		sambaoperatorv1alpha1.SmbSecurityIDMapRange{
+			Name: "QUARRY", RangeStart: 100000, RangeSize: 50000,
+		})
+
+	// unless no share uses the config
+	sc.Annotations = nil
+	sc.Spec.Domains[0].RangeStart = 200000
+	assert.NoError(t, recordIDMapRanges(sc, false))
+	assert.Contains(t, sc.Status.IDMapRanges,
+		sambaoperatorv1alpha1.SmbSecurityIDMapRange{
+			Name: "QUARRY", RangeStart: 200000, RangeSize: 50000,
+		})
+
+	// a new range may not overlap the recorded ones
+	sc.Spec.Domains = append(sc.Spec.Domains,
+		sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+			Name: "GRAVEL", Backend: "rid", RangeStart: 5000, RangeSize: 1000,
+		})
+	assert.Equal(t, ReasonInvalidDomains,
+		reason(recordIDMapRanges(sc, false)))
+	assert.Len(t, sc.Status.IDMapRanges, 3)
+}
+
+func TestDiscoverWorkgroupDeletesJob(t *testing.T) {
+	ctx := context.Background()
+	jobExists := false
+	deleted := 0
+	client := &fakeClient{
+		clientGet: func(
+			_ context.Context,
+			nn types.NamespacedName,
+			_ rtclient.Object) error {
+			// ---
+			if !jobExists {
+				return errors.NewNotFound(
+					schema.GroupResource{Resource: "jobs"}, nn.Name)
+			}
+			return nil
+		},
+		clientDelete: func(_ context.Context, _ rtclient.Object) error {
+			deleted++
+			return nil
+		},
+	}
+	m := &SmbSecurityConfigManager{
+		client: client,
+		reader: client,
+		logger: &fakeLogger{},
+	}
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "sc1", Namespace: "ns1"},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "domain1.sink.test",
+		},
+		Status: sambaoperatorv1alpha1.SmbSecurityConfigStatus{
+			Workgroup: "DOMAIN1",
+		},
+	}
+
+	assert.Equal(t, Done, m.discoverWorkgroup(ctx, sc))
+	assert.Equal(t, 0, deleted)
+
+	jobExists = true
+	assert.Equal(t, Done, m.discoverWorkgroup(ctx, sc))
+	assert.Equal(t, 1, deleted)
+}
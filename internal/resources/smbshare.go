@@ -18,10 +18,13 @@ package resources
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -38,6 +41,11 @@ import (
 
 const shareFinalizer = "samba-operator.samba.org/shareFinalizer"
 
+// domainLeaveTimeout is the maximum time to wait for the computer account
+// of a server group to be removed from the domain before the last share of
+// the group is deleted regardless.
+const domainLeaveTimeout = 5 * time.Minute
+
 const (
 	serverBackend    = "samba-operator.samba.org/serverBackend"
 	clusteredBackend = "clustered:ctdb/statefulset"
@@ -51,6 +59,7 @@ type SmbShareManager struct {
 	recorder record.EventRecorder
 	logger   Logger
 	cfg      *conf.OperatorConfig
+	execer   PodExecer
 }
 
 // NewSmbShareManager creates a SmbShareManager.
@@ -58,7 +67,8 @@ func NewSmbShareManager(
 	client rtclient.Client,
 	scheme *runtime.Scheme,
 	recorder record.EventRecorder,
-	logger Logger) *SmbShareManager {
+	logger Logger,
+	execer PodExecer) *SmbShareManager {
 	// ---
 	return &SmbShareManager{
 		client:   client,
@@ -66,6 +76,7 @@ func NewSmbShareManager(
 		recorder: recorder,
 		logger:   logger,
 		cfg:      conf.Get(),
+		execer:   execer,
 	}
 }
 
@@ -104,6 +115,21 @@ func (m *SmbShareManager) Process(
 
 // Update should be called when a SmbShare resource changes.
 func (m *SmbShareManager) Update(
+	ctx context.Context,
+	instance *sambaoperatorv1alpha1.SmbShare) Result {
+	// ---
+	previous := instance.Status.DeepCopy()
+	result := m.update(ctx, instance)
+	markReadiness(instance, result)
+	if err := m.updateStatus(ctx, instance, previous); err != nil {
+		if result.err == nil {
+			return Result{err: err}
+		}
+	}
+	return result
+}
+
+func (m *SmbShareManager) update(
 	ctx context.Context,
 	instance *sambaoperatorv1alpha1.SmbShare) Result {
 	// ---
@@ -115,7 +141,8 @@ func (m *SmbShareManager) Update(
 
 	changed, err := m.addFinalizer(ctx, instance)
 	if err != nil {
-		return Result{err: err}
+		return markIncomplete(instance,
+			sambaoperatorv1alpha1.ShareConditionConfigApplied, Result{err: err})
 	}
 	if changed {
 		m.logger.Info("Added finalizer")
@@ -123,7 +150,8 @@ func (m *SmbShareManager) Update(
 	}
 
 	if result := m.updateForOpenshift(ctx, instance); result.Yield() {
-		return result
+		return markIncomplete(instance,
+			sambaoperatorv1alpha1.ShareConditionServerAvailable, result)
 	}
 
 	// assign the share to a Server Group. The server group represents
@@ -132,13 +160,22 @@ func (m *SmbShareManager) Update(
 	// many (all?) of these resources.
 	changed, err = m.setServerGroup(ctx, instance)
 	if err != nil {
-		return Result{err: err}
+		return markIncomplete(instance,
+			sambaoperatorv1alpha1.ShareConditionConfigApplied, Result{err: err})
 	}
 	if changed {
 		m.logger.Info("Updated server group")
 		return Requeue
 	}
 
+	if result := m.waitForWorkgroup(ctx, instance); result.Yield() {
+		return result
+	}
+	if result := m.checkComputerAccount(ctx, instance); result.Yield() {
+		return markIncomplete(
+			instance, sambaoperatorv1alpha1.ShareConditionConfigApplied, result)
+	}
+
 	var planner *pln.Planner
 	if p, result := m.updateConfigMap(ctx, instance); !result.Yield() {
 		// p and result only exist within the scope of the if-statement. This
@@ -146,38 +183,54 @@ func (m *SmbShareManager) Update(
 		// the planner, we need to assign p to the func scoped var
 		planner = p
 	} else {
-		return result
+		return markIncomplete(
+			instance, sambaoperatorv1alpha1.ShareConditionConfigApplied, result)
 	}
+	setShareCondition(instance,
+		sambaoperatorv1alpha1.ShareConditionConfigApplied,
+		metav1.ConditionTrue,
+		ReasonConfigApplied,
+		"samba configuration is up to date")
 
 	if shareNeedsPvc(instance) {
 		if result := m.updatePVC(ctx, instance); result.Yield() {
-			return result
+			return markIncomplete(
+				instance, sambaoperatorv1alpha1.ShareConditionStorageBound, result)
 		}
 	}
+	if result := m.checkStorageBound(ctx, instance); result.Yield() {
+		return markIncomplete(
+			instance, sambaoperatorv1alpha1.ShareConditionStorageBound, result)
+	}
 
 	hasBackend := instance.Annotations[serverBackend] != ""
 	if !hasBackend {
 		if result := m.updateBackend(ctx, planner); result.Yield() {
-			return result
+			return markIncomplete(instance,
+				sambaoperatorv1alpha1.ShareConditionServerAvailable, result)
 		}
 	} else {
-		if result := m.validateBackend(planner); result.Yield() {
-			return result
+		if result := m.validateBackend(ctx, planner); result.Yield() {
+			return markIncomplete(instance,
+				sambaoperatorv1alpha1.ShareConditionServerAvailable, result)
 		}
 	}
 
 	if planner.IsClustered() {
 		if result := m.updateClusteredState(ctx, planner); result.Yield() {
-			return result
+			return markIncomplete(
+				instance, sambaoperatorv1alpha1.ShareConditionServerAvailable, result)
 		}
 	} else {
 		if result := m.updateNonClusteredState(ctx, planner); result.Yield() {
-			return result
+			return markIncomplete(
+				instance, sambaoperatorv1alpha1.ShareConditionServerAvailable, result)
 		}
 	}
 
 	if result := m.updateSmbService(ctx, planner); result.Yield() {
-		return result
+		return markIncomplete(
+			instance, sambaoperatorv1alpha1.ShareConditionServerAvailable, result)
 	}
 
 	if result := m.updateMetricsService(ctx, planner); result.Yield() {
@@ -196,6 +249,72 @@ func (m *SmbShareManager) Update(
 	return Done
 }
 
+// waitForWorkgroup holds off the configuration of the share until the
+// workgroup of the share's active directory domain has been discovered.
+// Servers set up before would use a workgroup merely derived from the
+// realm. The share is processed again when the security config changes.
+func (m *SmbShareManager) waitForWorkgroup(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare) Result {
+	// ---
+	sc, err := m.getSecurityConfig(ctx, s)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// reported when the configuration is updated
+			return Done
+		}
+		return markIncomplete(s,
+			sambaoperatorv1alpha1.ShareConditionConfigApplied, Result{err: err})
+	}
+	if sc == nil || !needsWorkgroup(sc) {
+		return Done
+	}
+	msg := fmt.Sprintf(
+		"waiting for the workgroup of security config %s to be discovered",
+		sc.Name)
+	if c := meta.FindStatusCondition(sc.Status.Conditions,
+		sambaoperatorv1alpha1.SecurityConfigConditionWorkgroupDiscovered); c != nil {
+		msg = fmt.Sprintf("%s: %s", msg, c.Message)
+	}
+	setShareCondition(s, sambaoperatorv1alpha1.ShareConditionConfigApplied,
+		metav1.ConditionFalse, ReasonWaitingForWorkgroup, msg)
+	return Result{requeue: true, requeueAfter: workgroupRetryInterval}
+}
+
+// checkComputerAccount refuses to configure a share whose servers would
+// join the domain with the computer account of another server group. The
+// computer account name of a security config is reserved for the server
+// group of the oldest share using it.
+func (m *SmbShareManager) checkComputerAccount(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare) Result {
+	// ---
+	sc, err := m.getSecurityConfig(ctx, s)
+	if errors.IsNotFound(err) {
+		return Done
+	} else if err != nil {
+		return Result{err: err}
+	}
+	if sc == nil || s.Spec.NetbiosName != "" {
+		return Done
+	}
+	shares := &sambaoperatorv1alpha1.SmbShareList{}
+	err = m.client.List(ctx, shares, rtclient.InNamespace(s.Namespace))
+	if err != nil {
+		return Result{err: err}
+	}
+	groups := pln.ComputerAccountGroups(sc, shares.Items)
+	if len(groups) < 2 || groups[0] == s.Status.ServerGroup {
+		return Done
+	}
+	err = fmt.Errorf(
+		"computer account name %s of security config %s is already used"+
+			" by server group %s",
+		sc.Spec.JoinOptions.ComputerAccountName, sc.Name, groups[0])
+	m.recorder.Event(s, EventWarning, ReasonInvalidConfiguration, err.Error())
+	return Result{err: err}
+}
+
 func (m *SmbShareManager) updateConfigMap(
 	ctx context.Context,
 	smbshare *sambaoperatorv1alpha1.SmbShare) (*pln.Planner, Result) {
@@ -216,6 +335,13 @@ func (m *SmbShareManager) updateConfigMap(
 		m.logger.Info("Updated config map ownership")
 		return nil, Requeue
 	}
+	changed, err = m.setPrimaryVolume(ctx, smbshare, cm)
+	if err != nil {
+		return nil, Result{err: err}
+	} else if changed {
+		m.logger.Info("Recorded primary volume of server group")
+		return nil, Requeue
+	}
 	planner, changed, err := m.updateConfiguration(ctx, cm, smbshare)
 	if err != nil {
 		return nil, Result{err: err}
@@ -245,7 +371,75 @@ func (m *SmbShareManager) updatePVC(
 		return Requeue
 	}
 	// if name is unset in the YAML, set it here
-	smbshare.Spec.Storage.Pvc.Name = pvc.Name
+	if pvcSpec := smbshare.Spec.Storage.Pvc; pvcSpec != nil {
+		pvcSpec.Name = pvc.Name
+		if pvcSpec.Spec != nil && isOrphaned(pvc) {
+			return m.adoptPVC(ctx, smbshare, pvc)
+		}
+		if pvcSpec.Spec != nil && metav1.IsControlledBy(pvc, smbshare) {
+			// the PVC was created from the embedded spec, keep its
+			// storage request in sync
+			return m.expandPVC(ctx, smbshare, pvc)
+		}
+	}
+	return Done
+}
+
+// checkStorageBound sets the StorageBound condition based on the phase of
+// the data PVC. An unbound PVC does not block further processing as some
+// storage classes only bind volumes once a pod has been scheduled.
+func (m *SmbShareManager) checkStorageBound(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare) Result {
+	// ---
+	planner := pln.New(pln.InstanceConfiguration{SmbShare: smbshare}, nil)
+	switch planner.StorageKind() {
+	case pln.StorageNone:
+		setShareCondition(smbshare,
+			sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionFalse,
+			ReasonNoStorage,
+			"no storage is specified for the share")
+		return Done
+	case pln.StorageEphemeral, pln.StorageCSI, pln.StorageNFS:
+		// inline volumes are set up along with the pods
+		setShareCondition(smbshare,
+			sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionTrue,
+			ReasonInlineVolume,
+			fmt.Sprintf("share uses an inline %s volume",
+				planner.StorageKind()))
+		return Done
+	case pln.StoragePVC, pln.StoragePersistentVolume:
+	}
+	name := pvcName(smbshare)
+	pvc, err := m.getExistingPVC(ctx, name, smbshare.Namespace)
+	if err != nil {
+		return Result{err: err}
+	}
+	switch {
+	case pvc == nil:
+		setShareCondition(smbshare,
+			sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionFalse,
+			ReasonPVCNotBound,
+			fmt.Sprintf("PVC %s not found", name))
+	case pvc.Status.Phase != corev1.ClaimBound:
+		setShareCondition(smbshare,
+			sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionFalse,
+			ReasonPVCNotBound,
+			fmt.Sprintf("PVC %s is %s", name, pvc.Status.Phase))
+	default:
+		setShareCondition(smbshare,
+			sambaoperatorv1alpha1.ShareConditionStorageBound,
+			metav1.ConditionTrue,
+			ReasonPVCBound,
+			fmt.Sprintf("PVC %s is bound", name))
+		if capacity, found := pvc.Status.Capacity[corev1.ResourceStorage]; found {
+			smbshare.Status.Capacity = &capacity
+		}
+	}
 	return Done
 }
 
@@ -284,46 +478,26 @@ func (m *SmbShareManager) updateBackend(
 }
 
 func (m *SmbShareManager) validateBackend(
+	ctx context.Context,
 	planner *pln.Planner) Result {
 	// ---
-	var (
-		err      error
-		smbshare = planner.SmbShare
-	)
-	// As of today, the system is too immature to try and trivially
-	// reconcile a change in availability mode. We use the previously
-	// recorded backend to check if a change is being made after the
-	// "point of no return".
-	// In the future we should certainly revisit this and support
-	// intelligent methods to handle changes like this.
+	smbshare := planner.SmbShare
+	// The previously recorded backend tells us if the availability mode
+	// of the share has been changed since the servers were created.
+	// A change is handled by converting the server group to the new
+	// backend.
 	b := smbshare.Annotations[serverBackend]
-	if planner.IsClustered() && b != clusteredBackend {
-		err = fmt.Errorf(
-			"Can not convert SmbShare to clustered instance."+
-				" Current backend: %s",
-			b)
-		m.logger.Error(
-			err,
-			"Backend inconsistency detected",
-			"SmbShare.Namespace", smbshare.Namespace,
-			"SmbShare.Name", smbshare.Name,
-			"SmbShare.UID", smbshare.UID)
-		return Result{err: err}
-	}
-	if !planner.IsClustered() && b != standardBackend {
-		err = fmt.Errorf(
-			"Can not convert SmbShare to non-clustered instance."+
-				" Current backend: %s",
-			b)
-		m.logger.Error(
-			err,
-			"Backend inconsistency detected",
-			"SmbShare.Namespace", smbshare.Namespace,
-			"SmbShare.Name", smbshare.Name,
-			"SmbShare.UID", smbshare.UID)
-		return Result{err: err}
+	if (planner.IsClustered() && b == clusteredBackend) ||
+		(!planner.IsClustered() && b == standardBackend) {
+		markConversionDone(smbshare)
+		return Done
 	}
-	return Done
+	m.logger.Info("Backend change detected",
+		"SmbShare.Namespace", smbshare.Namespace,
+		"SmbShare.Name", smbshare.Name,
+		"SmbShare.UID", smbshare.UID,
+		"backend", b)
+	return m.convertBackend(ctx, planner)
 }
 
 func (m *SmbShareManager) updateClusteredState(
@@ -338,6 +512,13 @@ func (m *SmbShareManager) updateClusteredState(
 		m.logger.Error(err, "Clustering support is not enabled")
 		return Result{err: err}
 	}
+	if !planner.SharedStorage() {
+		err = fmt.Errorf(
+			"storage kind %s can not be used by clustered shares",
+			planner.StorageKind())
+		m.logger.Error(err, "Unsupported storage for clustered share")
+		return Result{err: err}
+	}
 	_, created, err := m.getOrCreateStatePVC(
 		ctx, planner, planner.SmbShare.Namespace)
 	if err != nil {
@@ -371,18 +552,21 @@ func (m *SmbShareManager) updateClusteredState(
 		return Requeue
 	}
 
-	resized, err := m.updateStatefulSetSize(
-		ctx, statefulSet,
-		// revive:disable:line-length-limit gosec rule ignore
-		int32(planner.SmbShare.Spec.Scaling.MinClusterSize)) // #nosec G115
-	// revive:enable:line-length-limit
+	changed, err = m.updateStatefulSetVolumes(ctx, planner, statefulSet)
 	if err != nil {
 		return Result{err: err}
-	}
-	if resized {
-		m.logger.Info("Resized statefulSet")
+	} else if changed {
+		m.logger.Info("Updated stateful set volumes")
 		return Requeue
 	}
+
+	markClusterSize(planner.SmbShare)
+	if result := m.updateClusterSize(ctx, planner, statefulSet); result.Yield() {
+		return result
+	}
+	setScaleStatus(planner, statefulSet.Status.Replicas)
+	markServerAvailability(planner.SmbShare, "StatefulSet",
+		statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas)
 	return Done
 }
 
@@ -414,6 +598,14 @@ func (m *SmbShareManager) updateNonClusteredState(
 		return Requeue
 	}
 
+	changed, err = m.updateDeploymentVolumes(ctx, planner, deployment)
+	if err != nil {
+		return Result{err: err}
+	} else if changed {
+		m.logger.Info("Updated deployment volumes")
+		return Requeue
+	}
+
 	resized, err := m.updateDeploymentSize(ctx, deployment)
 	if err != nil {
 		return Result{err: err}
@@ -422,6 +614,9 @@ func (m *SmbShareManager) updateNonClusteredState(
 		m.logger.Info("Resized deployment")
 		return Requeue
 	}
+	setScaleStatus(planner, deployment.Status.Replicas)
+	markServerAvailability(planner.SmbShare, "Deployment",
+		deployment.Status.ReadyReplicas, *deployment.Spec.Replicas)
 	return Done
 }
 
@@ -447,6 +642,10 @@ func (m *SmbShareManager) updateSmbService(
 		return Requeue
 	}
 
+	// the load balancer address may be assigned some time after the
+	// service is created. The update of the service will trigger another
+	// reconcile and thus update the endpoint.
+	planner.SmbShare.Status.Endpoint = endpointForService(planner, svc)
 	return Done
 }
 
@@ -512,6 +711,10 @@ func (m *SmbShareManager) Finalize(
 		}
 	}
 
+	if result := m.finalizeDomainMembership(ctx, instance); result.Yield() {
+		return result
+	}
+
 	m.logger.Info("Removing finalizer")
 	controllerutil.RemoveFinalizer(instance, shareFinalizer)
 	err := m.client.Update(ctx, instance)
@@ -597,6 +800,9 @@ func (m *SmbShareManager) finalizeDataPVC(
 	ctx context.Context,
 	smbshare *sambaoperatorv1alpha1.SmbShare) Result {
 	// ---
+	if !shareNeedsPvc(smbshare) {
+		return Done
+	}
 	destNamespace := smbshare.Namespace
 	name := pvcName(smbshare)
 	pvc, err := m.getExistingPVC(ctx, name, destNamespace)
@@ -607,10 +813,155 @@ func (m *SmbShareManager) finalizeDataPVC(
 		if result := m.transferOwnership(ctx, pvc, smbshare); result.Yield() {
 			return result
 		}
+		// without another owner the PVC would be garbage collected
+		// along with the share
+		if result := m.retainPVC(ctx, smbshare, pvc); result.Yield() {
+			return result
+		}
 	}
 	return Done
 }
 
+// finalizeDomainMembership removes the computer account of an AD server
+// group from the domain once the last share of the group is deleted. The
+// share is deleted regardless if leaving the domain fails or does not
+// finish within domainLeaveTimeout. A warning event is recorded whenever
+// the share is deleted without the computer account being removed.
+func (m *SmbShareManager) finalizeDomainMembership(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare) Result {
+	// ---
+	security, err := m.getSecurityConfig(ctx, smbshare)
+	if errors.IsNotFound(err) {
+		m.logger.Info("SmbSecurityConfig not found: not leaving domain")
+		last, err := m.isLastGroupMember(ctx, smbshare)
+		if err != nil {
+			return Result{err: err}
+		}
+		if last {
+			m.recorder.Eventf(smbshare,
+				EventWarning,
+				ReasonLeavingDomain,
+				"Not removing the computer account of server group %s"+
+					" from the domain: security config %s not found",
+				smbshare.Status.ServerGroup, smbshare.Spec.SecurityConfig)
+		}
+		return Done
+	} else if err != nil {
+		return Result{err: err}
+	}
+	common, err := m.getCommonConfig(ctx, smbshare)
+	if err != nil && !errors.IsNotFound(err) {
+		return Result{err: err}
+	}
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare:       smbshare,
+		SecurityConfig: security,
+		CommonConfig:   common,
+		GlobalConfig:   m.cfg,
+	}, nil)
+	if planner.SecurityMode() != pln.ADMode {
+		return Done
+	}
+	last, err := m.isLastGroupMember(ctx, smbshare)
+	if err != nil {
+		return Result{err: err}
+	}
+	if !last {
+		return Done
+	}
+	jsrc := getJoinSources(planner)
+	if len(jsrc.paths) == 0 && len(jsrc.keytabs) == 0 {
+		m.logger.Info("No join source can remove the computer account")
+		m.recorder.Eventf(smbshare,
+			EventWarning,
+			ReasonLeavingDomain,
+			"Not removing computer account %s from domain %s:"+
+				" security config %s has no user or keytab join sources",
+			planner.NetbiosName(), planner.Realm(), security.Name)
+		return Done
+	}
+
+	job, created, err := m.getOrCreateDomainLeaveJob(
+		ctx, planner, smbshare.Namespace)
+	if err != nil {
+		// the job may never be created, for example in a namespace that
+		// is being deleted
+		if time.Since(smbshare.DeletionTimestamp.Time) < domainLeaveTimeout {
+			return Result{err: err}
+		}
+		m.recorder.Eventf(smbshare,
+			EventWarning,
+			ReasonLeavingDomain,
+			"Failed to remove computer account %s from domain %s: %s",
+			planner.NetbiosName(), planner.Realm(), err.Error())
+		return Done
+	}
+	if created {
+		m.recorder.Eventf(smbshare,
+			EventNormal,
+			ReasonLeavingDomain,
+			"Removing computer account %s from domain %s",
+			planner.NetbiosName(), planner.Realm())
+		return Requeue
+	}
+	finished, cond := jobFinished(job)
+	switch {
+	case !finished && time.Since(job.CreationTimestamp.Time) < domainLeaveTimeout:
+		return Requeue
+	case !finished:
+		m.recorder.Eventf(smbshare,
+			EventWarning,
+			ReasonLeavingDomain,
+			"Timed out removing computer account %s from domain %s",
+			planner.NetbiosName(), planner.Realm())
+	case cond.Type == batchv1.JobFailed:
+		m.recorder.Eventf(smbshare,
+			EventWarning,
+			ReasonLeavingDomain,
+			"Failed to remove computer account %s from domain %s: %s",
+			planner.NetbiosName(), planner.Realm(), cond.Message)
+	default:
+		m.recorder.Eventf(smbshare,
+			EventNormal,
+			ReasonLeftDomain,
+			"Removed computer account %s from domain %s",
+			planner.NetbiosName(), planner.Realm())
+	}
+	return Done
+}
+
+// isLastGroupMember returns true if no other share that is not being
+// deleted is hosted by the server group of the share. The server group
+// never existed if its ConfigMap does not.
+func (m *SmbShareManager) isLastGroupMember(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare) (bool, error) {
+	// ---
+	cm, err := m.getConfigMap(ctx, smbshare, smbshare.Namespace)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	names, err := ownerSharesExcluding(cm, smbshare)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		other, err := m.getSmbShareByName(ctx, name)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if other.GetDeletionTimestamp() == nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func (m *SmbShareManager) updateStatefulSetSize(
 	ctx context.Context,
 	statefulSet *appsv1.StatefulSet,
@@ -656,18 +1007,18 @@ func (m *SmbShareManager) updateDeploymentSize(
 }
 
 func pvcName(s *sambaoperatorv1alpha1.SmbShare) string {
-	if s.Spec.Storage.Pvc.Name != "" {
-		return s.Spec.Storage.Pvc.Name
-	}
-	return s.Name + "-pvc"
+	return pln.New(pln.InstanceConfiguration{SmbShare: s}, nil).PVCName()
 }
 
 func sharedStatePVCName(planner *pln.Planner) string {
 	return planner.InstanceName() + "-state"
 }
 
+// shareNeedsPvc returns true if the operator manages the PVC holding the
+// data of the share.
 func shareNeedsPvc(s *sambaoperatorv1alpha1.SmbShare) bool {
-	return s.Spec.Storage.Pvc != nil && s.Spec.Storage.Pvc.Spec != nil
+	st := s.Spec.Storage
+	return (st.Pvc != nil && st.Pvc.Spec != nil) || st.PersistentVolume != nil
 }
 
 func (m *SmbShareManager) updateConfiguration(
@@ -699,30 +1050,22 @@ func (m *SmbShareManager) updateConfiguration(
 		return nil, false, err
 	}
 
-	if len(otherShares) > 0 {
-		// this server group will be hosting > 1 share, but we must
-		// first pass our sanity checks
-		other, err := m.getSmbShareByName(ctx, otherShares[0])
-		if err != nil {
-			return nil, false, err
-		}
-		otherInstance, err := m.getShareInstance(ctx, other)
-		if err != nil {
-			return nil, false, err
-		}
-		if err = pln.CheckCompatible(shareInstance, otherInstance); err != nil {
-			m.recorder.Event(
-				s,
-				EventWarning,
-				ReasonInvalidConfiguration,
-				err.Error())
-			return nil, false, err
-		}
+	// this server group may be hosting > 1 share, but we must first pass
+	// our sanity checks
+	peers, err := m.getPeerInstances(ctx, shareInstance, otherShares)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// extract config from map
 	var changed bool
 	planner := pln.New(shareInstance, cc)
+	planner.Peers = peers
+	planner.PrimaryVolume = cm.Annotations[primaryVolumeAnnotation]
+	planner.Conversion, err = m.conversionPhase(ctx, planner)
+	if err != nil {
+		return nil, false, err
+	}
 	changed, err = planner.Update()
 	if err != nil {
 		m.logger.Error(err, "unable to update samba container config")
@@ -731,6 +1074,7 @@ func (m *SmbShareManager) updateConfiguration(
 	if !changed {
 		// nothing changed between the planner and the config stored in the cm
 		// we can just return now as no changes need to be applied to the cm
+		m.recordShareName(s, planner)
 		return planner, false, nil
 	}
 	err = setContainerConfig(cm, planner.ConfigState)
@@ -751,9 +1095,112 @@ func (m *SmbShareManager) updateConfiguration(
 			"ConfigMap.Name", cm.Name)
 		return nil, false, err
 	}
+	m.recordShareName(s, planner)
 	return planner, true, nil
 }
 
+// getPeerInstances returns the instance configurations of the other shares
+// hosted by the server group of the share. An error is returned if any of
+// the peers is not compatible with the share.
+func (m *SmbShareManager) getPeerInstances(
+	ctx context.Context,
+	shareInstance pln.InstanceConfiguration,
+	names []types.NamespacedName) ([]pln.InstanceConfiguration, error) {
+	// ---
+	s := shareInstance.SmbShare
+	instanceName := pln.New(shareInstance, nil).InstanceName()
+	peers := []pln.InstanceConfiguration{}
+	for _, name := range names {
+		other, err := m.getSmbShareByName(ctx, name)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			m.logger.Error(
+				err,
+				"Failed to get server group member",
+				"SmbShare.Namespace", name.Namespace,
+				"SmbShare.Name", name.Name)
+			return nil, err
+		}
+		if other.GetDeletionTimestamp() != nil ||
+			other.Status.ServerGroup != instanceName {
+			continue
+		}
+		otherInstance, err := m.getShareInstance(ctx, other)
+		if err != nil {
+			return nil, err
+		}
+		if err = pln.CheckCompatible(shareInstance, otherInstance); err != nil {
+			m.recorder.Event(
+				s,
+				EventWarning,
+				ReasonInvalidConfiguration,
+				err.Error())
+			return nil, err
+		}
+		peers = append(peers, otherInstance)
+	}
+	return peers, nil
+}
+
+// getPeerShares returns the other shares hosted by the server group of the
+// planner's share. Unlike getPeerInstances the related configuration
+// resources of the shares are not looked up.
+func (m *SmbShareManager) getPeerShares(
+	ctx context.Context,
+	planner *pln.Planner,
+	names []types.NamespacedName) ([]pln.InstanceConfiguration, error) {
+	// ---
+	peers := []pln.InstanceConfiguration{}
+	for _, name := range names {
+		other, err := m.getSmbShareByName(ctx, name)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			m.logger.Error(
+				err,
+				"Failed to get server group member",
+				"SmbShare.Namespace", name.Namespace,
+				"SmbShare.Name", name.Name)
+			return nil, err
+		}
+		if other.GetDeletionTimestamp() != nil ||
+			other.Status.ServerGroup != planner.InstanceName() {
+			continue
+		}
+		peers = append(peers, pln.InstanceConfiguration{
+			SmbShare:     other,
+			GlobalConfig: m.cfg,
+		})
+	}
+	return peers, nil
+}
+
+// recordShareName records the name the share was configured with in the
+// status of the SmbShare, reporting when the share was renamed.
+func (m *SmbShareManager) recordShareName(
+	s *sambaoperatorv1alpha1.SmbShare,
+	planner *pln.Planner) {
+	// ---
+	prev := s.Status.ShareName
+	name := planner.ShareName()
+	if prev == name {
+		return
+	}
+	if prev != "" {
+		m.logger.Info("Renamed share",
+			"SmbShare.Namespace", s.Namespace,
+			"SmbShare.Name", s.Name,
+			"PreviousShareName", prev,
+			"ShareName", name)
+		m.recorder.Eventf(s,
+			EventNormal,
+			ReasonRenamedShare,
+			"Renamed share %s to %s", prev, name)
+	}
+	s.Status.ShareName = name
+}
+
 func (m *SmbShareManager) pruneConfiguration(
 	ctx context.Context,
 	cm *corev1.ConfigMap,
@@ -782,7 +1229,16 @@ func (m *SmbShareManager) pruneConfiguration(
 		SmbShare:     s,
 		GlobalConfig: m.cfg,
 	}
+	otherShares, err := ownerSharesExcluding(cm, s)
+	if err != nil {
+		m.logger.Error(err, "unable to get shares owning config map")
+		return false, err
+	}
 	planner := pln.New(shareInstance, cc)
+	planner.Peers, err = m.getPeerShares(ctx, planner, otherShares)
+	if err != nil {
+		return false, err
+	}
 	changed, err = planner.Prune()
 	if err != nil {
 		m.logger.Error(err, "unable to update samba container config")
@@ -6,10 +6,17 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -210,3 +217,185 @@ func TestTransferOwnership(t *testing.T) {
 		assert.False(t, res.requeue)
 	})
 }
+
+func TestIsLastGroupMember(t *testing.T) {
+	ctx := context.Background()
+	m := testingManager()
+	s1 := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gumby",
+			Namespace: "clayland",
+			UID:       "111111111",
+		},
+	}
+	s2 := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pokey",
+			Namespace: "clayland",
+			UID:       "222222222",
+		},
+	}
+	cm, err := newDefaultConfigMap("gumby", "clayland")
+	assert.NoError(t, err)
+	_, err = m.claimOwnership(ctx, s1, cm)
+	assert.NoError(t, err)
+	_, err = m.claimOwnership(ctx, s2, cm)
+	assert.NoError(t, err)
+
+	m.client.(*fakeClient).clientGet = func(
+		_ context.Context,
+		nn types.NamespacedName,
+		obj rtclient.Object) error {
+		// ---
+		switch out := obj.(type) {
+		case *corev1.ConfigMap:
+			cm.DeepCopyInto(out)
+			return nil
+		case *sambaoperatorv1alpha1.SmbShare:
+			if nn.Name == "pokey" {
+				s2.DeepCopyInto(out)
+				return nil
+			}
+		}
+		return fmt.Errorf("unexpected name: %s/%s", nn.Namespace, nn.Name)
+	}
+	last, err := m.isLastGroupMember(ctx, s1)
+	assert.NoError(t, err)
+	assert.False(t, last)
+
+	// shares that are being deleted no longer count as members
+	ts := metav1.Now()
+	s2.SetDeletionTimestamp(&ts)
+	last, err = m.isLastGroupMember(ctx, s1)
+	assert.NoError(t, err)
+	assert.True(t, last)
+}
+
+func TestWaitForWorkgroup(t *testing.T) {
+	ctx := context.Background()
+	m := testingManager()
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "clayland",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "clayland.example.com",
+		},
+	}
+	m.client.(*fakeClient).clientGet = func(
+		_ context.Context,
+		_ types.NamespacedName,
+		obj rtclient.Object) error {
+		// ---
+		sc.DeepCopyInto(obj.(*sambaoperatorv1alpha1.SmbSecurityConfig))
+		return nil
+	}
+	s := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gumby",
+			Namespace: "clayland",
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{SecurityConfig: "addc"},
+	}
+
+	res := m.waitForWorkgroup(ctx, s)
+	assert.NoError(t, res.err)
+	assert.True(t, res.Yield())
+	c := meta.FindStatusCondition(s.Status.Conditions,
+		sambaoperatorv1alpha1.ShareConditionConfigApplied)
+	if assert.NotNil(t, c) {
+		assert.Equal(t, ReasonWaitingForWorkgroup, c.Reason)
+	}
+
+	sc.Status.Workgroup = "CLAYLAND"
+	assert.False(t, m.waitForWorkgroup(ctx, s).Yield())
+
+	sc.Status.Workgroup = ""
+	sc.Spec.Workgroup = "CLAYLAND"
+	assert.False(t, m.waitForWorkgroup(ctx, s).Yield())
+
+	s.Spec.SecurityConfig = ""
+	assert.False(t, m.waitForWorkgroup(ctx, s).Yield())
+}
+
+func TestFinalizeDomainMembership(t *testing.T) {
+	ctx := context.Background()
+	m := testingManager()
+	recorder := record.NewFakeRecorder(10)
+	m.recorder = recorder
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "clayland",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:      "active-directory",
+			Realm:     "clayland.example.com",
+			Workgroup: "CLAYLAND",
+			JoinSources: []sambaoperatorv1alpha1.SmbSecurityJoinSpec{{
+				UserJoin: &sambaoperatorv1alpha1.SmbSecurityUserJoinSpec{
+					Secret: "join1",
+					Key:    "join.json",
+				},
+			}},
+		},
+	}
+	forbidden := errors.NewForbidden(
+		schema.GroupResource{Group: "batch", Resource: "jobs"},
+		"gumby-leave", fmt.Errorf("namespace clayland is being terminated"))
+	m.client.(*fakeClient).clientGet = func(
+		_ context.Context,
+		_ types.NamespacedName,
+		obj rtclient.Object) error {
+		// ---
+		switch o := obj.(type) {
+		case *sambaoperatorv1alpha1.SmbSecurityConfig:
+			sc.DeepCopyInto(o)
+		case *batchv1.Job:
+			return forbidden
+		}
+		// the ConfigMap of the group has no other owners
+		return nil
+	}
+	deleted := metav1.Now()
+	s := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "gumby",
+			Namespace:         "clayland",
+			DeletionTimestamp: &deleted,
+		},
+		Spec:   sambaoperatorv1alpha1.SmbShareSpec{SecurityConfig: "addc"},
+		Status: sambaoperatorv1alpha1.SmbShareStatus{ServerGroup: "gumby"},
+	}
+
+	// the job can not be looked up or created, retry for a while
+	res := m.finalizeDomainMembership(ctx, s)
+	assert.ErrorIs(t, res.err, forbidden)
+	assert.Empty(t, recorder.Events)
+
+	// then delete the share regardless
+	deleted = metav1.NewTime(time.Now().Add(-domainLeaveTimeout))
+	s.DeletionTimestamp = &deleted
+	res = m.finalizeDomainMembership(ctx, s)
+	assert.False(t, res.Yield())
+	if assert.Len(t, recorder.Events, 1) {
+		event := <-recorder.Events
+		assert.Contains(t, event, EventWarning)
+		assert.Contains(t, event, "Failed to remove computer account gumby")
+	}
+
+	// a computer account can not remove itself
+	sc.Spec.JoinSources = []sambaoperatorv1alpha1.SmbSecurityJoinSpec{{
+		ComputerAccountJoin: &sambaoperatorv1alpha1.SmbSecurityComputerAccountJoinSpec{
+			Secret: "otp1",
+		},
+	}}
+	res = m.finalizeDomainMembership(ctx, s)
+	assert.False(t, res.Yield())
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Contains(t, <-recorder.Events,
+			"has no user or keytab join sources")
+	}
+}
@@ -0,0 +1,595 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+const (
+	// snapshotOfLabel names the share a VolumeSnapshot, or a PVC restored
+	// from a VolumeSnapshot, belongs to.
+	snapshotOfLabel = "samba-operator.samba.org/snapshot-of"
+	// snapshotTimeAnnotation records the time a snapshot was taken.
+	snapshotTimeAnnotation = "samba-operator.samba.org/snapshot-time"
+
+	// snapshotRetryInterval is the time to wait before checking again
+	// for a share's PVC to be bound.
+	snapshotRetryInterval = time.Minute
+	// snapshotReadyTimeout is the time a snapshot may take to become
+	// ready. A snapshot that is not ready by then is reported and no
+	// longer holds back the next snapshot.
+	snapshotReadyTimeout = 30 * time.Minute
+	// snapshotNamePrefixMax is the length of the share name used in the
+	// names of snapshots. Along with the hash the name fits a DNS label.
+	snapshotNamePrefixMax = 50
+)
+
+// VolumeSnapshotGVK is the group, version and kind of the VolumeSnapshots
+// taken of share PVCs. The VolumeSnapshot API is provided by the CSI
+// external-snapshotter and is not part of Kubernetes itself.
+var VolumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   "snapshot.storage.k8s.io",
+	Version: "v1",
+	Kind:    "VolumeSnapshot",
+}
+
+// SnapshotManager takes and prunes the VolumeSnapshots of SmbShares and
+// restores them to PVCs the smb servers mount.
+type SnapshotManager struct {
+	client   rtclient.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	logger   Logger
+	cfg      *conf.OperatorConfig
+	now      func() time.Time
+	// available is false if the VolumeSnapshot API is not installed
+	// in the cluster.
+	available bool
+}
+
+// NewSnapshotManager creates a SnapshotManager. If the VolumeSnapshot API
+// is not available the manager only reports that snapshots can not be
+// taken.
+func NewSnapshotManager(
+	client rtclient.Client,
+	scheme *runtime.Scheme,
+	recorder record.EventRecorder,
+	logger Logger,
+	available bool) *SnapshotManager {
+	// ---
+	return &SnapshotManager{
+		client:    client,
+		scheme:    scheme,
+		recorder:  recorder,
+		logger:    logger,
+		cfg:       conf.Get(),
+		now:       time.Now,
+		available: available,
+	}
+}
+
+// shareSnapshot describes a VolumeSnapshot taken of a share's PVC.
+type shareSnapshot struct {
+	name        string
+	taken       time.Time
+	ready       bool
+	restoreSize string
+}
+
+// NewVolumeSnapshot returns an empty VolumeSnapshot object.
+func NewVolumeSnapshot() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(VolumeSnapshotGVK)
+	return u
+}
+
+// Process is called by the controller on any type of reconciliation.
+func (m *SnapshotManager) Process(
+	ctx context.Context,
+	nsname types.NamespacedName) Result {
+	// ---
+	instance := &sambaoperatorv1alpha1.SmbShare{}
+	err := m.client.Get(ctx, nsname, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return Done
+		}
+		m.logger.Error(
+			err,
+			"Failed to get SmbShare",
+			"SmbShare.Namespace", nsname.Namespace,
+			"SmbShare.Name", nsname.Name)
+		return Result{err: err}
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		// snapshots and restored PVCs are garbage collected along
+		// with the share
+		return Done
+	}
+	planner := pln.New(pln.InstanceConfiguration{
+		SmbShare:     instance,
+		GlobalConfig: m.cfg,
+	}, nil)
+	previous := instance.Status.DeepCopy()
+	var result Result
+	switch {
+	case !planner.SnapshotsEnabled():
+		meta.RemoveStatusCondition(&instance.Status.Conditions,
+			sambaoperatorv1alpha1.ShareConditionSnapshotsReady)
+		// the snapshots are kept, the smb servers stop mounting them
+		result = Done
+		if err := m.restoreSnapshots(ctx, instance, nil, nil); err != nil {
+			result = Result{err: err}
+		}
+	case !m.available:
+		m.markSnapshots(instance, metav1.ConditionFalse, EventWarning,
+			ReasonSnapshotAPIUnavailable,
+			"the VolumeSnapshot API is not installed in the cluster")
+		result = Done
+	default:
+		result = m.update(ctx, planner)
+	}
+	if err := m.updateStatus(ctx, instance, previous); err != nil {
+		return Result{err: err}
+	}
+	return result
+}
+
+func (m *SnapshotManager) updateStatus(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare,
+	previous *sambaoperatorv1alpha1.SmbShareStatus) error {
+	// ---
+	if equality.Semantic.DeepEqual(previous, &s.Status) {
+		return nil
+	}
+	err := m.client.Status().Update(ctx, s)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update SmbShare status",
+			"SmbShare.Namespace", s.Namespace,
+			"SmbShare.Name", s.Name)
+	}
+	return err
+}
+
+func (m *SnapshotManager) update(
+	ctx context.Context, planner *pln.Planner) Result {
+	// ---
+	smbshare := planner.SmbShare
+	dataPVC := &corev1.PersistentVolumeClaim{}
+	err := m.client.Get(ctx, types.NamespacedName{
+		Namespace: smbshare.Namespace,
+		Name:      planner.PVCName(),
+	}, dataPVC)
+	if errors.IsNotFound(err) {
+		return requeueAfter(snapshotRetryInterval)
+	} else if err != nil {
+		return Result{err: err}
+	}
+	if dataPVC.Status.Phase != corev1.ClaimBound {
+		m.logger.Info("Waiting for PVC to be bound before taking snapshots",
+			"PersistentVolumeClaim.Name", dataPVC.Name)
+		return requeueAfter(snapshotRetryInterval)
+	}
+
+	snaps, err := m.listSnapshots(ctx, smbshare)
+	if err != nil {
+		return Result{err: err}
+	}
+	now := m.now()
+	m.trackSnapshots(smbshare, snaps, now)
+	if due, _ := snapshotDue(snaps, planner.SnapshotInterval(), now); due {
+		if err := m.takeSnapshot(ctx, planner, now); err != nil {
+			return Result{err: err}
+		}
+		return Requeue
+	}
+	// snapshots exceeding the retention are removed once the newest
+	// snapshot is ready, so that the server pods change only once to
+	// mount the newest snapshot and unmount the removed ones
+	if newest := snaps[0]; newest.ready || snapshotTimedOut(newest, now) {
+		for _, snap := range pruneSnapshots(snaps, planner.SnapshotRetention()) {
+			if err := m.deleteSnapshot(ctx, smbshare, snap); err != nil {
+				return Result{err: err}
+			}
+		}
+		snaps = retainSnapshots(snaps, planner.SnapshotRetention())
+	}
+	if err := m.restoreSnapshots(ctx, smbshare, dataPVC, snaps); err != nil {
+		return Result{err: err}
+	}
+	_, wait := snapshotDue(snaps, planner.SnapshotInterval(), now)
+	return requeueAfter(wait)
+}
+
+func (m *SnapshotManager) listSnapshots(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare) ([]shareSnapshot, error) {
+	// ---
+	l := &unstructured.UnstructuredList{}
+	l.SetGroupVersionKind(VolumeSnapshotGVK.GroupVersion().WithKind(
+		VolumeSnapshotGVK.Kind + "List"))
+	err := m.client.List(ctx, l,
+		rtclient.InNamespace(smbshare.Namespace),
+		rtclient.MatchingLabels{snapshotOfLabel: smbshare.Name})
+	if err != nil {
+		m.logger.Error(err, "Failed to list VolumeSnapshots",
+			"SmbShare.Namespace", smbshare.Namespace,
+			"SmbShare.Name", smbshare.Name)
+		return nil, err
+	}
+	snaps := []shareSnapshot{}
+	for i := range l.Items {
+		u := &l.Items[i]
+		if u.GetDeletionTimestamp() != nil {
+			continue
+		}
+		snaps = append(snaps, toShareSnapshot(u))
+	}
+	sortSnapshots(snaps)
+	return snaps, nil
+}
+
+func toShareSnapshot(u *unstructured.Unstructured) shareSnapshot {
+	snap := shareSnapshot{name: u.GetName()}
+	taken, err := time.Parse(
+		time.RFC3339, u.GetAnnotations()[snapshotTimeAnnotation])
+	if err != nil {
+		taken = u.GetCreationTimestamp().Time
+	}
+	snap.taken = taken.UTC()
+	snap.ready, _, _ = unstructured.NestedBool(
+		u.Object, "status", "readyToUse")
+	snap.restoreSize, _, _ = unstructured.NestedString(
+		u.Object, "status", "restoreSize")
+	return snap
+}
+
+// sortSnapshots orders the snapshots newest first.
+func sortSnapshots(snaps []shareSnapshot) {
+	sort.SliceStable(snaps, func(i, j int) bool {
+		return snaps[i].taken.After(snaps[j].taken)
+	})
+}
+
+// snapshotDue returns true if a new snapshot should be taken. Otherwise
+// it returns the time until the next snapshot is due. No snapshot is taken
+// while the newest snapshot is becoming ready, so that slow snapshots do
+// not pile up. A snapshot that timed out no longer holds back the next one.
+func snapshotDue(
+	snaps []shareSnapshot,
+	interval time.Duration,
+	now time.Time) (bool, time.Duration) {
+	// ---
+	if len(snaps) == 0 {
+		return true, 0
+	}
+	newest := snaps[0]
+	if !newest.ready && !snapshotTimedOut(newest, now) {
+		// check again once the snapshot times out. the snapshot
+		// becoming ready triggers a reconciliation before that
+		return false, newest.taken.Add(snapshotReadyTimeout).Sub(now)
+	}
+	wait := newest.taken.Add(interval).Sub(now)
+	if wait <= 0 {
+		return true, 0
+	}
+	return false, wait
+}
+
+func snapshotTimedOut(snap shareSnapshot, now time.Time) bool {
+	return !snap.ready && now.Sub(snap.taken) >= snapshotReadyTimeout
+}
+
+// trackSnapshots sets the SnapshotsReady condition of the share based on
+// the state of the newest snapshot.
+func (m *SnapshotManager) trackSnapshots(
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	snaps []shareSnapshot,
+	now time.Time) {
+	// ---
+	if len(snaps) == 0 {
+		return
+	}
+	newest := snaps[0]
+	switch {
+	case newest.ready:
+		m.markSnapshots(smbshare, metav1.ConditionTrue, EventNormal,
+			ReasonSnapshotReady,
+			fmt.Sprintf("VolumeSnapshot %s taken at %s is ready",
+				newest.name, newest.taken.Format(time.RFC3339)))
+	case snapshotTimedOut(newest, now):
+		m.markSnapshots(smbshare, metav1.ConditionFalse, EventWarning,
+			ReasonSnapshotTimedOut,
+			fmt.Sprintf("VolumeSnapshot %s did not become ready within %s",
+				newest.name, snapshotReadyTimeout))
+	default:
+		m.markSnapshots(smbshare, metav1.ConditionFalse, EventNormal,
+			ReasonSnapshotPending,
+			fmt.Sprintf("waiting for VolumeSnapshot %s to become ready",
+				newest.name))
+	}
+}
+
+// markSnapshots sets the SnapshotsReady condition of the share. Like
+// markResizing, an event is only recorded when the reason changes.
+func (m *SnapshotManager) markSnapshots(
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	status metav1.ConditionStatus,
+	eventType, reason, message string) {
+	// ---
+	c := meta.FindStatusCondition(smbshare.Status.Conditions,
+		sambaoperatorv1alpha1.ShareConditionSnapshotsReady)
+	if c == nil || c.Reason != reason {
+		m.recorder.Event(smbshare, eventType, reason, message)
+	}
+	setShareCondition(smbshare,
+		sambaoperatorv1alpha1.ShareConditionSnapshotsReady,
+		status, reason, message)
+}
+
+// pruneSnapshots returns the snapshots, ordered newest first, exceeding
+// the retention count.
+func pruneSnapshots(snaps []shareSnapshot, retention int) []shareSnapshot {
+	if len(snaps) <= retention {
+		return nil
+	}
+	return snaps[retention:]
+}
+
+// retainSnapshots returns the snapshots, ordered newest first, kept by
+// the retention count.
+func retainSnapshots(snaps []shareSnapshot, retention int) []shareSnapshot {
+	if len(snaps) <= retention {
+		return snaps
+	}
+	return snaps[:retention]
+}
+
+// snapshotName returns the name of the snapshot of the share taken at the
+// given time. The name ends with a hash of the share's UID and the time,
+// and is short enough to be used as a DNS label.
+func snapshotName(smbshare *sambaoperatorv1alpha1.SmbShare, t time.Time) string {
+	prefix := smbshare.Name
+	if len(prefix) > snapshotNamePrefixMax {
+		prefix = strings.TrimRight(prefix[:snapshotNamePrefixMax], "-.")
+	}
+	sum := sha256.Sum256(
+		[]byte(string(smbshare.UID) + "@" + t.UTC().Format(time.RFC3339)))
+	return fmt.Sprintf("%s-%x", prefix, sum[:6])
+}
+
+func buildVolumeSnapshot(
+	planner *pln.Planner, t time.Time) *unstructured.Unstructured {
+	// ---
+	smbshare := planner.SmbShare
+	u := NewVolumeSnapshot()
+	u.SetName(snapshotName(smbshare, t))
+	u.SetNamespace(smbshare.Namespace)
+	u.SetLabels(map[string]string{snapshotOfLabel: smbshare.Name})
+	u.SetAnnotations(map[string]string{
+		snapshotTimeAnnotation: t.UTC().Format(time.RFC3339),
+	})
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": planner.PVCName(),
+		},
+	}
+	if c := planner.SnapshotClassName(); c != "" {
+		spec["volumeSnapshotClassName"] = c
+	}
+	u.Object["spec"] = spec
+	return u
+}
+
+func (m *SnapshotManager) takeSnapshot(
+	ctx context.Context, planner *pln.Planner, now time.Time) error {
+	// ---
+	smbshare := planner.SmbShare
+	// snapshot names have a resolution of one second
+	now = now.Truncate(time.Second)
+	snap := buildVolumeSnapshot(planner, now)
+	err := controllerutil.SetControllerReference(smbshare, snap, m.scheme)
+	if err != nil {
+		return err
+	}
+	m.logger.Info(
+		"Creating a new VolumeSnapshot",
+		"SmbShare.Namespace", smbshare.Namespace,
+		"SmbShare.Name", smbshare.Name,
+		"VolumeSnapshot.Name", snap.GetName())
+	err = m.client.Create(ctx, snap)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to create VolumeSnapshot",
+			"VolumeSnapshot.Namespace", snap.GetNamespace(),
+			"VolumeSnapshot.Name", snap.GetName())
+		return err
+	}
+	m.recorder.Eventf(smbshare,
+		EventNormal,
+		ReasonCreatedVolumeSnapshot,
+		"Created VolumeSnapshot %s of PVC %s",
+		snap.GetName(), planner.PVCName())
+	return nil
+}
+
+// deleteSnapshot removes a snapshot along with the PVC restored from it.
+func (m *SnapshotManager) deleteSnapshot(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	snap shareSnapshot) error {
+	// ---
+	m.logger.Info(
+		"Deleting VolumeSnapshot exceeding retention",
+		"SmbShare.Namespace", smbshare.Namespace,
+		"SmbShare.Name", smbshare.Name,
+		"VolumeSnapshot.Name", snap.name)
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.Name = snap.name
+	pvc.Namespace = smbshare.Namespace
+	err := m.client.Delete(ctx, pvc)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	u := NewVolumeSnapshot()
+	u.SetName(snap.name)
+	u.SetNamespace(smbshare.Namespace)
+	err = m.client.Delete(ctx, u)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	m.recorder.Eventf(smbshare,
+		EventNormal,
+		ReasonDeletedVolumeSnapshot,
+		"Deleted VolumeSnapshot %s", snap.name)
+	return nil
+}
+
+// restoreSnapshots makes sure a PVC is restored from every ready snapshot
+// and removes restored PVCs of the other snapshots. Without snapshots all
+// the restored PVCs are removed.
+func (m *SnapshotManager) restoreSnapshots(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	dataPVC *corev1.PersistentVolumeClaim,
+	snaps []shareSnapshot) error {
+	// ---
+	restored, err := listSnapshotPVCs(ctx, m.client, smbshare)
+	if err != nil {
+		return err
+	}
+	found := map[string]bool{}
+	for _, pvc := range restored {
+		found[pvc.Name] = true
+	}
+	wanted := map[string]bool{}
+	for _, snap := range snaps {
+		wanted[snap.name] = true
+		if !snap.ready || found[snap.name] {
+			continue
+		}
+		pvc := buildSnapshotPVC(smbshare, dataPVC, snap)
+		err := controllerutil.SetControllerReference(smbshare, pvc, m.scheme)
+		if err != nil {
+			return err
+		}
+		m.logger.Info(
+			"Restoring VolumeSnapshot to a new PVC",
+			"SmbShare.Namespace", smbshare.Namespace,
+			"SmbShare.Name", smbshare.Name,
+			"PersistentVolumeClaim.Name", pvc.Name)
+		err = m.client.Create(ctx, pvc)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			m.logger.Error(
+				err,
+				"Failed to create PVC",
+				"PersistentVolumeClaim.Namespace", pvc.Namespace,
+				"PersistentVolumeClaim.Name", pvc.Name)
+			return err
+		}
+	}
+	for i := range restored {
+		pvc := &restored[i]
+		if wanted[pvc.Name] || pvc.GetDeletionTimestamp() != nil {
+			continue
+		}
+		m.logger.Info(
+			"Deleting PVC of removed VolumeSnapshot",
+			"PersistentVolumeClaim.Name", pvc.Name)
+		err := m.client.Delete(ctx, pvc)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildSnapshotPVC returns a PVC restoring the snapshot, using the storage
+// class and access modes of the share's PVC.
+func buildSnapshotPVC(
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	dataPVC *corev1.PersistentVolumeClaim,
+	snap shareSnapshot) *corev1.PersistentVolumeClaim {
+	// ---
+	size := dataPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	if q, err := resource.ParseQuantity(snap.restoreSize); err == nil {
+		size = q
+	}
+	apiGroup := VolumeSnapshotGVK.Group
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      snap.name,
+			Namespace: smbshare.Namespace,
+			Labels:    map[string]string{snapshotOfLabel: smbshare.Name},
+			Annotations: map[string]string{
+				snapshotTimeAnnotation: snap.taken.Format(time.RFC3339),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      dataPVC.Spec.AccessModes,
+			StorageClassName: dataPVC.Spec.StorageClassName,
+			VolumeMode:       dataPVC.Spec.VolumeMode,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     VolumeSnapshotGVK.Kind,
+				Name:     snap.name,
+			},
+		},
+	}
+}
+
+// listSnapshotPVCs returns the PVCs restored from the share's snapshots.
+func listSnapshotPVCs(
+	ctx context.Context,
+	client rtclient.Client,
+	smbshare *sambaoperatorv1alpha1.SmbShare) (
+	[]corev1.PersistentVolumeClaim, error) {
+	// ---
+	l := &corev1.PersistentVolumeClaimList{}
+	err := client.List(ctx, l,
+		rtclient.InNamespace(smbshare.Namespace),
+		rtclient.MatchingLabels{snapshotOfLabel: smbshare.Name})
+	if err != nil {
+		return nil, err
+	}
+	return l.Items, nil
+}
+
+// snapshotTime returns the time the snapshot a restored PVC was created
+// from was taken.
+func snapshotTime(pvc *corev1.PersistentVolumeClaim) (time.Time, error) {
+	return time.Parse(time.RFC3339, pvc.Annotations[snapshotTimeAnnotation])
+}
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+func snapshotShare() *sambaoperatorv1alpha1.SmbShare {
+	return &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pebbles",
+			Namespace: "bedrock",
+			UID:       types.UID("phonyuid1"),
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+					Name: "quarry",
+				},
+			},
+			Snapshots: &sambaoperatorv1alpha1.SmbShareSnapshotsSpec{
+				VolumeSnapshotClassName: "fast",
+			},
+		},
+	}
+}
+
+func TestSnapshotDue(t *testing.T) {
+	now := time.Date(2021, 7, 4, 12, 0, 0, 0, time.UTC)
+	interval := 6 * time.Hour
+
+	due, _ := snapshotDue(nil, interval, now)
+	assert.True(t, due)
+
+	snaps := []shareSnapshot{
+		{name: "b", taken: now.Add(-2 * time.Hour), ready: true},
+		{name: "a", taken: now.Add(-8 * time.Hour), ready: true},
+	}
+	due, wait := snapshotDue(snaps, interval, now)
+	assert.False(t, due)
+	assert.Equal(t, 4*time.Hour, wait)
+
+	snaps[0].taken = now.Add(-7 * time.Hour)
+	due, _ = snapshotDue(snaps, interval, now)
+	assert.True(t, due)
+
+	// never take a new snapshot while the newest is becoming ready
+	snaps[0].ready = false
+	snaps[0].taken = now.Add(-10 * time.Minute)
+	due, wait = snapshotDue(snaps, interval, now)
+	assert.False(t, due)
+	assert.Equal(t, snapshotReadyTimeout-10*time.Minute, wait)
+
+	// a snapshot that timed out no longer holds back the next one
+	snaps[0].taken = now.Add(-7 * time.Hour)
+	due, _ = snapshotDue(snaps, interval, now)
+	assert.True(t, due)
+}
+
+func TestSnapshotName(t *testing.T) {
+	smbshare := snapshotShare()
+	taken := time.Date(2021, 7, 4, 12, 30, 15, 0, time.UTC)
+	name := snapshotName(smbshare, taken)
+	assert.Regexp(t, "^pebbles-[0-9a-f]{12}$", name)
+	assert.Equal(t, name, snapshotName(smbshare, taken.Local()))
+	assert.NotEqual(t, name, snapshotName(smbshare, taken.Add(time.Second)))
+
+	smbshare.Name = strings.Repeat("pebbles-", 10)
+	name = snapshotName(smbshare, taken)
+	assert.Empty(t, validation.IsDNS1123Label(name))
+	assert.True(t, strings.HasPrefix(name, "pebbles-pebbles-"))
+}
+
+func TestTrackSnapshots(t *testing.T) {
+	now := time.Date(2021, 7, 4, 12, 0, 0, 0, time.UTC)
+	recorder := record.NewFakeRecorder(10)
+	m := &SnapshotManager{recorder: recorder}
+	smbshare := snapshotShare()
+	condition := func() *metav1.Condition {
+		return meta.FindStatusCondition(smbshare.Status.Conditions,
+			sambaoperatorv1alpha1.ShareConditionSnapshotsReady)
+	}
+	snaps := []shareSnapshot{
+		{name: "b", taken: now.Add(-10 * time.Minute)},
+		{name: "a", taken: now.Add(-6 * time.Hour), ready: true},
+	}
+
+	m.trackSnapshots(smbshare, snaps, now)
+	if c := condition(); assert.NotNil(t, c) {
+		assert.Equal(t, metav1.ConditionFalse, c.Status)
+		assert.Equal(t, ReasonSnapshotPending, c.Reason)
+	}
+
+	m.trackSnapshots(smbshare, snaps, now.Add(snapshotReadyTimeout))
+	if c := condition(); assert.NotNil(t, c) {
+		assert.Equal(t, metav1.ConditionFalse, c.Status)
+		assert.Equal(t, ReasonSnapshotTimedOut, c.Reason)
+	}
+	// the timeout is reported by a single event
+	m.trackSnapshots(smbshare, snaps, now.Add(2*snapshotReadyTimeout))
+	assert.Len(t, recorder.Events, 2)
+
+	snaps[0].ready = true
+	m.trackSnapshots(smbshare, snaps, now)
+	if c := condition(); assert.NotNil(t, c) {
+		assert.Equal(t, metav1.ConditionTrue, c.Status)
+		assert.Equal(t, ReasonSnapshotReady, c.Reason)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	now := time.Date(2021, 7, 4, 12, 0, 0, 0, time.UTC)
+	snaps := []shareSnapshot{
+		{name: "a", taken: now.Add(-3 * time.Hour)},
+		{name: "c", taken: now.Add(-1 * time.Hour)},
+		{name: "b", taken: now.Add(-2 * time.Hour)},
+	}
+	sortSnapshots(snaps)
+	assert.Equal(t, "c", snaps[0].name)
+	assert.Equal(t, "a", snaps[2].name)
+
+	assert.Empty(t, pruneSnapshots(snaps, 3))
+	assert.Len(t, retainSnapshots(snaps, 3), 3)
+
+	pruned := pruneSnapshots(snaps, 2)
+	if assert.Len(t, pruned, 1) {
+		assert.Equal(t, "a", pruned[0].name)
+	}
+	assert.Len(t, retainSnapshots(snaps, 2), 2)
+}
+
+func TestBuildVolumeSnapshot(t *testing.T) {
+	smbshare := snapshotShare()
+	planner := pln.New(pln.InstanceConfiguration{SmbShare: smbshare}, nil)
+	taken := time.Date(2021, 7, 4, 12, 30, 15, 0, time.UTC)
+
+	u := buildVolumeSnapshot(planner, taken)
+	assert.Equal(t, VolumeSnapshotGVK, u.GroupVersionKind())
+	assert.Equal(t, snapshotName(smbshare, taken), u.GetName())
+	assert.Equal(t, "bedrock", u.GetNamespace())
+	assert.Equal(t, "pebbles", u.GetLabels()[snapshotOfLabel])
+	src, _, _ := unstructured.NestedString(
+		u.Object, "spec", "source", "persistentVolumeClaimName")
+	assert.Equal(t, "quarry", src)
+	class, _, _ := unstructured.NestedString(
+		u.Object, "spec", "volumeSnapshotClassName")
+	assert.Equal(t, "fast", class)
+
+	_ = unstructured.SetNestedField(u.Object, true, "status", "readyToUse")
+	_ = unstructured.SetNestedField(u.Object, "2Gi", "status", "restoreSize")
+	snap := toShareSnapshot(u)
+	assert.Equal(t, u.GetName(), snap.name)
+	assert.True(t, snap.ready)
+	assert.True(t, taken.Equal(snap.taken))
+
+	storageClass := "slow"
+	dataPVC := &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteMany,
+			},
+			StorageClassName: &storageClass,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}
+	pvc := buildSnapshotPVC(smbshare, dataPVC, snap)
+	assert.Equal(t, snap.name, pvc.Name)
+	assert.Equal(t, "pebbles", pvc.Labels[snapshotOfLabel])
+	assert.Equal(t, &storageClass, pvc.Spec.StorageClassName)
+	assert.Equal(t, dataPVC.Spec.AccessModes, pvc.Spec.AccessModes)
+	size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	assert.Equal(t, "2Gi", size.String())
+	if assert.NotNil(t, pvc.Spec.DataSource) {
+		assert.Equal(t, "VolumeSnapshot", pvc.Spec.DataSource.Kind)
+		assert.Equal(t, snap.name, pvc.Spec.DataSource.Name)
+	}
+	restored, err := snapshotTime(pvc)
+	assert.NoError(t, err)
+	assert.True(t, taken.Equal(restored))
+
+	vm := snapshotVolumeAndMount(planner, pvc.Name, restored)
+	assert.Equal(t, pvc.Name, vm.volume.Name)
+	assert.Equal(t,
+		"/snapshots/phonyuid1/@GMT-2021.07.04-12.30.15", vm.mount.MountPath)
+	assert.True(t, vm.mount.ReadOnly)
+}
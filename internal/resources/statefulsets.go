@@ -25,11 +25,12 @@ import (
 
 func buildStatefulSet(
 	planner *pln.Planner,
-	dataPVCName, statePVCName, ns string) *appsv1.StatefulSet {
+	shareVols []volMount,
+	statePVCName, ns string) *appsv1.StatefulSet {
 	// ---
 	labels := labelsForSmbServer(planner)
 	size := planner.ClusterSize()
-	podSpec := buildClusteredPodSpec(planner, dataPVCName, statePVCName)
+	podSpec := buildClusteredPodSpec(planner, shareVols, statePVCName)
 	if planner.NodeSpread() {
 		podSpec.Affinity = buildOneSmbdPerNodeAffinity(planner, labels, serviceLabel)
 	} else {
@@ -48,7 +49,7 @@ func buildStatefulSet(
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      labels,
+					Labels:      podLabelsForSmbServer(planner),
 					Annotations: annotationsForSmbPod(planner.GlobalConfig),
 				},
 				Spec: podSpec,
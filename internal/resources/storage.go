@@ -0,0 +1,576 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+const (
+	// orphanedLabel marks a PVC retained after its share was deleted.
+	orphanedLabel = "samba-operator.samba.org/orphaned"
+	// orphanedFromLabel names the share a retained PVC belonged to.
+	orphanedFromLabel = "samba-operator.samba.org/orphaned-from"
+)
+
+// dataPVCName returns the name of the PVC holding the share's data or an
+// empty string if the share's storage is not accessed through a PVC.
+func dataPVCName(planner *pln.Planner) string {
+	if !planner.UsesPVC() {
+		return ""
+	}
+	return planner.PVCName()
+}
+
+// persistentVolumeClaimSpec returns the spec of a PVC that binds the named
+// PersistentVolume.
+func (m *SmbShareManager) persistentVolumeClaimSpec(
+	ctx context.Context,
+	name string) (*corev1.PersistentVolumeClaimSpec, error) {
+	// ---
+	pv := &corev1.PersistentVolume{}
+	err := m.client.Get(ctx, types.NamespacedName{Name: name}, pv)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to get PersistentVolume",
+			"PersistentVolume.Name", name)
+		return nil, err
+	}
+	return claimSpecForVolume(pv), nil
+}
+
+// claimSpecForVolume returns a PVC spec matching, and binding to, the given
+// PersistentVolume.
+func claimSpecForVolume(pv *corev1.PersistentVolume) *corev1.PersistentVolumeClaimSpec {
+	// an empty storage class prevents a default storage class from
+	// being assigned to the claim
+	storageClass := pv.Spec.StorageClassName
+	spec := &corev1.PersistentVolumeClaimSpec{
+		AccessModes:      pv.Spec.AccessModes,
+		StorageClassName: &storageClass,
+		VolumeMode:       pv.Spec.VolumeMode,
+		VolumeName:       pv.Name,
+	}
+	if size, found := pv.Spec.Capacity[corev1.ResourceStorage]; found {
+		spec.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceStorage: size,
+		}
+	}
+	return spec
+}
+
+// setPrimaryVolume records the volume of the share as the primary volume of
+// the server group if the group has none yet. Groups created before a group
+// could host shares of more than one volume have their volume mounted at
+// the path of the group, the primary volume keeps using that path.
+func (m *SmbShareManager) setPrimaryVolume(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	cm *corev1.ConfigMap) (bool, error) {
+	// ---
+	if cm.Annotations[primaryVolumeAnnotation] != "" {
+		return false, nil
+	}
+	planner := pln.New(pln.InstanceConfiguration{SmbShare: smbshare}, nil)
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[primaryVolumeAnnotation] = planner.VolumeKey()
+	err := m.client.Update(ctx, cm)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update ConfigMap",
+			"ConfigMap.Namespace", cm.Namespace,
+			"ConfigMap.Name", cm.Name)
+		return false, err
+	}
+	return true, nil
+}
+
+// shareVolumes returns the volumes holding the data of all the shares
+// hosted by the planner's server group.
+func (m *SmbShareManager) shareVolumes(
+	ctx context.Context,
+	planner *pln.Planner) ([]volMount, error) {
+	// ---
+	planners := []*pln.Planner{planner}
+	smbshare := planner.SmbShare
+	cm, err := m.getConfigMap(ctx, smbshare, smbshare.Namespace)
+	if errors.IsNotFound(err) {
+		return m.withSnapshotVolumes(ctx, planners)
+	} else if err != nil {
+		return nil, err
+	}
+	others, err := ownerSharesExcluding(cm, smbshare)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].Name < others[j].Name
+	})
+	for _, name := range others {
+		other, err := m.getSmbShareByName(ctx, name)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			m.logger.Error(
+				err,
+				"Failed to get server group member",
+				"SmbShare.Namespace", name.Namespace,
+				"SmbShare.Name", name.Name)
+			return nil, err
+		}
+		if other.GetDeletionTimestamp() != nil ||
+			other.Status.ServerGroup != planner.InstanceName() {
+			continue
+		}
+		otherPlanner := pln.New(
+			pln.InstanceConfiguration{
+				SmbShare:     other,
+				GlobalConfig: m.cfg,
+			},
+			nil)
+		otherPlanner.PrimaryVolume = planner.PrimaryVolume
+		planners = append(planners, otherPlanner)
+	}
+	return m.withSnapshotVolumes(ctx, planners)
+}
+
+// withSnapshotVolumes returns the volumes of the shares along with the
+// volumes restored from snapshots of the shares, newest first.
+func (m *SmbShareManager) withSnapshotVolumes(
+	ctx context.Context,
+	planners []*pln.Planner) ([]volMount, error) {
+	// ---
+	vols := shareVolumesAndMounts(planners)
+	for _, planner := range planners {
+		if !planner.SnapshotsEnabled() {
+			continue
+		}
+		pvcs, err := listSnapshotPVCs(ctx, m.client, planner.SmbShare)
+		if err != nil {
+			return nil, err
+		}
+		snapVols := []volMount{}
+		taken := map[string]time.Time{}
+		for i := range pvcs {
+			pvc := &pvcs[i]
+			t, err := snapshotTime(pvc)
+			if err != nil || pvc.GetDeletionTimestamp() != nil {
+				continue
+			}
+			taken[pvc.Name] = t
+			snapVols = append(snapVols,
+				snapshotVolumeAndMount(planner, pvc.Name, t))
+		}
+		sort.Slice(snapVols, func(i, j int) bool {
+			return taken[snapVols[i].volume.Name].After(
+				taken[snapVols[j].volume.Name])
+		})
+		vols = append(vols, snapVols...)
+	}
+	return vols, nil
+}
+
+// updateDeploymentVolumes updates the pod template of the deployment when
+// the volumes of the server group changed. For example, when a share
+// backed by another PVC joined the group.
+func (m *SmbShareManager) updateDeploymentVolumes(
+	ctx context.Context,
+	planner *pln.Planner,
+	deployment *appsv1.Deployment) (bool, error) {
+	// ---
+	shareVols, err := m.shareVolumes(ctx, planner)
+	if err != nil {
+		return false, err
+	}
+	desired := buildDeployment(m.cfg, planner, shareVols, deployment.Namespace)
+	if !podVolumesChanged(
+		&deployment.Spec.Template.Spec, &desired.Spec.Template.Spec) {
+		return false, nil
+	}
+	applyPodVolumes(&deployment.Spec.Template.Spec, &desired.Spec.Template.Spec)
+	applyAuditLog(planner, &deployment.Spec.Template.Spec, &desired.Spec.Template.Spec)
+	err = m.client.Update(ctx, deployment)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update Deployment",
+			"Deployment.Namespace", deployment.Namespace,
+			"Deployment.Name", deployment.Name)
+		return false, err
+	}
+	return true, nil
+}
+
+// updateStatefulSetVolumes updates the pod template of the stateful set
+// when the volumes of the server group changed.
+func (m *SmbShareManager) updateStatefulSetVolumes(
+	ctx context.Context,
+	planner *pln.Planner,
+	statefulSet *appsv1.StatefulSet) (bool, error) {
+	// ---
+	shareVols, err := m.shareVolumes(ctx, planner)
+	if err != nil {
+		return false, err
+	}
+	desired := buildStatefulSet(
+		planner,
+		shareVols,
+		sharedStatePVCName(planner),
+		statefulSet.Namespace)
+	if !podVolumesChanged(
+		&statefulSet.Spec.Template.Spec, &desired.Spec.Template.Spec) {
+		return false, nil
+	}
+	applyPodVolumes(&statefulSet.Spec.Template.Spec, &desired.Spec.Template.Spec)
+	applyAuditLog(planner, &statefulSet.Spec.Template.Spec, &desired.Spec.Template.Spec)
+	err = m.client.Update(ctx, statefulSet)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update StatefulSet",
+			"StatefulSet.Namespace", statefulSet.Namespace,
+			"StatefulSet.Name", statefulSet.Name)
+		return false, err
+	}
+	return true, nil
+}
+
+// podVolumesChanged returns true if the pod specs differ in the volumes
+// they define or in where the containers mount them. Other properties of
+// the volumes are ignored as the API server fills in defaults.
+func podVolumesChanged(current, desired *corev1.PodSpec) bool {
+	return !equality.Semantic.DeepEqual(
+		podVolumeMounts(current), podVolumeMounts(desired))
+}
+
+// applyPodVolumes updates the volumes of the current pod spec, and where the
+// containers mount them, to match the desired pod spec. Volumes defined by
+// both specs are left as they are, keeping the defaults filled in by the API
+// server. Nothing else of the pod spec is changed.
+func applyPodVolumes(current, desired *corev1.PodSpec) {
+	existing := map[string]corev1.Volume{}
+	for _, v := range current.Volumes {
+		existing[v.Name] = v
+	}
+	vols := make([]corev1.Volume, 0, len(desired.Volumes))
+	for _, v := range desired.Volumes {
+		if cv, found := existing[v.Name]; found {
+			v = cv
+		}
+		vols = append(vols, v)
+	}
+	current.Volumes = vols
+	applyVolumeMounts(current.InitContainers, desired.InitContainers)
+	applyVolumeMounts(current.Containers, desired.Containers)
+}
+
+func applyVolumeMounts(current, desired []corev1.Container) {
+	mounts := map[string][]corev1.VolumeMount{}
+	for _, ctr := range desired {
+		mounts[ctr.Name] = ctr.VolumeMounts
+	}
+	for i := range current {
+		if vm, found := mounts[current[i].Name]; found {
+			current[i].VolumeMounts = vm
+		}
+	}
+}
+
+func podVolumeMounts(podSpec *corev1.PodSpec) map[string]map[string]string {
+	vols := map[string]map[string]string{}
+	for _, v := range podSpec.Volumes {
+		vols[v.Name] = map[string]string{}
+	}
+	ctrs := append([]corev1.Container{}, podSpec.InitContainers...)
+	ctrs = append(ctrs, podSpec.Containers...)
+	for _, ctr := range ctrs {
+		for _, vm := range ctr.VolumeMounts {
+			if vols[vm.Name] == nil {
+				vols[vm.Name] = map[string]string{}
+			}
+			vols[vm.Name][ctr.Name] = vm.MountPath
+		}
+	}
+	return vols
+}
+
+// expandPVC grows the data PVC when the storage request of the share's
+// embedded PVC spec was raised above the request of the PVC, and records
+// the progress of the resize in the share's Resizing condition.
+func (m *SmbShareManager) expandPVC(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	pvc *corev1.PersistentVolumeClaim) Result {
+	// ---
+	desired, found :=
+		smbshare.Spec.Storage.Pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !found {
+		return Done
+	}
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if desired.Cmp(current) < 0 {
+		// volumes can not be shrunk. the webhook rejects such changes,
+		// there's nothing to do if it was bypassed
+		m.logger.Info(
+			"Ignoring decreased storage request",
+			"PersistentVolumeClaim.Name", pvc.Name,
+			"Requested", desired.String(),
+			"Current", current.String())
+		return Done
+	}
+	if desired.Cmp(current) == 0 {
+		m.trackResize(smbshare, pvc)
+		return Done
+	}
+
+	allowed, scName, err := m.volumeExpansionAllowed(ctx, pvc)
+	if err != nil {
+		return Result{err: err}
+	}
+	if !allowed {
+		m.markResizing(smbshare, metav1.ConditionFalse, EventWarning,
+			ReasonExpansionNotSupported,
+			fmt.Sprintf(
+				"storage class %q of PVC %s does not allow volume expansion",
+				scName, pvc.Name))
+		return Done
+	}
+	m.logger.Info(
+		"Expanding PVC",
+		"PersistentVolumeClaim.Namespace", pvc.Namespace,
+		"PersistentVolumeClaim.Name", pvc.Name,
+		"Requested", desired.String(),
+		"Current", current.String())
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = desired
+	err = m.client.Update(ctx, pvc)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update PVC",
+			"PersistentVolumeClaim.Namespace", pvc.Namespace,
+			"PersistentVolumeClaim.Name", pvc.Name)
+		return Result{err: err}
+	}
+	m.markResizing(smbshare, metav1.ConditionTrue, EventNormal,
+		ReasonExpandingVolume,
+		fmt.Sprintf("expanding PVC %s from %s to %s",
+			pvc.Name, current.String(), desired.String()))
+	return Requeue
+}
+
+// trackResize updates the Resizing condition of the share based on the
+// conditions and capacity of a PVC whose request was already raised.
+func (m *SmbShareManager) trackResize(
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	pvc *corev1.PersistentVolumeClaim) {
+	// ---
+	switch {
+	case pvcConditionTrue(pvc, corev1.PersistentVolumeClaimResizing):
+		m.markResizing(smbshare, metav1.ConditionTrue, EventNormal,
+			ReasonVolumeResizing,
+			fmt.Sprintf("volume of PVC %s is being resized", pvc.Name))
+		return
+	case pvcConditionTrue(pvc, corev1.PersistentVolumeClaimFileSystemResizePending):
+		m.markResizing(smbshare, metav1.ConditionTrue, EventNormal,
+			ReasonFileSystemResizePending,
+			fmt.Sprintf("PVC %s is waiting for its file system to be resized",
+				pvc.Name))
+		return
+	}
+	if !meta.IsStatusConditionTrue(
+		smbshare.Status.Conditions, sambaoperatorv1alpha1.ShareConditionResizing) {
+		return
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity, found := pvc.Status.Capacity[corev1.ResourceStorage]
+	if !found || capacity.Cmp(requested) < 0 {
+		// the resizer has yet to pick up the change
+		return
+	}
+	m.markResizing(smbshare, metav1.ConditionFalse, EventNormal,
+		ReasonVolumeResized,
+		fmt.Sprintf("PVC %s was resized to %s", pvc.Name, capacity.String()))
+}
+
+// markResizing sets the Resizing condition of the share. An event is
+// recorded only when the reason of the condition changes, so that the
+// repeated reconciliation of an unchanged state does not flood the share
+// with events.
+func (m *SmbShareManager) markResizing(
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	status metav1.ConditionStatus,
+	eventType, reason, message string) {
+	// ---
+	c := meta.FindStatusCondition(
+		smbshare.Status.Conditions, sambaoperatorv1alpha1.ShareConditionResizing)
+	if c == nil || c.Reason != reason {
+		m.recorder.Event(smbshare, eventType, reason, message)
+	}
+	setShareCondition(smbshare,
+		sambaoperatorv1alpha1.ShareConditionResizing, status, reason, message)
+}
+
+// volumeExpansionAllowed returns true if the storage class of the PVC
+// allows volumes to be expanded. The name of the storage class is returned
+// for reporting.
+func (m *SmbShareManager) volumeExpansionAllowed(
+	ctx context.Context,
+	pvc *corev1.PersistentVolumeClaim) (bool, string, error) {
+	// ---
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return false, "", nil
+	}
+	name := *pvc.Spec.StorageClassName
+	sc := &storagev1.StorageClass{}
+	err := m.client.Get(ctx, types.NamespacedName{Name: name}, sc)
+	if errors.IsNotFound(err) {
+		return false, name, nil
+	} else if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to get StorageClass",
+			"StorageClass.Name", name)
+		return false, name, err
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, name, nil
+}
+
+func pvcConditionTrue(
+	pvc *corev1.PersistentVolumeClaim,
+	ctype corev1.PersistentVolumeClaimConditionType) bool {
+	// ---
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == ctype {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// reclaimPolicy returns the reclaim policy of a PVC created from the
+// share's embedded PVC spec.
+func reclaimPolicy(s *sambaoperatorv1alpha1.SmbShare) string {
+	if pvc := s.Spec.Storage.Pvc; pvc != nil && pvc.ReclaimPolicy != "" {
+		return pvc.ReclaimPolicy
+	}
+	return sambaoperatorv1alpha1.PvcReclaimDelete
+}
+
+func isOrphaned(pvc *corev1.PersistentVolumeClaim) bool {
+	return pvc.Labels[orphanedLabel] == "true"
+}
+
+// retainPVC keeps the data PVC of a deleted share using the Retain reclaim
+// policy from being garbage collected along with the share. The owner
+// references to shares are removed and the PVC is labeled as orphaned.
+func (m *SmbShareManager) retainPVC(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	pvc *corev1.PersistentVolumeClaim) Result {
+	// ---
+	if reclaimPolicy(smbshare) != sambaoperatorv1alpha1.PvcReclaimRetain ||
+		!metav1.IsControlledBy(pvc, smbshare) {
+		return Done
+	}
+	refs, err := nonSmbShareOwnerRefs(pvc)
+	if err != nil {
+		m.logger.Error(err, "Failed to get PVC owner references")
+		return Result{err: err}
+	}
+	pvc.SetOwnerReferences(refs)
+	if pvc.Labels == nil {
+		pvc.Labels = map[string]string{}
+	}
+	pvc.Labels[orphanedLabel] = "true"
+	if len(validation.IsValidLabelValue(smbshare.Name)) == 0 {
+		pvc.Labels[orphanedFromLabel] = smbshare.Name
+	}
+	m.logger.Info(
+		"Retaining PVC",
+		"SmbShare.Namespace", smbshare.Namespace,
+		"SmbShare.Name", smbshare.Name,
+		"PersistentVolumeClaim.Name", pvc.Name)
+	err = m.client.Update(ctx, pvc)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update PVC",
+			"PersistentVolumeClaim.Namespace", pvc.Namespace,
+			"PersistentVolumeClaim.Name", pvc.Name)
+		return Result{err: err}
+	}
+	m.recorder.Eventf(smbshare,
+		EventNormal,
+		ReasonRetainedPersistentVolumeClaim,
+		"Retained PVC %s of deleted SmbShare", pvc.Name)
+	return Requeue
+}
+
+// adoptPVC makes the share the controlling owner of a PVC retained after
+// the deletion of another share.
+func (m *SmbShareManager) adoptPVC(
+	ctx context.Context,
+	smbshare *sambaoperatorv1alpha1.SmbShare,
+	pvc *corev1.PersistentVolumeClaim) Result {
+	// ---
+	if metav1.GetControllerOf(pvc) != nil {
+		m.logger.Info(
+			"Orphaned PVC is controlled by another resource",
+			"PersistentVolumeClaim.Name", pvc.Name)
+		return Done
+	}
+	err := controllerutil.SetControllerReference(smbshare, pvc, m.scheme)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to set controller reference",
+			"SmbShare.Namespace", smbshare.Namespace,
+			"SmbShare.Name", smbshare.Name,
+			"PersistentVolumeClaim.Name", pvc.Name)
+		return Result{err: err}
+	}
+	from := pvc.Labels[orphanedFromLabel]
+	delete(pvc.Labels, orphanedLabel)
+	delete(pvc.Labels, orphanedFromLabel)
+	m.logger.Info(
+		"Adopting orphaned PVC",
+		"SmbShare.Namespace", smbshare.Namespace,
+		"SmbShare.Name", smbshare.Name,
+		"PersistentVolumeClaim.Name", pvc.Name,
+		"OrphanedFrom", from)
+	err = m.client.Update(ctx, pvc)
+	if err != nil {
+		m.logger.Error(
+			err,
+			"Failed to update PVC",
+			"PersistentVolumeClaim.Namespace", pvc.Namespace,
+			"PersistentVolumeClaim.Name", pvc.Name)
+		return Result{err: err}
+	}
+	m.recorder.Eventf(smbshare,
+		EventNormal,
+		ReasonAdoptedPersistentVolumeClaim,
+		"Adopted PVC %s", pvc.Name)
+	return Requeue
+}
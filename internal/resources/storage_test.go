@@ -0,0 +1,401 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+func TestShareVolumeAndMount(t *testing.T) {
+	planner := pln.New(
+		pln.InstanceConfiguration{
+			SmbShare: &sambaoperatorv1alpha1.SmbShare{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "fred",
+					Namespace: "bedrock",
+					UID:       "fred1",
+				},
+				Spec: sambaoperatorv1alpha1.SmbShareSpec{
+					Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+						Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+							Name: "quarry",
+						},
+					},
+				},
+			},
+		},
+		&smbcc.SambaContainerConfig{})
+	storage := &planner.SmbShare.Spec.Storage
+
+	vmnt := shareVolumeAndMount(planner)
+	assert.Equal(t, "quarry-smb", vmnt.volume.Name)
+	assert.Equal(t, "quarry", vmnt.volume.PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "/mnt/fred1", vmnt.mount.MountPath)
+
+	storage.Pvc = nil
+	storage.NFS = &sambaoperatorv1alpha1.SmbShareNFSSpec{
+		Server: "nfs.example.com",
+		Path:   "/exports/quarry",
+	}
+	assert.Equal(t, "", dataPVCName(planner))
+	vmnt = shareVolumeAndMount(planner)
+	assert.Equal(t, "fred-nfs-smb", vmnt.volume.Name)
+	assert.Equal(t, "fred-nfs-smb", vmnt.mount.Name)
+	assert.Equal(t, "nfs.example.com", vmnt.volume.NFS.Server)
+	assert.Equal(t, "/exports/quarry", vmnt.volume.NFS.Path)
+
+	storage.NFS = nil
+	storage.CSI = &sambaoperatorv1alpha1.SmbShareCSISpec{
+		CSIVolumeSource: corev1.CSIVolumeSource{Driver: "csi.example.com"},
+	}
+	vmnt = shareVolumeAndMount(planner)
+	assert.Equal(t, "csi.example.com", vmnt.volume.CSI.Driver)
+
+	storage.CSI = nil
+	storage.Ephemeral = &sambaoperatorv1alpha1.SmbShareEphemeralSpec{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+		},
+	}
+	vmnt = shareVolumeAndMount(planner)
+	if assert.NotNil(t, vmnt.volume.Ephemeral) {
+		assert.Equal(t, storage.Ephemeral.Spec,
+			vmnt.volume.Ephemeral.VolumeClaimTemplate.Spec)
+	}
+}
+
+func TestShareVolumesAndMounts(t *testing.T) {
+	groupedPlanner := func(name, pvc string) *pln.Planner {
+		planner := pln.New(
+			pln.InstanceConfiguration{
+				SmbShare: &sambaoperatorv1alpha1.SmbShare{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "bedrock",
+					},
+					Spec: sambaoperatorv1alpha1.SmbShareSpec{
+						Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+							Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+								Name: pvc,
+							},
+						},
+						Scaling: &sambaoperatorv1alpha1.SmbShareScalingSpec{
+							GroupMode: "explicit",
+							Group:     "flintstones",
+						},
+					},
+					Status: sambaoperatorv1alpha1.SmbShareStatus{
+						ServerGroup: "flintstones",
+					},
+				},
+			},
+			nil)
+		planner.PrimaryVolume = "quarry"
+		return planner
+	}
+	vols := shareVolumesAndMounts([]*pln.Planner{
+		groupedPlanner("fred", "quarry"),
+		groupedPlanner("wilma", "home"),
+		groupedPlanner("pebbles", "home"),
+	})
+	if assert.Len(t, vols, 2) {
+		assert.Equal(t, "quarry-smb", vols[0].volume.Name)
+		assert.Equal(t, "/mnt/flintstones", vols[0].mount.MountPath)
+		assert.Equal(t, "home-smb", vols[1].volume.Name)
+		assert.Equal(t, "/mnt/flintstones-home", vols[1].mount.MountPath)
+	}
+	assert.NoError(t, newVolKeeper().extend(vols).validate())
+}
+
+func TestPodVolumesChanged(t *testing.T) {
+	podSpec := func(mountPath string) *corev1.PodSpec {
+		return &corev1.PodSpec{
+			Volumes: []corev1.Volume{{Name: "quarry-smb"}},
+			Containers: []corev1.Container{{
+				Name: "samba",
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "quarry-smb",
+					MountPath: mountPath,
+				}},
+			}},
+		}
+	}
+	current := podSpec("/mnt/flintstones/quarry")
+	assert.False(t, podVolumesChanged(current, podSpec("/mnt/flintstones/quarry")))
+	assert.True(t, podVolumesChanged(current, podSpec("/mnt/flintstones")))
+
+	// defaults filled in by the API server are ignored
+	defaulted := podSpec("/mnt/flintstones/quarry")
+	defaulted.Volumes[0].PersistentVolumeClaim =
+		&corev1.PersistentVolumeClaimVolumeSource{ClaimName: "quarry"}
+	assert.False(t, podVolumesChanged(current, defaulted))
+
+	desired := podSpec("/mnt/flintstones/quarry")
+	desired.Volumes = append(desired.Volumes, corev1.Volume{Name: "home-smb"})
+	assert.True(t, podVolumesChanged(current, desired))
+}
+
+func TestApplyPodVolumes(t *testing.T) {
+	current := &corev1.PodSpec{
+		ServiceAccountName: "samba",
+		Volumes: []corev1.Volume{
+			{
+				Name: "quarry-smb",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "quarry",
+					},
+				},
+			},
+			{Name: "cave-smb"},
+		},
+		Containers: []corev1.Container{{
+			Name:  "samba",
+			Image: "samba:custom",
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: "quarry-smb", MountPath: "/mnt/flintstones"},
+				{Name: "cave-smb", MountPath: "/mnt/flintstones-cave"},
+			},
+		}},
+	}
+	desired := &corev1.PodSpec{
+		Volumes: []corev1.Volume{{Name: "quarry-smb"}, {Name: "home-smb"}},
+		Containers: []corev1.Container{
+			{
+				Name: "samba",
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "quarry-smb", MountPath: "/mnt/flintstones"},
+					{Name: "home-smb", MountPath: "/mnt/flintstones-home"},
+				},
+			},
+			{Name: "other"},
+		},
+	}
+	applyPodVolumes(current, desired)
+	assert.False(t, podVolumesChanged(current, desired))
+	// other properties of the pod spec are retained
+	assert.Equal(t, "samba", current.ServiceAccountName)
+	assert.Len(t, current.Containers, 1)
+	assert.Equal(t, "samba:custom", current.Containers[0].Image)
+	if assert.Len(t, current.Volumes, 2) {
+		assert.NotNil(t, current.Volumes[0].PersistentVolumeClaim)
+		assert.Equal(t, "home-smb", current.Volumes[1].Name)
+	}
+}
+
+func TestClaimSpecForVolume(t *testing.T) {
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1"},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("10Gi"),
+			},
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteMany,
+			},
+		},
+	}
+	spec := claimSpecForVolume(pv)
+	assert.Equal(t, "pv1", spec.VolumeName)
+	if assert.NotNil(t, spec.StorageClassName) {
+		assert.Equal(t, "", *spec.StorageClassName)
+	}
+	assert.Equal(t, pv.Spec.AccessModes, spec.AccessModes)
+	size := spec.Resources.Requests[corev1.ResourceStorage]
+	assert.Equal(t, "10Gi", size.String())
+}
+
+func TestExpandPVC(t *testing.T) {
+	ctx := context.Background()
+	expandable := true
+	m := testingManager()
+	m.client.(*fakeClient).clientGet = func(
+		_ context.Context, _ types.NamespacedName, obj rtclient.Object) error {
+		// ---
+		if sc, ok := obj.(*storagev1.StorageClass); ok {
+			sc.AllowVolumeExpansion = &expandable
+		}
+		return nil
+	}
+	recorder := record.NewFakeRecorder(10)
+	m.recorder = recorder
+
+	scName := "fast"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	s := &sambaoperatorv1alpha1.SmbShare{
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+					Spec: pvc.Spec.DeepCopy(),
+				},
+			},
+		},
+	}
+	resizing := func() *metav1.Condition {
+		return meta.FindStatusCondition(
+			s.Status.Conditions, sambaoperatorv1alpha1.ShareConditionResizing)
+	}
+	setRequest := func(size string) {
+		s.Spec.Storage.Pvc.Spec.Resources.Requests[corev1.ResourceStorage] =
+			resource.MustParse(size)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Nil(t, resizing())
+		assert.Len(t, recorder.Events, 0)
+	})
+	t.Run("shrink", func(t *testing.T) {
+		setRequest("512Mi")
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Nil(t, resizing())
+		assert.Equal(t, "1Gi", pvc.Spec.Resources.Requests.Storage().String())
+	})
+	t.Run("notExpandable", func(t *testing.T) {
+		expandable = false
+		defer func() { expandable = true }()
+		setRequest("2Gi")
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Equal(t, ReasonExpansionNotSupported, resizing().Reason)
+		assert.Equal(t, metav1.ConditionFalse, resizing().Status)
+		assert.Equal(t, "1Gi", pvc.Spec.Resources.Requests.Storage().String())
+		assert.Len(t, recorder.Events, 1)
+		<-recorder.Events
+
+		// the event is not repeated
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Len(t, recorder.Events, 0)
+	})
+	t.Run("expand", func(t *testing.T) {
+		setRequest("2Gi")
+		assert.Equal(t, Requeue, m.expandPVC(ctx, s, pvc))
+		assert.Equal(t, "2Gi", pvc.Spec.Resources.Requests.Storage().String())
+		assert.Equal(t, ReasonExpandingVolume, resizing().Reason)
+		assert.Equal(t, metav1.ConditionTrue, resizing().Status)
+		assert.Len(t, recorder.Events, 1)
+		<-recorder.Events
+	})
+	t.Run("resizing", func(t *testing.T) {
+		pvc.Status.Conditions = []corev1.PersistentVolumeClaimCondition{{
+			Type:   corev1.PersistentVolumeClaimResizing,
+			Status: corev1.ConditionTrue,
+		}}
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Equal(t, ReasonVolumeResizing, resizing().Reason)
+		assert.Equal(t, metav1.ConditionTrue, resizing().Status)
+
+		pvc.Status.Conditions[0].Type =
+			corev1.PersistentVolumeClaimFileSystemResizePending
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Equal(t, ReasonFileSystemResizePending, resizing().Reason)
+		assert.Len(t, recorder.Events, 2)
+		<-recorder.Events
+		<-recorder.Events
+	})
+	t.Run("resized", func(t *testing.T) {
+		pvc.Status.Conditions = nil
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		// capacity has not been updated yet
+		assert.Equal(t, metav1.ConditionTrue, resizing().Status)
+
+		pvc.Status.Capacity[corev1.ResourceStorage] = resource.MustParse("2Gi")
+		assert.Equal(t, Done, m.expandPVC(ctx, s, pvc))
+		assert.Equal(t, ReasonVolumeResized, resizing().Reason)
+		assert.Equal(t, metav1.ConditionFalse, resizing().Status)
+		assert.Len(t, recorder.Events, 1)
+		<-recorder.Events
+	})
+}
+
+func TestRetainAndAdoptPVC(t *testing.T) {
+	ctx := context.Background()
+	m := testingManager()
+	recorder := record.NewFakeRecorder(10)
+	m.recorder = recorder
+
+	s := &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "accounting",
+			Namespace: "finance",
+			UID:       "abc123",
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+					Spec: &corev1.PersistentVolumeClaimSpec{},
+				},
+			},
+		},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "accounting-pvc",
+			Namespace: "finance",
+		},
+	}
+	assert.NoError(t,
+		controllerutil.SetControllerReference(s, pvc, m.scheme))
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, sambaoperatorv1alpha1.PvcReclaimDelete, reclaimPolicy(s))
+		assert.Equal(t, Done, m.retainPVC(ctx, s, pvc))
+		assert.True(t, metav1.IsControlledBy(pvc, s))
+		assert.False(t, isOrphaned(pvc))
+	})
+	t.Run("retain", func(t *testing.T) {
+		s.Spec.Storage.Pvc.ReclaimPolicy = sambaoperatorv1alpha1.PvcReclaimRetain
+		assert.Equal(t, Requeue, m.retainPVC(ctx, s, pvc))
+		assert.Len(t, pvc.OwnerReferences, 0)
+		assert.True(t, isOrphaned(pvc))
+		assert.Equal(t, "accounting", pvc.Labels[orphanedFromLabel])
+		assert.Len(t, recorder.Events, 1)
+		<-recorder.Events
+
+		// nothing left to do for the next pass of the finalizer
+		assert.Equal(t, Done, m.retainPVC(ctx, s, pvc))
+	})
+	t.Run("adopt", func(t *testing.T) {
+		s2 := s.DeepCopy()
+		s2.Name = "accounting2"
+		s2.UID = "def456"
+		assert.Equal(t, Requeue, m.adoptPVC(ctx, s2, pvc))
+		assert.True(t, metav1.IsControlledBy(pvc, s2))
+		assert.False(t, isOrphaned(pvc))
+		assert.NotContains(t, pvc.Labels, orphanedFromLabel)
+		assert.Len(t, recorder.Events, 1)
+		<-recorder.Events
+	})
+}
@@ -24,6 +24,18 @@ func (*fakeLogger) Info(string, ...interface{}) {
 func (*fakeLogger) Error(error, string, ...interface{}) {
 }
 
+// fakeExecer runs the exec function in place of a command in a pod.
+type fakeExecer struct {
+	exec func(pod, container string, cmd []string) ([]byte, error)
+}
+
+func (e *fakeExecer) Exec(
+	_ context.Context,
+	_, pod, container string,
+	cmd []string) ([]byte, error) {
+	return e.exec(pod, container, cmd)
+}
+
 // fakeClient does nothing. It just fits in the hole we call the controller
 // runtime client interface.  You can use it directly or reuse it as a base for
 // your own test cases.
@@ -31,7 +43,8 @@ type fakeClient struct {
 	scheme *runtime.Scheme
 
 	// mockable functions
-	clientGet func(context.Context, types.NamespacedName, rtclient.Object) error
+	clientGet    func(context.Context, types.NamespacedName, rtclient.Object) error
+	clientDelete func(context.Context, rtclient.Object) error
 }
 
 func (*fakeClient) Create(
@@ -48,10 +61,13 @@ func (*fakeClient) Update(
 	return nil
 }
 
-func (*fakeClient) Delete(
-	_ context.Context,
-	_ rtclient.Object,
+func (c *fakeClient) Delete(
+	ctx context.Context,
+	obj rtclient.Object,
 	_ ...rtclient.DeleteOption) error {
+	if c.clientDelete != nil {
+		return c.clientDelete(ctx, obj)
+	}
 	return nil
 }
 
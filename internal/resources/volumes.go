@@ -17,6 +17,7 @@ package resources
 
 import (
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -29,6 +30,7 @@ const (
 	stateVolName      = "samba-state-dir"
 	osRunVolName      = "run"
 	joinJSONVolName   = "join-data"
+	auditVolName      = "samba-audit"
 )
 
 type volMountTag uint
@@ -145,22 +147,82 @@ func (vk *volKeeper) exclude(t volMountTag) *volKeeper {
 	return vk2
 }
 
-func shareVolumeAndMount(planner *pln.Planner, pvcName string) volMount {
+func shareVolumeAndMount(planner *pln.Planner) volMount {
 	var vmnt volMount
 	// volume
-	pvcVolName := pvcName + "-smb"
+	volName := planner.VolumeKey() + "-smb"
+	storage := planner.SmbShare.Spec.Storage
+	src := corev1.VolumeSource{}
+	switch planner.StorageKind() {
+	case pln.StorageEphemeral:
+		src.Ephemeral = &corev1.EphemeralVolumeSource{
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+				Spec: *storage.Ephemeral.Spec.DeepCopy(),
+			},
+		}
+	case pln.StorageCSI:
+		src.CSI = storage.CSI.CSIVolumeSource.DeepCopy()
+	case pln.StorageNFS:
+		src.NFS = &corev1.NFSVolumeSource{
+			Server: storage.NFS.Server,
+			Path:   storage.NFS.Path,
+		}
+	case pln.StorageNone, pln.StoragePVC, pln.StoragePersistentVolume:
+		src.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: dataPVCName(planner),
+		}
+	}
 	vmnt.volume = corev1.Volume{
-		Name: pvcVolName,
+		Name:         volName,
+		VolumeSource: src,
+	}
+	// mount
+	vmnt.mount = corev1.VolumeMount{
+		MountPath: planner.Paths().ShareMountPath(),
+		Name:      volName,
+	}
+	vmnt.tag = tagData
+	return vmnt
+}
+
+// shareVolumesAndMounts returns the volumes holding the data of the shares
+// of the given planners. Shares backed by the same volume share a volMount.
+func shareVolumesAndMounts(planners []*pln.Planner) []volMount {
+	vols := []volMount{}
+	seen := map[string]bool{}
+	for _, planner := range planners {
+		vmnt := shareVolumeAndMount(planner)
+		if seen[vmnt.volume.Name] {
+			continue
+		}
+		seen[vmnt.volume.Name] = true
+		vols = append(vols, vmnt)
+	}
+	return vols
+}
+
+// snapshotVolumeAndMount returns the volume of a PVC restored from a
+// snapshot of the share's volume. The volume is mounted read-only in the
+// directory vfs_shadow_copy2 expects the snapshot taken at the given time.
+func snapshotVolumeAndMount(
+	planner *pln.Planner, pvcName string, taken time.Time) volMount {
+	// ---
+	var vmnt volMount
+	// volume
+	vmnt.volume = corev1.Volume{
+		Name: pvcName,
 		VolumeSource: corev1.VolumeSource{
 			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
 				ClaimName: pvcName,
+				ReadOnly:  true,
 			},
 		},
 	}
 	// mount
 	vmnt.mount = corev1.VolumeMount{
-		MountPath: planner.Paths().ShareMountPath(),
-		Name:      pvcVolName,
+		MountPath: planner.Paths().SnapshotDir(taken),
+		Name:      pvcName,
+		ReadOnly:  true,
 	}
 	vmnt.tag = tagData
 	return vmnt
@@ -272,19 +334,30 @@ func wbSocketsVolumeAndMount(planner *pln.Planner) volMount {
 	return vmnt
 }
 
-func joinJSONFileVolumeAndMount(planner *pln.Planner, index int) volMount {
+func joinSourceVolumeAndMount(planner *pln.Planner, index int) volMount {
 	var vmnt volMount
 	// volume
 	vname := joinJSONVolumeSuffix(joinJSONVolName, index)
-	j := planner.SecurityConfig.Spec.JoinSources[index]
+	var secret, key, fname string
+	switch j := planner.SecurityConfig.Spec.JoinSources[index]; {
+	case j.KeytabJoin != nil:
+		secret, key = j.KeytabJoin.Secret, j.KeytabJoin.Key
+		fname = planner.Paths().JoinKeytabBaseName()
+	case j.ComputerAccountJoin != nil:
+		secret, key = j.ComputerAccountJoin.Secret, j.ComputerAccountJoin.Key
+		fname = planner.Paths().JoinPasswordBaseName()
+	default:
+		secret, key = j.UserJoin.Secret, j.UserJoin.Key
+		fname = planner.Paths().JoinJSONBaseName()
+	}
 	vmnt.volume = corev1.Volume{
 		Name: vname,
 		VolumeSource: corev1.VolumeSource{
 			Secret: &corev1.SecretVolumeSource{
-				SecretName: j.UserJoin.Secret,
+				SecretName: secret,
 				Items: []corev1.KeyToPath{{
-					Key:  j.UserJoin.Key,
-					Path: planner.Paths().JoinJSONBaseName(),
+					Key:  key,
+					Path: fname,
 				}},
 			},
 		},
@@ -319,6 +392,26 @@ func svcWatchVolumeAndMount(dir string) volMount {
 	return vmnt
 }
 
+func auditVolumeAndMount(planner *pln.Planner) volMount {
+	var vmnt volMount
+	// volume
+	vmnt.volume = corev1.Volume{
+		Name: auditVolName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				Medium: corev1.StorageMediumMemory,
+			},
+		},
+	}
+	// mount
+	vmnt.mount = corev1.VolumeMount{
+		MountPath: planner.Paths().AuditDir(),
+		Name:      auditVolName,
+	}
+	vmnt.tag = tagMeta
+	return vmnt
+}
+
 func ctdbConfigVolumeAndMount(_ *pln.Planner) volMount {
 	var vmnt volMount
 	name := "ctdb-config"
@@ -15,7 +15,11 @@ limitations under the License.
 
 package smbcc
 
-import "strconv"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
 
 // Key values are used to select subsections in the container config.
 type Key string
@@ -111,10 +115,25 @@ const (
 	// users and groups.
 	AllEntriesKey = Key("all_entries")
 
+	// PathParam is the path of the directory to be shared.
+	PathParam = "path"
+	// VFSObjectsParam lists the vfs modules loaded for a share.
+	VFSObjectsParam = "vfs objects"
 	// BrowseableParam controls if a share is browseable.
 	BrowseableParam = "browseable"
 	// ReadOnlyParam controls if a share is read only.
 	ReadOnlyParam = "read only"
+	// ValidUsersParam lists the users allowed to connect to a share.
+	ValidUsersParam = "valid users"
+	// InvalidUsersParam lists the users never allowed to connect to a share.
+	InvalidUsersParam = "invalid users"
+	// AdminUsersParam lists the users with administrative privileges on a
+	// share.
+	AdminUsersParam = "admin users"
+	// ReadListParam lists the users given read-only access to a share.
+	ReadListParam = "read list"
+	// WriteListParam lists the users given read-write access to a share.
+	WriteListParam = "write list"
 
 	// Yes means yes.
 	Yes = "yes"
@@ -154,11 +173,11 @@ func NewGlobals(opts GlobalOptions) GlobalConfig {
 	}
 
 	if opts.AddVFSFileid {
-		_, found := cfg.Options["vfs objects"]
+		_, found := cfg.Options[VFSObjectsParam]
 		if found {
-			cfg.Options["vfs objects"] += " fileid"
+			cfg.Options[VFSObjectsParam] += " fileid"
 		} else {
-			cfg.Options["vfs objects"] = "fileid"
+			cfg.Options[VFSObjectsParam] = "fileid"
 		}
 		cfg.Options["fileid:algorithm"] = "fsid"
 	}
@@ -170,8 +189,8 @@ func NewGlobals(opts GlobalOptions) GlobalConfig {
 func NewSimpleShare(path string) ShareConfig {
 	return ShareConfig{
 		Options: SmbOptions{
-			"path":      path,
-			"read only": No,
+			PathParam:     path,
+			ReadOnlyParam: No,
 		},
 	}
 }
@@ -204,3 +223,37 @@ func NewDefaultUsers() map[Key]UserEntries {
 		}},
 	}
 }
+
+// ParseUsers parses the JSON of a users and groups configuration, like
+// the one stored in the users secret of an SmbSecurityConfig.
+func ParseUsers(data []byte) (*SambaContainerConfig, error) {
+	scc := &SambaContainerConfig{}
+	if err := json.Unmarshal(data, scc); err != nil {
+		return nil, err
+	}
+	if scc.SCCVersion != version0 {
+		return nil, fmt.Errorf(
+			"unsupported samba-container-config version: %q", scc.SCCVersion)
+	}
+	return scc, nil
+}
+
+// HasUser returns true if the named user is defined.
+func (scc *SambaContainerConfig) HasUser(name string) bool {
+	for _, u := range scc.Users[AllEntriesKey] {
+		if u.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGroup returns true if the named group is defined.
+func (scc *SambaContainerConfig) HasGroup(name string) bool {
+	for _, g := range scc.Groups[AllEntriesKey] {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -110,3 +110,35 @@ func TestMarshal(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, scc, scc2)
 }
+
+const usersJSON = `
+{
+  "samba-container-config": "v0",
+  "users": {
+    "all_entries": [
+      {"name": "alice", "password": "wond3r1and"},
+      {"name": "bob", "password": "r0b0t"}
+    ]
+  },
+  "groups": {
+    "all_entries": [
+      {"name": "staff"}
+    ]
+  }
+}
+`
+
+func TestParseUsers(t *testing.T) {
+	scc, err := ParseUsers([]byte(usersJSON))
+	require.NoError(t, err)
+	require.True(t, scc.HasUser("alice"))
+	require.True(t, scc.HasUser("bob"))
+	require.False(t, scc.HasUser("carol"))
+	require.True(t, scc.HasGroup("staff"))
+	require.False(t, scc.HasGroup("alice"))
+
+	_, err = ParseUsers([]byte(`{"users": {}}`))
+	require.Error(t, err)
+	_, err = ParseUsers([]byte(`{"samba-container-config": "v0",`))
+	require.Error(t, err)
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+// revive:disable:line-length-limit kubebuilder markers
+
+// +kubebuilder:webhook:path=/mutate-samba-operator-samba-org-v1alpha1-smbshare,mutating=true,failurePolicy=fail,sideEffects=None,groups=samba-operator.samba.org,resources=smbshares,verbs=create,versions=v1alpha1,name=msmbshare.samba-operator.samba.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-samba-operator-samba-org-v1alpha1-smbcommonconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=samba-operator.samba.org,resources=smbcommonconfigs,verbs=create,versions=v1alpha1,name=msmbcommonconfig.samba-operator.samba.org,admissionReviewVersions=v1
+
+// revive:enable:line-length-limit
+
+// SmbShareDefaulter fills in the values the operator would otherwise
+// derive at run time when an SmbShare is created. Recording the values in
+// the resource makes them visible to the user and keeps later changes to
+// the operator's defaults from altering existing shares.
+type SmbShareDefaulter struct {
+	Config *conf.OperatorConfig
+}
+
+// Default sets the defaults of a new SmbShare.
+func (d *SmbShareDefaulter) Default(
+	_ context.Context, obj runtime.Object) error {
+	// ---
+	s, ok := obj.(*sambaoperatorv1alpha1.SmbShare)
+	if !ok {
+		return fmt.Errorf("expected an SmbShare but got %T", obj)
+	}
+	defaultSmbShare(s, d.Config)
+	return nil
+}
+
+func defaultSmbShare(
+	s *sambaoperatorv1alpha1.SmbShare,
+	cfg *conf.OperatorConfig) {
+	// ---
+	planner := pln.New(pln.InstanceConfiguration{SmbShare: s}, nil)
+	if s.Spec.ShareName == "" {
+		s.Spec.ShareName = planner.ShareName()
+	}
+	if s.Spec.SecurityConfig == "" && cfg != nil {
+		s.Spec.SecurityConfig = cfg.DefaultSecurityConfig
+	}
+	if s.Spec.CommonConfig == "" && cfg != nil {
+		s.Spec.CommonConfig = cfg.DefaultCommonConfig
+	}
+	if s.Spec.Storage.Pvc != nil && s.Spec.Storage.Pvc.Name == "" {
+		s.Spec.Storage.Pvc.Name = planner.PVCName()
+	}
+
+	if s.Spec.Scaling == nil {
+		s.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{}
+	}
+	scaling := s.Spec.Scaling
+	if scaling.AvailabilityMode == "" {
+		scaling.AvailabilityMode = "standard"
+	}
+	if scaling.GroupMode == "" {
+		scaling.GroupMode = string(pln.GroupModeNever)
+	}
+	if scaling.AvailabilityMode == "clustered" && scaling.MinClusterSize == 0 {
+		scaling.MinClusterSize = 1
+	}
+}
+
+// SmbCommonConfigDefaulter fills in the pod settings the operator would
+// otherwise fall back to when an SmbCommonConfig is created.
+type SmbCommonConfigDefaulter struct {
+	Config *conf.OperatorConfig
+}
+
+// Default sets the defaults of a new SmbCommonConfig.
+func (d *SmbCommonConfigDefaulter) Default(
+	_ context.Context, obj runtime.Object) error {
+	// ---
+	c, ok := obj.(*sambaoperatorv1alpha1.SmbCommonConfig)
+	if !ok {
+		return fmt.Errorf("expected an SmbCommonConfig but got %T", obj)
+	}
+	return defaultSmbCommonConfig(c, d.Config)
+}
+
+func defaultSmbCommonConfig(
+	c *sambaoperatorv1alpha1.SmbCommonConfig,
+	cfg *conf.OperatorConfig) error {
+	// ---
+	if cfg == nil {
+		return nil
+	}
+	if c.Spec.PodSettings == nil {
+		c.Spec.PodSettings = &sambaoperatorv1alpha1.SmbCommonConfigPodSettings{}
+	}
+	ps := c.Spec.PodSettings
+	if len(ps.NodeSelector) == 0 {
+		planner := pln.New(pln.InstanceConfiguration{GlobalConfig: cfg}, nil)
+		nsel, err := planner.NodeSelector()
+		if err != nil {
+			return err
+		}
+		ps.NodeSelector = map[string]string{}
+		for k, v := range nsel {
+			ps.NodeSelector[k] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+)
+
+func TestDefaultSmbShare(t *testing.T) {
+	d := &SmbShareDefaulter{
+		Config: &conf.OperatorConfig{
+			DefaultSecurityConfig: "sec1",
+		},
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		s := sampleShare("fred")
+		s.Spec.Storage.Pvc.Name = ""
+		err := d.Default(context.TODO(), s)
+		assert.NoError(t, err)
+		assert.Equal(t, "fred", s.Spec.ShareName)
+		assert.Equal(t, "sec1", s.Spec.SecurityConfig)
+		assert.Equal(t, "", s.Spec.CommonConfig)
+		assert.Equal(t, "fred-pvc", s.Spec.Storage.Pvc.Name)
+		if assert.NotNil(t, s.Spec.Scaling) {
+			assert.Equal(t, "standard", s.Spec.Scaling.AvailabilityMode)
+			assert.Equal(t, "never", s.Spec.Scaling.GroupMode)
+			// the cluster size only applies to clustered shares
+			assert.Equal(t, 0, s.Spec.Scaling.MinClusterSize)
+		}
+	})
+
+	t.Run("clustered", func(t *testing.T) {
+		s := sampleShare("fred")
+		s.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+			AvailabilityMode: "clustered",
+		}
+		err := d.Default(context.TODO(), s)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, s.Spec.Scaling.MinClusterSize)
+	})
+
+	t.Run("keepValues", func(t *testing.T) {
+		s := groupedShare("fred", "flintstones")
+		s.Spec.ShareName = "Rubble"
+		s.Spec.SecurityConfig = "sec2"
+		s.Spec.CommonConfig = "common2"
+		s.Spec.Scaling.AvailabilityMode = "clustered"
+		s.Spec.Scaling.MinClusterSize = 3
+		err := d.Default(context.TODO(), s)
+		assert.NoError(t, err)
+		assert.Equal(t, "Rubble", s.Spec.ShareName)
+		assert.Equal(t, "sec2", s.Spec.SecurityConfig)
+		assert.Equal(t, "common2", s.Spec.CommonConfig)
+		assert.Equal(t, "quarry", s.Spec.Storage.Pvc.Name)
+		assert.Equal(t, "clustered", s.Spec.Scaling.AvailabilityMode)
+		assert.Equal(t, "explicit", s.Spec.Scaling.GroupMode)
+		assert.Equal(t, "flintstones", s.Spec.Scaling.Group)
+		assert.Equal(t, 3, s.Spec.Scaling.MinClusterSize)
+	})
+
+	t.Run("noPvc", func(t *testing.T) {
+		s := sampleShare("fred")
+		s.Spec.Storage.Pvc = nil
+		err := d.Default(context.TODO(), s)
+		assert.NoError(t, err)
+		assert.Nil(t, s.Spec.Storage.Pvc)
+	})
+
+	t.Run("wrongType", func(t *testing.T) {
+		err := d.Default(context.TODO(), &sambaoperatorv1alpha1.SmbCommonConfig{})
+		assert.Error(t, err)
+	})
+}
+
+func TestDefaultSmbCommonConfig(t *testing.T) {
+	t.Run("builtin", func(t *testing.T) {
+		d := &SmbCommonConfigDefaulter{Config: &conf.OperatorConfig{}}
+		c := &sambaoperatorv1alpha1.SmbCommonConfig{}
+		err := d.Default(context.TODO(), c)
+		assert.NoError(t, err)
+		if assert.NotNil(t, c.Spec.PodSettings) {
+			assert.Equal(t, "linux",
+				c.Spec.PodSettings.NodeSelector["kubernetes.io/os"])
+		}
+	})
+
+	t.Run("operatorDefault", func(t *testing.T) {
+		d := &SmbCommonConfigDefaulter{Config: &conf.OperatorConfig{
+			DefaultNodeSelector: `{"samba": "yes"}`,
+		}}
+		c := &sambaoperatorv1alpha1.SmbCommonConfig{}
+		err := d.Default(context.TODO(), c)
+		assert.NoError(t, err)
+		assert.Equal(t,
+			map[string]string{"samba": "yes"},
+			c.Spec.PodSettings.NodeSelector)
+	})
+
+	t.Run("keepValues", func(t *testing.T) {
+		d := &SmbCommonConfigDefaulter{Config: &conf.OperatorConfig{}}
+		c := &sambaoperatorv1alpha1.SmbCommonConfig{
+			Spec: sambaoperatorv1alpha1.SmbCommonConfigSpec{
+				PodSettings: &sambaoperatorv1alpha1.SmbCommonConfigPodSettings{
+					NodeSelector: map[string]string{"zone": "a"},
+				},
+			},
+		}
+		err := d.Default(context.TODO(), c)
+		assert.NoError(t, err)
+		assert.Equal(t,
+			map[string]string{"zone": "a"},
+			c.Spec.PodSettings.NodeSelector)
+	})
+
+	t.Run("wrongType", func(t *testing.T) {
+		d := &SmbCommonConfigDefaulter{Config: &conf.OperatorConfig{}}
+		err := d.Default(context.TODO(), &sambaoperatorv1alpha1.SmbShare{})
+		assert.Error(t, err)
+	})
+}
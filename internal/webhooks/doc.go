@@ -0,0 +1,3 @@
+// Package webhooks implements the admission and conversion webhooks of the
+// operator.
+package webhooks
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+// +kubebuilder:webhook:path=/validate-samba-operator-samba-org-v1alpha1-smbcommonconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=samba-operator.samba.org,resources=smbcommonconfigs,verbs=create;update,versions=v1alpha1,name=vsmbcommonconfig.samba-operator.samba.org,admissionReviewVersions=v1
+
+// SmbCommonConfigValidator validates SmbCommonConfig resources.
+type SmbCommonConfigValidator struct{}
+
+// ValidateCreate validates a new SmbCommonConfig.
+func (*SmbCommonConfigValidator) ValidateCreate(
+	_ context.Context, obj runtime.Object) error {
+	// ---
+	cc, ok := obj.(*sambaoperatorv1alpha1.SmbCommonConfig)
+	if !ok {
+		return fmt.Errorf("expected an SmbCommonConfig but got %T", obj)
+	}
+	return invalid(cc, validateSmbCommonConfigSpec(cc))
+}
+
+// ValidateUpdate validates changes to an SmbCommonConfig.
+func (*SmbCommonConfigValidator) ValidateUpdate(
+	_ context.Context, _, newObj runtime.Object) error {
+	// ---
+	cc, ok := newObj.(*sambaoperatorv1alpha1.SmbCommonConfig)
+	if !ok {
+		return fmt.Errorf("expected an SmbCommonConfig but got %T", newObj)
+	}
+	return invalid(cc, validateSmbCommonConfigSpec(cc))
+}
+
+// ValidateDelete validates the deletion of an SmbCommonConfig.
+func (*SmbCommonConfigValidator) ValidateDelete(
+	_ context.Context, _ runtime.Object) error {
+	// ---
+	return nil
+}
+
+func validateSmbCommonConfigSpec(
+	cc *sambaoperatorv1alpha1.SmbCommonConfig) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	gc := cc.Spec.CustomGlobalConfig
+	if gc != nil && len(gc.Configs) > 0 && !gc.UseUnsafeCustomConfig {
+		errs = append(errs, field.Invalid(
+			field.NewPath("spec", "customGlobalConfig", "useUnsafeCustomConfig"),
+			gc.UseUnsafeCustomConfig,
+			"must be set to true for custom configs to be applied"))
+	}
+	if sec := cc.Spec.Security; sec != nil {
+		err := pln.ValidateProtocolRange(sec.MinProtocol, sec.MaxProtocol)
+		if err != nil {
+			errs = append(errs, field.Invalid(
+				field.NewPath("spec", "security", "minProtocol"),
+				sec.MinProtocol, err.Error()))
+		}
+	}
+	return errs
+}
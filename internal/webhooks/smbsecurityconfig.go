@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+// +kubebuilder:webhook:path=/validate-samba-operator-samba-org-v1alpha1-smbsecurityconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=samba-operator.samba.org,resources=smbsecurityconfigs,verbs=create;update,versions=v1alpha1,name=vsmbsecurityconfig.samba-operator.samba.org,admissionReviewVersions=v1
+
+// SmbSecurityConfigValidator validates SmbSecurityConfig resources.
+type SmbSecurityConfigValidator struct {
+	// Client is used to look up the shares using the config.
+	Client rtclient.Client
+}
+
+// ValidateCreate validates a new SmbSecurityConfig.
+func (v *SmbSecurityConfigValidator) ValidateCreate(
+	ctx context.Context, obj runtime.Object) error {
+	// ---
+	sc, ok := obj.(*sambaoperatorv1alpha1.SmbSecurityConfig)
+	if !ok {
+		return fmt.Errorf("expected an SmbSecurityConfig but got %T", obj)
+	}
+	errs := validateSmbSecurityConfigSpec(sc)
+	if len(errs) == 0 {
+		// shares may refer to the config before it is created
+		errs = append(errs, v.validateComputerAccount(ctx, sc)...)
+	}
+	return invalid(sc, errs)
+}
+
+// ValidateUpdate validates changes to an SmbSecurityConfig.
+func (v *SmbSecurityConfigValidator) ValidateUpdate(
+	ctx context.Context, oldObj, newObj runtime.Object) error {
+	// ---
+	sc, ok := newObj.(*sambaoperatorv1alpha1.SmbSecurityConfig)
+	if !ok {
+		return fmt.Errorf("expected an SmbSecurityConfig but got %T", newObj)
+	}
+	old, ok := oldObj.(*sambaoperatorv1alpha1.SmbSecurityConfig)
+	if !ok {
+		return fmt.Errorf("expected an SmbSecurityConfig but got %T", oldObj)
+	}
+	errs := validateSmbSecurityConfigSpec(sc)
+	spec := field.NewPath("spec")
+	// servers are set up for (and joined to) a particular domain
+	if sc.Spec.Mode != old.Spec.Mode {
+		errs = append(errs, field.Forbidden(
+			spec.Child("mode"), "field is immutable"))
+	}
+	if sc.Spec.Realm != old.Spec.Realm {
+		errs = append(errs, field.Forbidden(
+			spec.Child("realm"), "field is immutable"))
+	}
+	if len(errs) == 0 && computerAccountName(sc) != computerAccountName(old) {
+		errs = append(errs, v.validateComputerAccount(ctx, sc)...)
+	}
+	changed := pln.ChangedIDMapDomains(
+		sc.Spec.Domains, old.Status.IDMapRanges)
+	if len(changed) > 0 &&
+		sc.Annotations[sambaoperatorv1alpha1.ForceIDMapChangeAnnotation] != "true" {
+		// changing a range changes the IDs of the users and groups of
+		// the domain, and so the owners of the files of the shares
+		inUse, err := v.inUse(ctx, sc)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			errs = append(errs, field.Forbidden(
+				spec.Child("domains"),
+				fmt.Sprintf("the idmap ranges of domains %v may not be"+
+					" changed while in use by SmbShares,"+
+					" unless annotation %s is set to \"true\"",
+					changed,
+					sambaoperatorv1alpha1.ForceIDMapChangeAnnotation)))
+		}
+	}
+	return invalid(sc, errs)
+}
+
+// ValidateDelete validates the deletion of an SmbSecurityConfig.
+func (*SmbSecurityConfigValidator) ValidateDelete(
+	_ context.Context, _ runtime.Object) error {
+	// ---
+	return nil
+}
+
+func validateSmbSecurityConfigSpec(
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	switch pln.SecurityMode(sc.Spec.Mode) {
+	case pln.UserMode:
+		if sc.Spec.Realm != "" {
+			errs = append(errs, field.Invalid(
+				spec.Child("realm"), sc.Spec.Realm,
+				"a realm may not be specified in user mode"))
+		}
+		if sc.Spec.Workgroup != "" {
+			errs = append(errs, field.Invalid(
+				spec.Child("workgroup"), sc.Spec.Workgroup,
+				"a workgroup may not be specified in user mode"))
+		}
+		if len(sc.Spec.JoinSources) > 0 {
+			errs = append(errs, field.Forbidden(
+				spec.Child("joinSources"),
+				"join sources may not be specified in user mode"))
+		}
+		if sc.Spec.JoinOptions != nil {
+			errs = append(errs, field.Forbidden(
+				spec.Child("joinOptions"),
+				"join options may not be specified in user mode"))
+		}
+	case pln.ADMode:
+		if sc.Spec.Realm == "" {
+			errs = append(errs, field.Required(
+				spec.Child("realm"),
+				"a realm is required in active-directory mode"))
+		}
+		for i, js := range sc.Spec.JoinSources {
+			switch joinSourceTypes(js) {
+			case 0:
+				errs = append(errs, field.Required(
+					spec.Child("joinSources").Index(i),
+					"a join source must be specified"))
+			case 1:
+			default:
+				errs = append(errs, field.Forbidden(
+					spec.Child("joinSources").Index(i),
+					"only one type of join source may be specified"))
+			}
+		}
+	}
+	if err := pln.ValidateIDMapDomains(sc.Spec.Domains); err != nil {
+		errs = append(errs, field.Invalid(
+			spec.Child("domains"), len(sc.Spec.Domains), err.Error()))
+	}
+	return errs
+}
+
+// joinSourceTypes returns the number of join source types specified.
+func joinSourceTypes(js sambaoperatorv1alpha1.SmbSecurityJoinSpec) int {
+	n := 0
+	if js.UserJoin != nil {
+		n++
+	}
+	if js.KeytabJoin != nil {
+		n++
+	}
+	if js.ComputerAccountJoin != nil {
+		n++
+	}
+	return n
+}
+
+// validateComputerAccount checks that the computer account name of the
+// security config is not used by more than one server group of the shares
+// using the config.
+func (v *SmbSecurityConfigValidator) validateComputerAccount(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	name := computerAccountName(sc)
+	if name == "" || v.Client == nil {
+		return errs
+	}
+	l := &sambaoperatorv1alpha1.SmbShareList{}
+	if err := v.Client.List(ctx, l, rtclient.InNamespace(sc.Namespace)); err != nil {
+		return append(errs, field.InternalError(field.NewPath("spec"), err))
+	}
+	if err := pln.ValidateComputerAccount(sc, l.Items); err != nil {
+		errs = append(errs, field.Invalid(
+			field.NewPath("spec", "joinOptions", "computerAccountName"),
+			name, err.Error()))
+	}
+	return errs
+}
+
+func computerAccountName(sc *sambaoperatorv1alpha1.SmbSecurityConfig) string {
+	if sc.Spec.JoinOptions == nil {
+		return ""
+	}
+	return sc.Spec.JoinOptions.ComputerAccountName
+}
+
+// inUse returns true if any SmbShare refers to the security config. If
+// the shares can not be looked up the config is assumed to be in use.
+func (v *SmbSecurityConfigValidator) inUse(
+	ctx context.Context,
+	sc *sambaoperatorv1alpha1.SmbSecurityConfig) (bool, error) {
+	// ---
+	if v.Client == nil {
+		return true, nil
+	}
+	l := &sambaoperatorv1alpha1.SmbShareList{}
+	if err := v.Client.List(ctx, l, rtclient.InNamespace(sc.Namespace)); err != nil {
+		return false, err
+	}
+	for _, s := range l.Items {
+		if s.Spec.SecurityConfig == sc.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+)
+
+func TestValidateSmbSecurityConfig(t *testing.T) {
+	ctx := context.TODO()
+	v := &SmbSecurityConfigValidator{}
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+			JoinSources: []sambaoperatorv1alpha1.SmbSecurityJoinSpec{{
+				UserJoin: &sambaoperatorv1alpha1.SmbSecurityUserJoinSpec{
+					Secret: "join1",
+				},
+			}},
+		},
+	}
+	assert.NoError(t, v.ValidateCreate(ctx, sc))
+
+	bad := sc.DeepCopy()
+	bad.Spec.Realm = ""
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.Mode = "user"
+	bad.Spec.Realm = ""
+	bad.Spec.JoinSources = nil
+	bad.Spec.Workgroup = "BEDROCK"
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.JoinSources = append(bad.Spec.JoinSources,
+		sambaoperatorv1alpha1.SmbSecurityJoinSpec{})
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.JoinSources[0].KeytabJoin =
+		&sambaoperatorv1alpha1.SmbSecurityKeytabJoinSpec{Secret: "keytab1"}
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+	bad.Spec.JoinSources[0].UserJoin = nil
+	assert.NoError(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.JoinSources = append(bad.Spec.JoinSources,
+		sambaoperatorv1alpha1.SmbSecurityJoinSpec{
+			ComputerAccountJoin: &sambaoperatorv1alpha1.SmbSecurityComputerAccountJoinSpec{
+				Secret: "otp1",
+			},
+		})
+	bad.Spec.JoinOptions = &sambaoperatorv1alpha1.SmbSecurityJoinOptionsSpec{
+		OrganizationalUnit:  "Computers/Samba",
+		ComputerAccountName: "FILES1",
+	}
+	assert.NoError(t, v.ValidateCreate(ctx, bad))
+	bad.Spec.Mode = "user"
+	bad.Spec.Realm = ""
+	bad.Spec.JoinSources = nil
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.Domains = []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "BEDROCK", Backend: "autorid"},
+		{Name: "BEDROCK", Backend: "ad-rfc2307"},
+	}
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.Domains = []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+		{Name: "BEDROCK", Backend: "ad-rfc2307", RangeStart: 100000},
+	}
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+	bad.Spec.Domains[0].RangeSize = 50000
+	assert.NoError(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.Realm = "quarry.example.com"
+	assert.Error(t, v.ValidateUpdate(ctx, sc, bad))
+}
+
+func TestValidateSmbSecurityConfigIDMap(t *testing.T) {
+	ctx := context.TODO()
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+			Domains: []sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+				{Name: "BEDROCK", Backend: "ad", UnixNSSInfo: true},
+				{Name: "QUARRY", Backend: "rid"},
+			},
+		},
+	}
+	v := &SmbSecurityConfigValidator{}
+	assert.NoError(t, v.ValidateCreate(ctx, sc))
+
+	bad := sc.DeepCopy()
+	bad.Spec.Domains[1].UnixPrimaryGroup = true
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	bad = sc.DeepCopy()
+	bad.Spec.Domains[1].RangeStart = 5000
+	bad.Spec.Domains[1].RangeSize = 1000
+	assert.NoError(t, v.ValidateCreate(ctx, bad))
+	bad.Spec.Domains[0].RangeStart = 5500
+	bad.Spec.Domains[0].RangeSize = 1000
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	// autorid and tdb only map the default domain, which they must map
+	bad = sc.DeepCopy()
+	bad.Spec.Domains[1].Backend = "autorid"
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+	bad.Spec.Domains[1].Name = "*"
+	assert.NoError(t, v.ValidateCreate(ctx, bad))
+	bad.Spec.Domains[1].Backend = "rid"
+	assert.Error(t, v.ValidateCreate(ctx, bad))
+
+	sc.Status.IDMapRanges = pln.RecordIDMapRanges(
+		pln.IDMapRanges(sc.Spec.Domains, nil))
+	scheme := runtime.NewScheme()
+	_ = sambaoperatorv1alpha1.AddToScheme(scheme)
+	share := sampleShare("fred")
+	share.Spec.SecurityConfig = "addc"
+	v.Client = fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(share).
+		Build()
+
+	// reordering the domains keeps the recorded ranges
+	reordered := sc.DeepCopy()
+	reordered.Spec.Domains[0], reordered.Spec.Domains[1] =
+		reordered.Spec.Domains[1], reordered.Spec.Domains[0]
+	assert.NoError(t, v.ValidateUpdate(ctx, sc, reordered))
+
+	// the ranges are kept when only the backend changes
+	changed := sc.DeepCopy()
+	changed.Spec.Domains[1].Backend = "nss"
+	assert.NoError(t, v.ValidateUpdate(ctx, sc, changed))
+
+	// new domains do not change the recorded ranges
+	changed.Spec.Domains = append(changed.Spec.Domains,
+		sambaoperatorv1alpha1.SmbSecurityDomainSpec{
+			Name: "GRAVEL", Backend: "rid", RangeStart: 1000000, RangeSize: 1000,
+		})
+	assert.NoError(t, v.ValidateUpdate(ctx, sc, changed))
+
+	changed = sc.DeepCopy()
+	changed.Spec.Domains[1].RangeStart = 1000000
+	changed.Spec.Domains[1].RangeSize = 1000
+	assert.Error(t, v.ValidateUpdate(ctx, sc, changed))
+	changed.Annotations = map[string]string{
+		sambaoperatorv1alpha1.ForceIDMapChangeAnnotation: "true",
+	}
+	assert.NoError(t, v.ValidateUpdate(ctx, sc, changed))
+
+	changed.Annotations = nil
+	v.Client = fake.NewClientBuilder().WithScheme(scheme).Build()
+	assert.NoError(t, v.ValidateUpdate(ctx, sc, changed))
+}
+
+func TestValidateSmbSecurityConfigComputerAccount(t *testing.T) {
+	ctx := context.TODO()
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+		},
+	}
+	fred := sampleShare("fred")
+	fred.Spec.SecurityConfig = "addc"
+	barney := sampleShare("barney")
+	barney.Spec.SecurityConfig = "addc"
+	scheme := runtime.NewScheme()
+	_ = sambaoperatorv1alpha1.AddToScheme(scheme)
+	v := &SmbSecurityConfigValidator{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithRuntimeObjects(fred, barney).
+			Build(),
+	}
+	assert.NoError(t, v.ValidateCreate(ctx, sc))
+
+	named := sc.DeepCopy()
+	named.Spec.JoinOptions = &sambaoperatorv1alpha1.SmbSecurityJoinOptionsSpec{
+		ComputerAccountName: "FILES1",
+	}
+	assert.Error(t, v.ValidateCreate(ctx, named))
+	assert.Error(t, v.ValidateUpdate(ctx, sc, named))
+
+	// the organizational unit may be changed regardless
+	changed := named.DeepCopy()
+	changed.Spec.JoinOptions.OrganizationalUnit = "Computers/Samba"
+	assert.NoError(t, v.ValidateUpdate(ctx, named, changed))
+
+	barney.Spec.NetbiosName = "BARNEY"
+	v.Client = fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(fred, barney).
+		Build()
+	assert.NoError(t, v.ValidateCreate(ctx, named))
+}
+
+func TestValidateSmbCommonConfig(t *testing.T) {
+	ctx := context.TODO()
+	v := &SmbCommonConfigValidator{}
+	cc := &sambaoperatorv1alpha1.SmbCommonConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "common",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbCommonConfigSpec{
+			CustomGlobalConfig: &sambaoperatorv1alpha1.SmbCommonConfigGlobalConfig{
+				Configs: map[string]string{"log level": "3"},
+			},
+		},
+	}
+	assert.Error(t, v.ValidateCreate(ctx, cc))
+	cc.Spec.CustomGlobalConfig.UseUnsafeCustomConfig = true
+	assert.NoError(t, v.ValidateCreate(ctx, cc))
+
+	cc.Spec.Security = &sambaoperatorv1alpha1.SmbCommonSecuritySpec{
+		MinProtocol: "SMB2_10",
+		MaxProtocol: "SMB3",
+	}
+	assert.NoError(t, v.ValidateCreate(ctx, cc))
+	cc.Spec.Security.MinProtocol = "SMB3_02"
+	cc.Spec.Security.MaxProtocol = "SMB2"
+	assert.Error(t, v.ValidateCreate(ctx, cc))
+}
@@ -0,0 +1,532 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+	"github.com/samba-in-kubernetes/samba-operator/internal/smbcc"
+)
+
+// +kubebuilder:webhook:path=/validate-samba-operator-samba-org-v1alpha1-smbshare,mutating=false,failurePolicy=fail,sideEffects=None,groups=samba-operator.samba.org,resources=smbshares,verbs=create;update,versions=v1alpha1,name=vsmbshare.samba-operator.samba.org,admissionReviewVersions=v1
+
+// SmbShareValidator validates SmbShare resources.
+type SmbShareValidator struct {
+	Client rtclient.Client
+	// Reader is used to read the users secrets. Unlike the client it
+	// must not be backed by a cache of all secrets.
+	Reader rtclient.Reader
+	Config *conf.OperatorConfig
+}
+
+// ValidateCreate validates a new SmbShare.
+func (v *SmbShareValidator) ValidateCreate(
+	ctx context.Context, obj runtime.Object) error {
+	// ---
+	s, ok := obj.(*sambaoperatorv1alpha1.SmbShare)
+	if !ok {
+		return fmt.Errorf("expected an SmbShare but got %T", obj)
+	}
+	errs := validateSmbShareSpec(s)
+	if len(errs) == 0 {
+		errs = append(errs, v.validateGroupMembers(ctx, s, nil)...)
+		errs = append(errs, v.validateComputerAccount(ctx, s)...)
+		errs = append(errs, v.validateAccess(ctx, s)...)
+	}
+	return invalid(s, errs)
+}
+
+// ValidateUpdate validates changes to an SmbShare.
+func (v *SmbShareValidator) ValidateUpdate(
+	ctx context.Context, oldObj, newObj runtime.Object) error {
+	// ---
+	s, ok := newObj.(*sambaoperatorv1alpha1.SmbShare)
+	if !ok {
+		return fmt.Errorf("expected an SmbShare but got %T", newObj)
+	}
+	old, ok := oldObj.(*sambaoperatorv1alpha1.SmbShare)
+	if !ok {
+		return fmt.Errorf("expected an SmbShare but got %T", oldObj)
+	}
+	if s.GetDeletionTimestamp() != nil {
+		// never block the removal of finalizers from a deleted share
+		return nil
+	}
+	errs := validateSmbShareSpec(s)
+	errs = append(errs, validateSmbShareImmutable(s, old)...)
+	if len(errs) == 0 && groupSettingsChanged(s, old) {
+		errs = append(errs, v.validateGroupMembers(ctx, s, old)...)
+		errs = append(errs, v.validateComputerAccount(ctx, s)...)
+	}
+	if len(errs) == 0 && accessChanged(s, old) {
+		errs = append(errs, v.validateAccess(ctx, s)...)
+	}
+	return invalid(s, errs)
+}
+
+// ValidateDelete validates the deletion of an SmbShare.
+func (*SmbShareValidator) ValidateDelete(
+	_ context.Context, _ runtime.Object) error {
+	// ---
+	return nil
+}
+
+func validateSmbShareSpec(s *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	if s.Spec.ShareName != "" {
+		if err := pln.ValidateShareName(s.Spec.ShareName); err != nil {
+			errs = append(errs, field.Invalid(
+				spec.Child("shareName"), s.Spec.ShareName, err.Error()))
+		}
+	}
+	errs = append(errs, validateSmbShareStorage(s)...)
+	errs = append(errs, validateSmbShareSnapshots(s)...)
+	if tm := s.Spec.TimeMachine; tm != nil {
+		timeMachine := spec.Child("timeMachine")
+		if s.Spec.ReadOnly {
+			errs = append(errs, field.Forbidden(
+				timeMachine, "a read-only share can not store backups"))
+		}
+		if tm.MaxSize != nil && tm.MaxSize.Sign() <= 0 {
+			errs = append(errs, field.Invalid(
+				timeMachine.Child("maxSize"), tm.MaxSize.String(),
+				"must be greater than zero"))
+		}
+	}
+	if s.Spec.Scaling != nil {
+		scaling := spec.Child("scaling")
+		group := s.Spec.Scaling.Group
+		switch pln.GroupMode(s.Spec.Scaling.GroupMode) {
+		case pln.GroupModeNever, pln.GroupModeUnset:
+			if group != "" {
+				errs = append(errs, field.Invalid(
+					scaling.Child("group"), group,
+					"a group name may not be specified when groupMode is 'never'"))
+			}
+		case pln.GroupModeExplicit:
+			if group == "" {
+				errs = append(errs, field.Required(
+					scaling.Child("group"),
+					"a group name is required when groupMode is 'explicit'"))
+			}
+		}
+		minSize := s.Spec.Scaling.MinClusterSize
+		maxSize := s.Spec.Scaling.MaxClusterSize
+		if maxSize > 0 && minSize > maxSize {
+			errs = append(errs, field.Invalid(
+				scaling.Child("minClusterSize"), minSize,
+				"may not be larger than maxClusterSize"))
+		}
+	}
+	return errs
+}
+
+func validateSmbShareStorage(s *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	errs := field.ErrorList{}
+	storage := field.NewPath("spec", "storage")
+	kinds := pln.StorageKinds(s)
+	switch {
+	case len(kinds) == 0:
+		errs = append(errs, field.Required(
+			storage, "a source of storage is required"))
+	case len(kinds) > 1:
+		errs = append(errs, field.Forbidden(
+			storage, "only one source of storage may be specified"))
+	}
+	pvc := s.Spec.Storage.Pvc
+	if pvc != nil && pvc.Name == "" && pvc.Spec == nil {
+		errs = append(errs, field.Required(
+			storage.Child("pvc"),
+			"one of name or spec is required"))
+	}
+	if s.Spec.Storage.Ephemeral != nil && availabilityMode(s) == "clustered" {
+		errs = append(errs, field.Forbidden(
+			storage.Child("ephemeral"),
+			"ephemeral storage can not be used by clustered shares"))
+	}
+	return errs
+}
+
+// minSnapshotInterval limits how often snapshots are taken. Every snapshot
+// is restored to a PVC that the pods of the server group mount, so each new
+// snapshot replaces those pods.
+const minSnapshotInterval = time.Hour
+
+func validateSmbShareSnapshots(s *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	errs := field.ErrorList{}
+	snapshots := s.Spec.Snapshots
+	if snapshots == nil {
+		return errs
+	}
+	path := field.NewPath("spec", "snapshots")
+	st := s.Spec.Storage
+	if st.Pvc == nil && st.PersistentVolume == nil {
+		errs = append(errs, field.Forbidden(
+			path,
+			"snapshots require storage backed by a pvc or persistentVolume"))
+	}
+	if d := snapshots.Interval.Duration; d != 0 && d < minSnapshotInterval {
+		errs = append(errs, field.Invalid(
+			path.Child("interval"), snapshots.Interval.String(),
+			fmt.Sprintf("may not be shorter than %s", minSnapshotInterval)))
+	}
+	return errs
+}
+
+func validateSmbShareImmutable(
+	s, old *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	scaling := field.NewPath("spec", "scaling")
+	mode, group := groupOf(s)
+	oldMode, oldGroup := groupOf(old)
+	if mode != oldMode {
+		errs = append(errs, field.Forbidden(
+			scaling.Child("groupMode"), "field is immutable"))
+	}
+	if group != oldGroup {
+		errs = append(errs, field.Forbidden(
+			scaling.Child("group"), "field is immutable"))
+	}
+	// the servers are known to clients, and joined to a domain, under the
+	// NetBIOS name
+	if s.Spec.NetbiosName != old.Spec.NetbiosName {
+		errs = append(errs, field.Forbidden(
+			field.NewPath("spec", "netbiosName"), "field is immutable"))
+	}
+	size, found := pvcStorageRequest(s)
+	oldSize, oldFound := pvcStorageRequest(old)
+	if found && oldFound && size.Cmp(oldSize) < 0 {
+		errs = append(errs, field.Forbidden(
+			field.NewPath("spec", "storage", "pvc", "spec", "resources",
+				"requests", "storage"),
+			fmt.Sprintf("may not be less than the previous value (%s)",
+				oldSize.String())))
+	}
+	return errs
+}
+
+func pvcStorageRequest(
+	s *sambaoperatorv1alpha1.SmbShare) (resource.Quantity, bool) {
+	// ---
+	pvc := s.Spec.Storage.Pvc
+	if pvc == nil || pvc.Spec == nil {
+		return resource.Quantity{}, false
+	}
+	size, found := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	return size, found
+}
+
+// validateGroupMembers checks that the share is compatible with the other
+// shares hosted by the same server group. If the previous version of the
+// share is given the availability mode may only be changed if the share
+// is the only member of the group.
+func (v *SmbShareValidator) validateGroupMembers(
+	ctx context.Context,
+	s, old *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	mode, group := groupOf(s)
+	if mode != pln.GroupModeExplicit {
+		return errs
+	}
+	members, err := v.groupMembers(ctx, s, group)
+	if err != nil {
+		return append(errs, field.InternalError(field.NewPath("spec"), err))
+	}
+	if len(members) == 0 {
+		return errs
+	}
+
+	scaling := field.NewPath("spec", "scaling")
+	if old != nil && availabilityMode(s) != availabilityMode(old) {
+		errs = append(errs, field.Forbidden(
+			scaling.Child("availabilityMode"),
+			fmt.Sprintf("can not be changed while server group %s"+
+				" hosts other shares", group)))
+	}
+
+	current, err := v.instanceOf(ctx, s)
+	if err != nil {
+		return append(errs, field.InternalError(field.NewPath("spec"), err))
+	}
+	for i := range members {
+		if len(pln.StorageKinds(&members[i])) == 0 {
+			continue
+		}
+		existing, err := v.instanceOf(ctx, &members[i])
+		if err != nil {
+			return append(errs,
+				field.InternalError(field.NewPath("spec"), err))
+		}
+		if err := pln.CheckCompatible(current, existing); err != nil {
+			errs = append(errs, field.Invalid(
+				scaling.Child("group"), group, err.Error()))
+			break
+		}
+	}
+	return errs
+}
+
+// validateComputerAccount checks that the servers of the share would not
+// join the domain with a computer account name already used by another
+// server group.
+func (v *SmbShareValidator) validateComputerAccount(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	if s.Spec.SecurityConfig == "" || s.Spec.NetbiosName != "" {
+		return errs
+	}
+	spec := field.NewPath("spec")
+	security := &sambaoperatorv1alpha1.SmbSecurityConfig{}
+	found, err := v.get(ctx, s.Namespace, s.Spec.SecurityConfig, security)
+	if err != nil {
+		return append(errs, field.InternalError(spec, err))
+	}
+	if !found {
+		// checked when the security config is created
+		return errs
+	}
+	l := &sambaoperatorv1alpha1.SmbShareList{}
+	if err := v.Client.List(ctx, l, rtclient.InNamespace(s.Namespace)); err != nil {
+		return append(errs, field.InternalError(spec, err))
+	}
+	shares := []sambaoperatorv1alpha1.SmbShare{*s}
+	for _, other := range l.Items {
+		if other.Name != s.Name {
+			shares = append(shares, other)
+		}
+	}
+	if err := pln.ValidateComputerAccount(security, shares); err != nil {
+		errs = append(errs, field.Invalid(
+			spec.Child("securityConfig"), s.Spec.SecurityConfig, err.Error()))
+	}
+	return errs
+}
+
+// groupSettingsChanged returns true if the fields of the share that decide
+// if it can be hosted along with other shares have changed. Updates that
+// leave them alone, including the updates made by the operator itself, do
+// not need to be checked against the other members of the group.
+func groupSettingsChanged(s, old *sambaoperatorv1alpha1.SmbShare) bool {
+	return s.Spec.SecurityConfig != old.Spec.SecurityConfig ||
+		s.Spec.CommonConfig != old.Spec.CommonConfig ||
+		s.Spec.NetbiosName != old.Spec.NetbiosName ||
+		availabilityMode(s) != availabilityMode(old) ||
+		len(pln.StorageKinds(s)) != len(pln.StorageKinds(old))
+}
+
+// accessChanged returns true if the access lists of the share, or the
+// security config defining the users they refer to, changed. Access lists
+// that were accepted before are not checked again, as changes to the users
+// secret must not prevent unrelated updates of the share.
+func accessChanged(s, old *sambaoperatorv1alpha1.SmbShare) bool {
+	return s.Spec.SecurityConfig != old.Spec.SecurityConfig ||
+		!equality.Semantic.DeepEqual(s.Spec.ValidUsers, old.Spec.ValidUsers) ||
+		!equality.Semantic.DeepEqual(s.Spec.InvalidUsers, old.Spec.InvalidUsers) ||
+		!equality.Semantic.DeepEqual(s.Spec.AdminUsers, old.Spec.AdminUsers) ||
+		!equality.Semantic.DeepEqual(s.Spec.ReadList, old.Spec.ReadList) ||
+		!equality.Semantic.DeepEqual(s.Spec.WriteList, old.Spec.WriteList)
+}
+
+// groupMembers returns the other shares assigned to the named server
+// group.
+func (v *SmbShareValidator) groupMembers(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare,
+	group string) ([]sambaoperatorv1alpha1.SmbShare, error) {
+	// ---
+	l := &sambaoperatorv1alpha1.SmbShareList{}
+	if err := v.Client.List(ctx, l, rtclient.InNamespace(s.Namespace)); err != nil {
+		return nil, err
+	}
+	members := []sambaoperatorv1alpha1.SmbShare{}
+	for _, other := range l.Items {
+		if other.Name == s.Name || other.Status.ServerGroup != group {
+			continue
+		}
+		members = append(members, other)
+	}
+	return members, nil
+}
+
+// instanceOf returns the instance configuration of the share. Config
+// resources that do not (yet) exist are left unset.
+func (v *SmbShareValidator) instanceOf(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare) (pln.InstanceConfiguration, error) {
+	// ---
+	instance := pln.InstanceConfiguration{
+		SmbShare:     s,
+		GlobalConfig: v.Config,
+	}
+	if s.Spec.SecurityConfig != "" {
+		security := &sambaoperatorv1alpha1.SmbSecurityConfig{}
+		found, err := v.get(ctx, s.Namespace, s.Spec.SecurityConfig, security)
+		if err != nil {
+			return instance, err
+		}
+		if found {
+			instance.SecurityConfig = security
+		}
+	}
+	if s.Spec.CommonConfig != "" {
+		common := &sambaoperatorv1alpha1.SmbCommonConfig{}
+		found, err := v.get(ctx, s.Namespace, s.Spec.CommonConfig, common)
+		if err != nil {
+			return instance, err
+		}
+		if found {
+			instance.CommonConfig = common
+		}
+	}
+	return instance, nil
+}
+
+// validateAccess checks that the users and groups listed in the access
+// fields of the share are defined by the share's SmbSecurityConfig. Only
+// locally defined users can be checked, domain users and groups are left
+// for the domain controllers to resolve. The entries are not checked if
+// the users secret can not be read.
+func (v *SmbShareValidator) validateAccess(
+	ctx context.Context,
+	s *sambaoperatorv1alpha1.SmbShare) field.ErrorList {
+	// ---
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	accessLists := []struct {
+		name    string
+		entries []sambaoperatorv1alpha1.AccessEntry
+	}{
+		{"validUsers", s.Spec.ValidUsers},
+		{"invalidUsers", s.Spec.InvalidUsers},
+		{"adminUsers", s.Spec.AdminUsers},
+		{"readList", s.Spec.ReadList},
+		{"writeList", s.Spec.WriteList},
+	}
+	empty := true
+	for _, al := range accessLists {
+		if len(al.entries) > 0 {
+			empty = false
+		}
+	}
+	if empty || s.Spec.SecurityConfig == "" {
+		return errs
+	}
+
+	instance, err := v.instanceOf(ctx, s)
+	if err != nil {
+		return append(errs, field.InternalError(spec, err))
+	}
+	if instance.SecurityConfig == nil {
+		// can not be checked until the security config exists
+		return errs
+	}
+	planner := pln.New(instance, nil)
+	src := planner.UserSecuritySource()
+	if !src.Configured {
+		return errs
+	}
+	users, err := v.usersConfig(ctx, src)
+	if err != nil || users == nil {
+		// a users secret that is missing or can not be read is reported
+		// by the status of the SmbSecurityConfig, the share is not
+		// rejected because of it
+		return errs
+	}
+	for _, al := range accessLists {
+		for i, e := range al.entries {
+			if reason := unknownAccessEntry(users, e); reason != "" {
+				errs = append(errs, field.Invalid(
+					spec.Child(al.name).Index(i), e, reason))
+			}
+		}
+	}
+	return errs
+}
+
+// usersConfig returns the users and groups defined by the users secret.
+// Returns nil if the secret does not exist (yet).
+func (v *SmbShareValidator) usersConfig(
+	ctx context.Context,
+	src pln.UserSecuritySource) (*smbcc.SambaContainerConfig, error) {
+	// ---
+	if v.Reader == nil {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: src.Namespace, Name: src.Secret}
+	err := v.Reader.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, found := secret.Data[src.Key]
+	if !found {
+		return nil, fmt.Errorf("key %s not found", src.Key)
+	}
+	return smbcc.ParseUsers(data)
+}
+
+// unknownAccessEntry returns a reason if the user or group named by the
+// entry is not defined or an empty string if it is.
+func unknownAccessEntry(
+	users *smbcc.SambaContainerConfig,
+	e sambaoperatorv1alpha1.AccessEntry) string {
+	// ---
+	name := string(e)
+	isGroup := strings.HasPrefix(name, "@") || strings.HasPrefix(name, "+")
+	name = strings.TrimLeft(name, "@+")
+	switch {
+	case strings.Contains(name, `\`):
+		return "domain users and groups require active-directory security"
+	case isGroup && !users.HasGroup(name):
+		return fmt.Sprintf("group %s is not defined in the users secret", name)
+	case !isGroup && !users.HasUser(name):
+		return fmt.Sprintf("user %s is not defined in the users secret", name)
+	}
+	return ""
+}
+
+func (v *SmbShareValidator) get(
+	ctx context.Context,
+	ns, name string,
+	obj rtclient.Object) (bool, error) {
+	// ---
+	key := types.NamespacedName{Namespace: ns, Name: name}
+	err := v.Client.Get(ctx, key, obj)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func groupOf(s *sambaoperatorv1alpha1.SmbShare) (pln.GroupMode, string) {
+	return pln.New(pln.InstanceConfiguration{SmbShare: s}, nil).Grouping()
+}
+
+func availabilityMode(s *sambaoperatorv1alpha1.SmbShare) string {
+	if s.Spec.Scaling == nil || s.Spec.Scaling.AvailabilityMode == "" {
+		return "standard"
+	}
+	return s.Spec.Scaling.AvailabilityMode
+}
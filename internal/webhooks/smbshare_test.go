@@ -0,0 +1,369 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+)
+
+func sampleShare(name string) *sambaoperatorv1alpha1.SmbShare {
+	return &sambaoperatorv1alpha1.SmbShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbShareSpec{
+			Storage: sambaoperatorv1alpha1.SmbShareStorageSpec{
+				Pvc: &sambaoperatorv1alpha1.SmbSharePvcSpec{
+					Name: "quarry",
+				},
+			},
+		},
+	}
+}
+
+func groupedShare(name, group string) *sambaoperatorv1alpha1.SmbShare {
+	s := sampleShare(name)
+	s.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+		GroupMode: "explicit",
+		Group:     group,
+	}
+	return s
+}
+
+func newValidator(objs ...runtime.Object) *SmbShareValidator {
+	scheme := runtime.NewScheme()
+	_ = sambaoperatorv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	client := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(objs...).
+		Build()
+	return &SmbShareValidator{
+		Client: client,
+		Reader: client,
+		Config: &conf.OperatorConfig{},
+	}
+}
+
+func TestValidateSmbShareSpec(t *testing.T) {
+	assert.Empty(t, validateSmbShareSpec(sampleShare("fred")))
+
+	s := sampleShare("fred")
+	s.Spec.ShareName = "bad/name"
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = sampleShare("fred")
+	s.Spec.Storage.Pvc = nil
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = sampleShare("fred")
+	s.Spec.Storage.Pvc.Name = ""
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = sampleShare("fred")
+	s.Spec.Storage.NFS = &sambaoperatorv1alpha1.SmbShareNFSSpec{
+		Server: "nfs.example.com",
+		Path:   "/exports/quarry",
+	}
+	assert.Len(t, validateSmbShareSpec(s), 1)
+	s.Spec.Storage.Pvc = nil
+	assert.Empty(t, validateSmbShareSpec(s))
+
+	s = sampleShare("fred")
+	s.Spec.Storage.Pvc = nil
+	s.Spec.Storage.Ephemeral = &sambaoperatorv1alpha1.SmbShareEphemeralSpec{}
+	assert.Empty(t, validateSmbShareSpec(s))
+	s.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+		AvailabilityMode: "clustered",
+	}
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = groupedShare("fred", "")
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = groupedShare("fred", "flintstones")
+	s.Spec.Scaling.GroupMode = "never"
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = sampleShare("fred")
+	s.Spec.Scaling = &sambaoperatorv1alpha1.SmbShareScalingSpec{
+		MinClusterSize: 3,
+		MaxClusterSize: 2,
+	}
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = sampleShare("fred")
+	s.Spec.Snapshots = &sambaoperatorv1alpha1.SmbShareSnapshotsSpec{
+		Interval: metav1.Duration{Duration: 6 * time.Hour},
+	}
+	assert.Empty(t, validateSmbShareSpec(s))
+	s.Spec.Snapshots.Interval.Duration = 10 * time.Minute
+	assert.Len(t, validateSmbShareSpec(s), 1)
+	s.Spec.Snapshots.Interval.Duration = 0
+	s.Spec.Storage.Pvc = nil
+	s.Spec.Storage.Ephemeral = &sambaoperatorv1alpha1.SmbShareEphemeralSpec{}
+	assert.Len(t, validateSmbShareSpec(s), 1)
+
+	s = sampleShare("fred")
+	maxSize := resource.MustParse("500Gi")
+	s.Spec.TimeMachine = &sambaoperatorv1alpha1.SmbShareTimeMachineSpec{
+		MaxSize: &maxSize,
+	}
+	assert.Empty(t, validateSmbShareSpec(s))
+	s.Spec.ReadOnly = true
+	assert.Len(t, validateSmbShareSpec(s), 1)
+	s.Spec.ReadOnly = false
+	maxSize = resource.MustParse("0")
+	assert.Len(t, validateSmbShareSpec(s), 1)
+}
+
+func TestValidateSmbShareUpdate(t *testing.T) {
+	ctx := context.TODO()
+	v := newValidator()
+
+	old := sampleShare("fred")
+	s := old.DeepCopy()
+	s.Spec.ShareName = "Fred"
+	assert.NoError(t, v.ValidateUpdate(ctx, old, s))
+
+	s = groupedShare("fred", "flintstones")
+	assert.Error(t, v.ValidateUpdate(ctx, old, s))
+
+	old = groupedShare("fred", "flintstones")
+	s = groupedShare("fred", "rubbles")
+	assert.Error(t, v.ValidateUpdate(ctx, old, s))
+
+	// a lone share may change availability mode
+	s = old.DeepCopy()
+	s.Spec.Scaling.AvailabilityMode = "clustered"
+	assert.NoError(t, v.ValidateUpdate(ctx, old, s))
+
+	// the NetBIOS name may be neither set, changed nor removed later
+	s = old.DeepCopy()
+	s.Spec.NetbiosName = "BEDROCK"
+	assert.Error(t, v.ValidateUpdate(ctx, old, s))
+	assert.Error(t, v.ValidateUpdate(ctx, s, old))
+	assert.NoError(t, v.ValidateUpdate(ctx, s, s.DeepCopy()))
+
+	// the storage request may grow but not shrink
+	old = sampleShare("fred")
+	old.Spec.Storage.Pvc.Spec = &corev1.PersistentVolumeClaimSpec{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	s = old.DeepCopy()
+	s.Spec.Storage.Pvc.Spec.Resources.Requests[corev1.ResourceStorage] =
+		resource.MustParse("2Gi")
+	assert.NoError(t, v.ValidateUpdate(ctx, old, s))
+	assert.Error(t, v.ValidateUpdate(ctx, s, old))
+}
+
+func TestValidateSmbShareGroup(t *testing.T) {
+	ctx := context.TODO()
+	member := groupedShare("wilma", "flintstones")
+	member.Status.ServerGroup = "flintstones"
+	v := newValidator(member)
+
+	s := groupedShare("fred", "flintstones")
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+
+	// shares of a group may use different PVCs
+	s.Spec.Storage.Pvc.Name = "cave"
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+
+	s.Spec.SecurityConfig = "other"
+	err := v.ValidateCreate(ctx, s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "security config name mismatch")
+	}
+
+	// the group hosts another share, the availability mode is fixed
+	old := groupedShare("fred", "flintstones")
+	s = old.DeepCopy()
+	s.Spec.Scaling.AvailabilityMode = "clustered"
+	assert.Error(t, v.ValidateUpdate(ctx, old, s))
+
+	// updates not touching the group settings are not checked against
+	// the other members, even if the share is incompatible with them
+	old = groupedShare("fred", "flintstones")
+	old.Spec.SecurityConfig = "other"
+	s = old.DeepCopy()
+	s.Spec.Browseable = true
+	s.Finalizers = []string{"samba-operator.samba.org/shareFinalizer"}
+	assert.NoError(t, v.ValidateUpdate(ctx, old, s))
+
+	s.Spec.CommonConfig = "bedrock"
+	assert.Error(t, v.ValidateUpdate(ctx, old, s))
+}
+
+func TestValidateSmbShareComputerAccount(t *testing.T) {
+	ctx := context.TODO()
+	sc := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addc",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode:  "active-directory",
+			Realm: "bedrock.example.com",
+			JoinOptions: &sambaoperatorv1alpha1.SmbSecurityJoinOptionsSpec{
+				ComputerAccountName: "FILES1",
+			},
+		},
+	}
+	member := groupedShare("wilma", "flintstones")
+	member.Spec.SecurityConfig = "addc"
+	member.Status.ServerGroup = "flintstones"
+	v := newValidator(sc, member)
+
+	// shares of the same group join with the same computer account
+	s := groupedShare("fred", "flintstones")
+	s.Spec.SecurityConfig = "addc"
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+
+	s = sampleShare("barney")
+	s.Spec.SecurityConfig = "addc"
+	err := v.ValidateCreate(ctx, s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "computer account name FILES1")
+	}
+	s.Spec.NetbiosName = "BARNEY"
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+}
+
+func TestValidateSmbShareAccess(t *testing.T) {
+	ctx := context.TODO()
+	security := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "users",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode: "user",
+			Users: &sambaoperatorv1alpha1.SmbSecurityUsersSpec{
+				Secret: "users1",
+				Key:    "demousers",
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "users1",
+			Namespace: "bedrock",
+		},
+		Data: map[string][]byte{
+			"demousers": []byte(`{
+				"samba-container-config": "v0",
+				"users": {"all_entries": [{"name": "fred"}, {"name": "barney"}]},
+				"groups": {"all_entries": [{"name": "lodge"}]}
+			}`),
+		},
+	}
+	v := newValidator(security, secret)
+
+	s := sampleShare("quarry")
+	s.Spec.SecurityConfig = "users"
+	s.Spec.ValidUsers = []sambaoperatorv1alpha1.AccessEntry{"fred", "@lodge"}
+	s.Spec.WriteList = []sambaoperatorv1alpha1.AccessEntry{"barney"}
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+
+	s.Spec.AdminUsers = []sambaoperatorv1alpha1.AccessEntry{"wilma"}
+	err := v.ValidateCreate(ctx, s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "spec.adminUsers[0]")
+		assert.Contains(t, err.Error(), "user wilma is not defined")
+	}
+
+	s.Spec.AdminUsers = nil
+	s.Spec.ReadList = []sambaoperatorv1alpha1.AccessEntry{`@BEDROCK\lodge`}
+	err = v.ValidateCreate(ctx, s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "require active-directory")
+	}
+
+	// domain entries are not checked for active-directory security
+	security.Spec.Mode = "active-directory"
+	security.Spec.Users = nil
+	v = newValidator(security, secret)
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+
+	// the users can not be checked without the security config
+	v = newValidator()
+	s.Spec.ReadList = []sambaoperatorv1alpha1.AccessEntry{"nobody"}
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+
+	// a users secret that can not be read does not reject the share
+	security.Spec.Mode = "user"
+	security.Spec.Users = &sambaoperatorv1alpha1.SmbSecurityUsersSpec{
+		Secret: "users1",
+		Key:    "missing",
+	}
+	v = newValidator(security, secret)
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+	v = newValidator(security)
+	assert.NoError(t, v.ValidateCreate(ctx, s))
+}
+
+func TestValidateSmbShareAccessUpdate(t *testing.T) {
+	ctx := context.TODO()
+	security := &sambaoperatorv1alpha1.SmbSecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "users",
+			Namespace: "bedrock",
+		},
+		Spec: sambaoperatorv1alpha1.SmbSecurityConfigSpec{
+			Mode: "user",
+			Users: &sambaoperatorv1alpha1.SmbSecurityUsersSpec{
+				Secret: "users1",
+				Key:    "demousers",
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "users1",
+			Namespace: "bedrock",
+		},
+		Data: map[string][]byte{
+			"demousers": []byte(`{
+				"samba-container-config": "v0",
+				"users": {"all_entries": [{"name": "barney"}]}
+			}`),
+		},
+	}
+	v := newValidator(security, secret)
+
+	// fred was removed from the users secret after the share was created
+	old := sampleShare("quarry")
+	old.Spec.SecurityConfig = "users"
+	old.Spec.ValidUsers = []sambaoperatorv1alpha1.AccessEntry{"fred"}
+
+	s := old.DeepCopy()
+	s.Spec.Browseable = !old.Spec.Browseable
+	assert.NoError(t, v.ValidateUpdate(ctx, old, s))
+
+	s.Spec.WriteList = []sambaoperatorv1alpha1.AccessEntry{"barney"}
+	err := v.ValidateUpdate(ctx, old, s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "user fred is not defined")
+	}
+
+	s.Spec.ValidUsers = []sambaoperatorv1alpha1.AccessEntry{"barney"}
+	assert.NoError(t, v.ValidateUpdate(ctx, old, s))
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package webhooks
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
+)
+
+// SetupWithManager registers the webhooks with the manager's webhook
+// server. The conversion webhook, converting between the API versions of
+// the resources, is registered along with them as long as all versions
+// are known to the manager's scheme.
+func SetupWithManager(mgr ctrl.Manager, cfg *conf.OperatorConfig) error {
+	err := ctrl.NewWebhookManagedBy(mgr).
+		For(&sambaoperatorv1alpha1.SmbShare{}).
+		WithValidator(&SmbShareValidator{
+			Client: mgr.GetClient(),
+			Reader: mgr.GetAPIReader(),
+			Config: cfg,
+		}).
+		WithDefaulter(&SmbShareDefaulter{Config: cfg}).
+		Complete()
+	if err != nil {
+		return err
+	}
+	err = ctrl.NewWebhookManagedBy(mgr).
+		For(&sambaoperatorv1alpha1.SmbSecurityConfig{}).
+		WithValidator(&SmbSecurityConfigValidator{Client: mgr.GetClient()}).
+		Complete()
+	if err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&sambaoperatorv1alpha1.SmbCommonConfig{}).
+		WithValidator(&SmbCommonConfigValidator{}).
+		WithDefaulter(&SmbCommonConfigDefaulter{Config: cfg}).
+		Complete()
+}
+
+// invalid converts a list of field errors into an Invalid api error.
+// Returns nil if the list is empty.
+func invalid(obj rtclient.Object, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		// the type meta is not always filled in for decoded objects
+		gvk = sambaoperatorv1alpha1.GroupVersion.WithKind(kindOf(obj))
+	}
+	return apierrors.NewInvalid(gvk.GroupKind(), obj.GetName(), errs)
+}
+
+func kindOf(obj rtclient.Object) string {
+	switch obj.(type) {
+	case *sambaoperatorv1alpha1.SmbShare:
+		return "SmbShare"
+	case *sambaoperatorv1alpha1.SmbSecurityConfig:
+		return "SmbSecurityConfig"
+	case *sambaoperatorv1alpha1.SmbCommonConfig:
+		return "SmbCommonConfig"
+	}
+	return ""
+}
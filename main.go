@@ -33,9 +33,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	sambaoperatorv1alpha1 "github.com/samba-in-kubernetes/samba-operator/api/v1alpha1"
+	sambaoperatorv1beta1 "github.com/samba-in-kubernetes/samba-operator/api/v1beta1"
 	"github.com/samba-in-kubernetes/samba-operator/controllers"
 	"github.com/samba-in-kubernetes/samba-operator/internal/conf"
 	pln "github.com/samba-in-kubernetes/samba-operator/internal/planner"
+	"github.com/samba-in-kubernetes/samba-operator/internal/webhooks"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -56,6 +58,7 @@ func init() {
 	utilruntime.Must(monitoringv1.AddToScheme(scheme))
 
 	utilruntime.Must(sambaoperatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(sambaoperatorv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -129,6 +132,16 @@ func main() {
 			"controller", "SmbShare")
 		os.Exit(1)
 	}
+	if err = (&controllers.SmbShareSnapshotReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("SmbShareSnapshot"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(
+			err,
+			"unable to create controller",
+			"controller", "SmbShareSnapshot")
+		os.Exit(1)
+	}
 	if err = (&controllers.SmbSecurityConfigReconciler{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("controllers").WithName("SmbSecurityConfig"),
@@ -149,6 +162,12 @@ func main() {
 			"controller", "SmbCommonConfig")
 		os.Exit(1)
 	}
+	if conf.Get().EnableWebhooks {
+		if err = webhooks.SetupWithManager(mgr, conf.Get()); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")